@@ -0,0 +1,92 @@
+// wisp-bench mede a vazão do hub com clientes simulados em memória, sem
+// WebSocket nem MongoDB.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/model"
+	"wisp/src/ws"
+	"wisp/src/ws/wstest"
+
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	clients := flag.Int("clients", 20000, "clientes simulados")
+	messages := flag.Int("messages", 500000, "mensagens enviadas")
+	senders := flag.Int("senders", 64, "goroutines enviando")
+	shards := flag.Int("shards", 8, "shards do hub")
+	workers := flag.Int("workers", 16, "workers por pool")
+	flag.Parse()
+
+	cfg := wstest.Config("bench")
+	cfg.Hub.Shards = *shards
+	cfg.Hub.Workers = *workers
+
+	hub := ws.NewHub(cfg, wstest.NewStore(), wstest.NewReceipts(), bus.NewLocal(), bus.NewMemoryPresence())
+	hub.Run()
+
+	var received atomic.Int64
+	msgMarker := []byte(`"type":"message"`)
+	for i := range *clients {
+		c := ws.NewClient(hub, userID(i), "bench", nil, log.Logger)
+		hub.Register(c)
+		go func() {
+			for frame := range c.Send {
+				if bytes.Contains(frame, msgMarker) {
+					received.Add(1)
+				}
+			}
+		}()
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	perSender := *messages / *senders
+	for s := range *senders {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for range perSender {
+				hub.Broadcast(context.Background(), &model.Message{
+					Type:      "message",
+					From:      userID(rnd.Intn(*clients)),
+					To:        userID(rnd.Intn(*clients)),
+					Content:   "bench",
+					Timestamp: time.Now().Unix(),
+				})
+			}
+		}(int64(s))
+	}
+	wg.Wait()
+
+	total := int64(perSender * *senders)
+	deadline := time.Now().Add(time.Minute)
+	for received.Load() < total && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	elapsed := time.Since(start)
+
+	stats := hub.Stats()
+	fmt.Printf("clientes=%d shards=%d workers=%d\n", *clients, *shards, *workers)
+	fmt.Printf("entregues=%d/%d em %s (%.0f msg/s)\n", received.Load(), total, elapsed.Round(time.Millisecond), float64(received.Load())/elapsed.Seconds())
+	fmt.Printf("descartados=%d desviados=%d desconexões=%d\n", stats.FramesDropped, stats.FramesSpilled, stats.SlowDisconnects)
+
+	if received.Load() < total {
+		os.Exit(1)
+	}
+}
+
+func userID(i int) string {
+	return fmt.Sprintf("u%06d", i)
+}
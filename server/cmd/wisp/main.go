@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"wisp/config"
 	"wisp/src/db"
 	"wisp/src/logger"
 	"wisp/src/server"
+	"wisp/src/tracing"
 )
 
 func main() {
@@ -20,18 +25,56 @@ func main() {
 	log := logger.NewLogger(cfg.App.Env)
 	log.Info().Msg("starting wisp backend")
 
+	shutdownTracing, err := tracing.Init(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Erro ao configurar o tracing")
+	}
+
 	mongoClient, err := db.Connect(cfg.Mongo.URI)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Não foi possível conectar ao MongoDB")
 	}
-	defer mongoClient.Disconnect(context.TODO())
 	log.Info().Str("db", cfg.Mongo.DBName).Msg("Conectado ao MongoDB")
 
-	r := server.NewRouter(cfg, log, mongoClient)
+	app, err := server.New(cfg, log, mongoClient)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Erro ao montar o servidor")
+	}
 
 	addr := fmt.Sprintf(":%d", cfg.App.Port)
-	log.Info().Str("addr", addr).Msg("Servidor iniciado")
-	if err := r.Run(addr); err != nil {
-		log.Fatal().Err(err).Msg("servidor falhou ao iniciar")
+	srv := &http.Server{Addr: addr, Handler: app.Router}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Info().Str("addr", addr).Msg("Servidor iniciado")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Err(err).Msg("servidor falhou ao iniciar")
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Info().Msg("Sinal recebido, encerrando o servidor")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
+	defer cancel()
+
+	// O hub para de aceitar conexões antes de o listener fechar; conexões
+	// WebSocket já sequestradas não são esperadas pelo http.Server.
+	app.Hub.StopAccepting()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Erro ao encerrar o servidor HTTP")
+	}
+	if err := app.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Erro ao encerrar o hub")
+	}
+	if err := mongoClient.Disconnect(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Erro ao desconectar do MongoDB")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Erro ao enviar os últimos spans")
 	}
+	log.Info().Msg("Servidor encerrado")
 }
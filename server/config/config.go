@@ -1,13 +1,18 @@
 package config
 
 import (
+	"runtime"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 type Config struct {
 	App struct {
-		Port int
-		Env  string
+		Port            int
+		Env             string
+		ShutdownTimeout time.Duration
+		TrustedProxies  []string
 	}
 	Mongo struct {
 		URI    string
@@ -16,6 +21,120 @@ type Config struct {
 	Jwt struct {
 		Secret string
 	}
+	Hub struct {
+		Shards              int
+		Workers             int
+		QueueSize           int
+		DedupWindow         time.Duration
+		SlowConsumerTimeout time.Duration
+		RestartDelay        time.Duration
+		RestartJitter       time.Duration
+	}
+	Cluster struct {
+		NodeID        string
+		Bus           string
+		RedisAddr     string
+		RedisPassword string
+		NatsURL       string
+	}
+	Tracing struct {
+		Exporter    string
+		Endpoint    string
+		Insecure    bool
+		SampleRatio float64
+	}
+	RateLimit struct {
+		Enabled  bool
+		Store    string
+		Policies []RatePolicy
+		WSFrames struct {
+			Limit  int
+			Period time.Duration
+			Burst  int
+		}
+	}
+	Filters struct {
+		Enabled         bool
+		SuspendDuration time.Duration
+		SenderRate      struct {
+			Limit  int
+			Window time.Duration
+			Action string
+		}
+		NewRecipients struct {
+			Limit  int
+			Window time.Duration
+			Action string
+		}
+		FirstContact struct {
+			AccountAge time.Duration
+			Limit      int
+			Window     time.Duration
+			Action     string
+		}
+		DuplicateContent struct {
+			MinLength int
+			Limit     int
+			Window    time.Duration
+			Action    string
+		}
+	}
+	Reports struct {
+		MaxPerHour int
+	}
+	Push struct {
+		Enabled bool
+		Mode    string
+		Workers int
+		Fake    bool
+		FCM     struct {
+			CredentialsFile string
+		}
+		APNs struct {
+			KeyFile string
+			KeyID   string
+			TeamID  string
+			Topic   string
+			Sandbox bool
+		}
+		UnifiedPush struct {
+			Enabled bool
+			Timeout time.Duration
+		}
+	}
+	Webhooks struct {
+		Enabled      bool
+		Workers      int
+		MaxAttempts  int
+		BaseDelay    time.Duration
+		MaxDelay     time.Duration
+		Timeout      time.Duration
+		Retention    time.Duration
+		MaxPerUser   int
+		AllowHTTP    bool
+		AllowPrivate bool
+	}
+	Schedule struct {
+		Enabled    bool
+		MaxPending int
+	}
+	Search struct {
+		Index     string
+		BlevePath string
+	}
+	Bots struct {
+		MaxPerUser         int
+		MaxIncomingPerUser int
+	}
+	Health struct {
+		Prefix  string
+		Timeout time.Duration
+	}
+	Metrics struct {
+		Addr  string
+		Path  string
+		Token string
+	}
 	CORS struct {
 		AllowOrigins []string
 		AllowMethods []string
@@ -23,6 +142,17 @@ type Config struct {
 	}
 }
 
+// RatePolicy é uma política de rate limit: key "ip" ou "user"; routes no
+// formato "POST /auth/login" (vazio vale para todas as rotas).
+type RatePolicy struct {
+	Name   string
+	Key    string
+	Routes []string
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.AddConfigPath("./config")
@@ -30,6 +160,65 @@ func LoadConfig() (*Config, error) {
 	viper.AddConfigPath("$HOME/.wisp")
 	viper.AutomaticEnv()
 
+	viper.SetDefault("app.shutdownTimeout", "30s")
+	viper.SetDefault("health.timeout", "2s")
+	viper.SetDefault("reports.maxPerHour", 10)
+	viper.SetDefault("push.enabled", true)
+	viper.SetDefault("push.mode", "preview")
+	viper.SetDefault("push.workers", 8)
+	viper.SetDefault("push.unifiedPush.enabled", true)
+	viper.SetDefault("push.unifiedPush.timeout", "10s")
+	viper.SetDefault("webhooks.enabled", true)
+	viper.SetDefault("webhooks.workers", 4)
+	viper.SetDefault("webhooks.maxAttempts", 8)
+	viper.SetDefault("webhooks.baseDelay", "10s")
+	viper.SetDefault("webhooks.maxDelay", "1h")
+	viper.SetDefault("webhooks.timeout", "10s")
+	viper.SetDefault("webhooks.retention", "720h")
+	viper.SetDefault("webhooks.maxPerUser", 5)
+	viper.SetDefault("schedule.enabled", true)
+	viper.SetDefault("schedule.maxPending", 100)
+	viper.SetDefault("search.index", "mongo")
+	viper.SetDefault("search.blevePath", "data/search.bleve")
+	viper.SetDefault("bots.maxPerUser", 10)
+	viper.SetDefault("bots.maxIncomingPerUser", 20)
+	viper.SetDefault("rateLimit.enabled", true)
+	viper.SetDefault("rateLimit.store", "memory")
+	viper.SetDefault("rateLimit.policies", []map[string]any{
+		{"name": "ip", "key": "ip", "limit": 300, "period": "1m"},
+		{"name": "auth", "key": "ip", "routes": []string{"POST /auth/login", "POST /auth/register"}, "limit": 10, "period": "1m"},
+		{"name": "check", "key": "ip", "routes": []string{"GET /check"}, "limit": 20, "period": "1m"},
+		{"name": "user", "key": "user", "limit": 600, "period": "1m"},
+	})
+	viper.SetDefault("rateLimit.wsFrames.limit", 20)
+	viper.SetDefault("rateLimit.wsFrames.period", "1s")
+	viper.SetDefault("rateLimit.wsFrames.burst", 40)
+	viper.SetDefault("filters.enabled", true)
+	viper.SetDefault("filters.suspendDuration", "24h")
+	viper.SetDefault("filters.senderRate.limit", 120)
+	viper.SetDefault("filters.senderRate.window", "1m")
+	viper.SetDefault("filters.senderRate.action", "drop")
+	viper.SetDefault("filters.newRecipients.limit", 30)
+	viper.SetDefault("filters.newRecipients.window", "1h")
+	viper.SetDefault("filters.newRecipients.action", "flag")
+	viper.SetDefault("filters.firstContact.accountAge", "72h")
+	viper.SetDefault("filters.firstContact.limit", 10)
+	viper.SetDefault("filters.firstContact.window", "24h")
+	viper.SetDefault("filters.firstContact.action", "shadow_drop")
+	viper.SetDefault("filters.duplicateContent.minLength", 20)
+	viper.SetDefault("filters.duplicateContent.limit", 20)
+	viper.SetDefault("filters.duplicateContent.window", "10m")
+	viper.SetDefault("filters.duplicateContent.action", "flag")
+	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("tracing.sampleRatio", 1.0)
+	viper.SetDefault("hub.shards", runtime.NumCPU())
+	viper.SetDefault("hub.workers", 16)
+	viper.SetDefault("hub.queueSize", 1024)
+	viper.SetDefault("hub.dedupWindow", "24h")
+	viper.SetDefault("hub.slowConsumerTimeout", "30s")
+	viper.SetDefault("hub.restartDelay", "2s")
+	viper.SetDefault("hub.restartJitter", "10s")
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,74 @@
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"wisp/config"
+	"wisp/src/model"
+)
+
+// Bus leva frames entre instâncias do wisp. Cada instância assina o próprio
+// nodeId e as outras publicam nele.
+type Bus interface {
+	Publish(ctx context.Context, node string, data []byte) error
+	Subscribe(node string, handler func(data []byte)) (func(), error)
+	Close() error
+}
+
+// Presence mapeia userId/deviceId para a instância onde o dispositivo está
+// conectado.
+type Presence interface {
+	Register(ctx context.Context, userID, deviceID, node string) error
+	Unregister(ctx context.Context, userID, deviceID, node string) error
+	Lookup(ctx context.Context, userID string) (map[string]string, error)
+	Heartbeat(ctx context.Context, node string) error
+}
+
+const (
+	KindMessage    = "message"
+	KindFrame      = "frame"
+	KindDisconnect = "disconnect"
+)
+
+// Envelope é o que trafega no bus: uma mensagem a entregar para um usuário
+// ou um frame já serializado para os dispositivos dele.
+type Envelope struct {
+	Kind    string            `json:"kind"`
+	Origin  string            `json:"origin"`
+	UserID  string            `json:"userId"`
+	Message *model.Message    `json:"message,omitempty"`
+	Frame   json.RawMessage   `json:"frame,omitempty"`
+	Trace   map[string]string `json:"trace,omitempty"`
+}
+
+func New(cfg *config.Config) (Bus, error) {
+	switch cfg.Cluster.Bus {
+	case "", "local", "changestream":
+		return NewLocal(), nil
+	case "redis":
+		return NewRedis(cfg.Cluster.RedisAddr, cfg.Cluster.RedisPassword)
+	case "nats":
+		return NewNATS(cfg.Cluster.NatsURL)
+	default:
+		return nil, fmt.Errorf("bus desconhecido: %s", cfg.Cluster.Bus)
+	}
+}
+
+// NodeID devolve o id configurado ou gera um a partir do hostname.
+func NodeID(cfg *config.Config) string {
+	if cfg.Cluster.NodeID != "" {
+		return cfg.Cluster.NodeID
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "wisp"
+	}
+	buf := make([]byte, 3)
+	rand.Read(buf)
+	return host + "-" + hex.EncodeToString(buf)
+}
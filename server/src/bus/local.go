@@ -0,0 +1,104 @@
+package bus
+
+import (
+	"context"
+	"sync"
+)
+
+// Local entrega no próprio processo. Serve para rodar uma instância só e
+// para simular várias instâncias compartilhando o mesmo Local.
+type Local struct {
+	mu       sync.RWMutex
+	handlers map[string]map[int]func([]byte)
+	next     int
+}
+
+func NewLocal() *Local {
+	return &Local{handlers: make(map[string]map[int]func([]byte))}
+}
+
+func (l *Local) Publish(ctx context.Context, node string, data []byte) error {
+	l.mu.RLock()
+	handlers := make([]func([]byte), 0, len(l.handlers[node]))
+	for _, fn := range l.handlers[node] {
+		handlers = append(handlers, fn)
+	}
+	l.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(data)
+	}
+	return nil
+}
+
+func (l *Local) Subscribe(node string, handler func([]byte)) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.handlers[node]; !ok {
+		l.handlers[node] = make(map[int]func([]byte))
+	}
+	id := l.next
+	l.next++
+	l.handlers[node][id] = handler
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.handlers[node], id)
+	}, nil
+}
+
+func (l *Local) Close() error {
+	return nil
+}
+
+type MemoryPresence struct {
+	mu    sync.RWMutex
+	users map[string]map[string]string
+}
+
+func NewMemoryPresence() *MemoryPresence {
+	return &MemoryPresence{users: make(map[string]map[string]string)}
+}
+
+func (p *MemoryPresence) Register(ctx context.Context, userID, deviceID, node string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.users[userID]; !ok {
+		p.users[userID] = make(map[string]string)
+	}
+	p.users[userID][deviceID] = node
+	return nil
+}
+
+func (p *MemoryPresence) Unregister(ctx context.Context, userID, deviceID, node string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	devices, ok := p.users[userID]
+	if !ok || devices[deviceID] != node {
+		return nil
+	}
+	delete(devices, deviceID)
+	if len(devices) == 0 {
+		delete(p.users, userID)
+	}
+	return nil
+}
+
+func (p *MemoryPresence) Lookup(ctx context.Context, userID string) (map[string]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make(map[string]string, len(p.users[userID]))
+	for device, node := range p.users[userID] {
+		out[device] = node
+	}
+	return out, nil
+}
+
+func (p *MemoryPresence) Heartbeat(ctx context.Context, node string) error {
+	return nil
+}
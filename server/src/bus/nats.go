@@ -0,0 +1,189 @@
+package bus
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	natsSubjectPrefix  = "wisp.node."
+	natsPresenceBucket = "wisp_presence"
+)
+
+// Tentativas de gravar a presença quando outra instância alterou o mesmo
+// usuário no meio.
+const natsPresenceAttempts = 5
+
+// NATS leva os frames por subject e guarda a presença num bucket KV do
+// JetStream, uma chave por usuário, para o roteamento consultar um registro
+// só. O servidor precisa estar com o JetStream ligado.
+type NATS struct {
+	conn *nats.Conn
+	kv   nats.KeyValue
+	own  *ownDevices
+}
+
+func NewNATS(url string) (*NATS, error) {
+	conn, err := nats.Connect(url, nats.Name("wisp"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	kv, err := js.KeyValue(natsPresenceBucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:  natsPresenceBucket,
+			History: 1,
+			TTL:     presenceTTL,
+		})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("presença no NATS precisa do JetStream: %w", err)
+	}
+	return &NATS{conn: conn, kv: kv, own: newOwnDevices()}, nil
+}
+
+func (n *NATS) Publish(ctx context.Context, node string, data []byte) error {
+	return n.conn.Publish(natsSubjectPrefix+node, data)
+}
+
+func (n *NATS) Subscribe(node string, handler func([]byte)) (func(), error) {
+	sub, err := n.conn.Subscribe(natsSubjectPrefix+node, func(msg *nats.Msg) {
+		handler(msg.Data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() { sub.Unsubscribe() }, nil
+}
+
+func (n *NATS) Close() error {
+	n.conn.Drain()
+	return nil
+}
+
+func (n *NATS) Register(ctx context.Context, userID, deviceID, node string) error {
+	n.own.add(userID, deviceID)
+	return n.update(userID, func(devices map[string]presenceEntry) bool {
+		devices[deviceID] = newPresenceEntry(node)
+		return true
+	})
+}
+
+func (n *NATS) Unregister(ctx context.Context, userID, deviceID, node string) error {
+	n.own.remove(userID, deviceID)
+	return n.update(userID, func(devices map[string]presenceEntry) bool {
+		if e, ok := devices[deviceID]; !ok || e.Node != node {
+			return false
+		}
+		delete(devices, deviceID)
+		return true
+	})
+}
+
+func (n *NATS) Lookup(ctx context.Context, userID string) (map[string]string, error) {
+	devices, _, err := n.get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	out := make(map[string]string, len(devices))
+	for deviceID, e := range devices {
+		if !e.expired(now) {
+			out[deviceID] = e.Node
+		}
+	}
+	return out, nil
+}
+
+func (n *NATS) Heartbeat(ctx context.Context, node string) error {
+	var errs []error
+	for userID, ids := range n.own.snapshot() {
+		err := n.update(userID, func(devices map[string]presenceEntry) bool {
+			for _, deviceID := range ids {
+				if e, ok := devices[deviceID]; !ok || e.Node == node {
+					devices[deviceID] = newPresenceEntry(node)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func natsPresenceKey(userID string) string {
+	return hex.EncodeToString([]byte(userID))
+}
+
+func (n *NATS) get(userID string) (map[string]presenceEntry, uint64, error) {
+	devices := make(map[string]presenceEntry)
+	e, err := n.kv.Get(natsPresenceKey(userID))
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return devices, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := json.Unmarshal(e.Value(), &devices); err != nil {
+		return nil, 0, err
+	}
+	return devices, e.Revision(), nil
+}
+
+// update aplica fn à presença do usuário com controle de revisão, repetindo
+// se outra instância gravou no meio. Entradas vencidas saem de carona.
+func (n *NATS) update(userID string, fn func(devices map[string]presenceEntry) bool) error {
+	key := natsPresenceKey(userID)
+	for range natsPresenceAttempts {
+		devices, rev, err := n.get(userID)
+		if err != nil {
+			return err
+		}
+		if !fn(devices) {
+			return nil
+		}
+		now := time.Now()
+		for deviceID, e := range devices {
+			if e.expired(now) {
+				delete(devices, deviceID)
+			}
+		}
+
+		switch {
+		case len(devices) == 0 && rev == 0:
+			return nil
+		case len(devices) == 0:
+			err = n.kv.Delete(key, nats.LastRevision(rev))
+		default:
+			data, _ := json.Marshal(devices)
+			if rev == 0 {
+				_, err = n.kv.Create(key, data)
+			} else {
+				_, err = n.kv.Update(key, data, rev)
+			}
+		}
+		if !errors.Is(err, nats.ErrKeyExists) {
+			return err
+		}
+	}
+	return errors.New("presença alterada por outra instância, tente de novo")
+}
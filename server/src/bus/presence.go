@@ -0,0 +1,68 @@
+package bus
+
+import (
+	"sync"
+	"time"
+)
+
+// Dispositivos de instâncias que pararam de mandar heartbeat vencem depois
+// de presenceTTL.
+const presenceTTL = 90 * time.Second
+
+// presenceEntry é o que o Redis e o NATS guardam para cada dispositivo.
+type presenceEntry struct {
+	Node  string `json:"node"`
+	Until int64  `json:"until"`
+}
+
+func newPresenceEntry(node string) presenceEntry {
+	return presenceEntry{Node: node, Until: time.Now().Add(presenceTTL).Unix()}
+}
+
+func (e presenceEntry) expired(now time.Time) bool {
+	return now.Unix() >= e.Until
+}
+
+// ownDevices lembra os dispositivos registrados por esta instância, que o
+// heartbeat renova.
+type ownDevices struct {
+	mu    sync.Mutex
+	users map[string]map[string]struct{}
+}
+
+func newOwnDevices() *ownDevices {
+	return &ownDevices{users: make(map[string]map[string]struct{})}
+}
+
+func (o *ownDevices) add(userID, deviceID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.users[userID]; !ok {
+		o.users[userID] = make(map[string]struct{})
+	}
+	o.users[userID][deviceID] = struct{}{}
+}
+
+func (o *ownDevices) remove(userID, deviceID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.users[userID], deviceID)
+	if len(o.users[userID]) == 0 {
+		delete(o.users, userID)
+	}
+}
+
+func (o *ownDevices) snapshot() map[string][]string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	out := make(map[string][]string, len(o.users))
+	for userID, devices := range o.users {
+		for deviceID := range devices {
+			out[userID] = append(out[userID], deviceID)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisChannelPrefix  = "wisp:node:"
+	redisPresencePrefix = "wisp:presence:"
+)
+
+// Só mexem no dispositivo se ele ainda estiver registrado nesta instância;
+// se ele reconectou em outra, a entrada nova fica.
+var (
+	redisUnregister = redis.NewScript(`
+local v = redis.call('HGET', KEYS[1], ARGV[1])
+if v and cjson.decode(v).node == ARGV[2] then
+	return redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 0`)
+	redisRefresh = redis.NewScript(`
+local v = redis.call('HGET', KEYS[1], ARGV[1])
+if v and cjson.decode(v).node ~= ARGV[2] then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return 1`)
+)
+
+// Redis leva os frames por pub/sub e também guarda a presença, num hash por
+// usuário, para o roteamento consultar um registro só.
+type Redis struct {
+	client *redis.Client
+	own    *ownDevices
+}
+
+func NewRedis(addr, password string) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+	return &Redis{client: client, own: newOwnDevices()}, nil
+}
+
+func (r *Redis) Publish(ctx context.Context, node string, data []byte) error {
+	return r.client.Publish(ctx, redisChannelPrefix+node, data).Err()
+}
+
+func (r *Redis) Subscribe(node string, handler func([]byte)) (func(), error) {
+	sub := r.client.Subscribe(context.Background(), redisChannelPrefix+node)
+	if _, err := sub.Receive(context.Background()); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	go func() {
+		for msg := range sub.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+
+	return func() { sub.Close() }, nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+func (r *Redis) Register(ctx context.Context, userID, deviceID, node string) error {
+	r.own.add(userID, deviceID)
+
+	entry, _ := json.Marshal(newPresenceEntry(node))
+	key := redisPresencePrefix + userID
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, deviceID, entry)
+	pipe.Expire(ctx, key, presenceTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *Redis) Unregister(ctx context.Context, userID, deviceID, node string) error {
+	r.own.remove(userID, deviceID)
+	return redisUnregister.Run(ctx, r.client, []string{redisPresencePrefix + userID}, deviceID, node).Err()
+}
+
+func (r *Redis) Lookup(ctx context.Context, userID string) (map[string]string, error) {
+	fields, err := r.client.HGetAll(ctx, redisPresencePrefix+userID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	out := make(map[string]string, len(fields))
+	for deviceID, v := range fields {
+		var e presenceEntry
+		if json.Unmarshal([]byte(v), &e) == nil && !e.expired(now) {
+			out[deviceID] = e.Node
+		}
+	}
+	return out, nil
+}
+
+// Heartbeat renova os dispositivos desta instância num pipeline só.
+func (r *Redis) Heartbeat(ctx context.Context, node string) error {
+	devices := r.own.snapshot()
+	if len(devices) == 0 {
+		return nil
+	}
+	if err := redisRefresh.Load(ctx, r.client).Err(); err != nil {
+		return err
+	}
+
+	entry, _ := json.Marshal(newPresenceEntry(node))
+	ttl := int(presenceTTL.Seconds())
+	pipe := r.client.Pipeline()
+	for userID, ids := range devices {
+		for _, deviceID := range ids {
+			redisRefresh.EvalSha(ctx, pipe, []string{redisPresencePrefix + userID}, deviceID, node, entry, ttl)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,166 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Action é o que fazer com uma mensagem que caiu num filtro. A ordem das
+// constantes é a da severidade: quando mais de um filtro dispara, vale a
+// mais severa.
+type Action int
+
+const (
+	Allow Action = iota
+	Flag
+	ShadowDrop
+	Drop
+	Suspend
+)
+
+func (a Action) String() string {
+	switch a {
+	case Flag:
+		return "flag"
+	case ShadowDrop:
+		return "shadow_drop"
+	case Drop:
+		return "drop"
+	case Suspend:
+		return "suspend"
+	}
+	return "allow"
+}
+
+// ParseAction lê a ação configurada; vazio equivale a drop.
+func ParseAction(s string) (Action, error) {
+	switch s {
+	case "", "drop":
+		return Drop, nil
+	case "flag":
+		return Flag, nil
+	case "shadow_drop":
+		return ShadowDrop, nil
+	case "suspend":
+		return Suspend, nil
+	}
+	return Allow, fmt.Errorf("ação de filtro desconhecida: %q", s)
+}
+
+type Decision struct {
+	Action Action
+	Filter string
+	Reason string
+}
+
+// MessageFilter avalia uma mensagem antes da entrega. Filtros guardam o
+// próprio estado e precisam aceitar chamadas concorrentes. Window é a janela
+// de contagem do filtro.
+type MessageFilter interface {
+	Name() string
+	Window() time.Duration
+	Check(ctx context.Context, msg *model.Message) Decision
+}
+
+// Flagger abre uma denúncia automática para a mensagem.
+type Flagger interface {
+	FlagMessage(ctx context.Context, msg *model.Message, reason string) error
+}
+
+// Suspender suspende o remetente por d.
+type Suspender interface {
+	AutoSuspend(ctx context.Context, userID, reason string, d time.Duration) error
+}
+
+// Chain roda os filtros em ordem e aplica os efeitos colaterais da decisão
+// (denúncia ou suspensão). Descartar a mensagem fica a cargo de quem chama.
+// Os efeitos valem uma vez por remetente e filtro a cada janela do filtro:
+// quem passou do limite dispara em toda mensagem seguinte, e cada disparo
+// viraria uma nota e uma suspensão a mais.
+type Chain struct {
+	filters         []MessageFilter
+	flagger         Flagger
+	suspender       Suspender
+	suspendDuration time.Duration
+
+	mu      sync.Mutex
+	applied map[string]time.Time // remetente|filtro -> fim da janela
+}
+
+func NewChain(flagger Flagger, suspender Suspender, suspendDuration time.Duration, filters ...MessageFilter) *Chain {
+	return &Chain{
+		filters:         filters,
+		flagger:         flagger,
+		suspender:       suspender,
+		suspendDuration: suspendDuration,
+		applied:         make(map[string]time.Time),
+	}
+}
+
+func (c *Chain) Check(ctx context.Context, msg *model.Message) Decision {
+	verdict := Decision{Action: Allow}
+	var window time.Duration
+	for _, f := range c.filters {
+		d := f.Check(ctx, msg)
+		if d.Action == Allow {
+			continue
+		}
+		d.Filter = f.Name()
+		metrics.MessagesFiltered.WithLabelValues(d.Filter, d.Action.String()).Inc()
+		if d.Action > verdict.Action {
+			verdict, window = d, f.Window()
+		}
+	}
+
+	// Suspensões automáticas também vão para a fila, para revisão humana.
+	reason := verdict.Filter + ": " + verdict.Reason
+	act := verdict.Action == Flag || verdict.Action == Suspend
+	if act && c.firstInWindow(msg.From, verdict.Filter, window) {
+		if c.flagger != nil {
+			if err := c.flagger.FlagMessage(ctx, msg, reason); err != nil {
+				log.Error().Err(err).Str("from", msg.From).Msg("Erro ao denunciar mensagem filtrada")
+			}
+		}
+		if verdict.Action == Suspend && c.suspender != nil {
+			if err := c.suspender.AutoSuspend(ctx, msg.From, reason, c.suspendDuration); err != nil {
+				log.Error().Err(err).Str("from", msg.From).Msg("Erro ao suspender remetente")
+			}
+		}
+	}
+	if verdict.Action != Allow {
+		log.Info().Str("from", msg.From).Str("to", msg.To).Str("filter", verdict.Filter).
+			Str("action", verdict.Action.String()).Str("reason", verdict.Reason).Msg("Mensagem retida pelo filtro")
+	}
+	return verdict
+}
+
+// firstInWindow diz se é o primeiro disparo do filtro para o remetente
+// dentro da janela e, se for, o registra.
+func (c *Chain) firstInWindow(from, filter string, window time.Duration) bool {
+	key := from + "|" + filter
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until, ok := c.applied[key]; ok && now.Before(until) {
+		return false
+	}
+	if len(c.applied) >= cacheLimit {
+		for k, until := range c.applied {
+			if !now.Before(until) {
+				delete(c.applied, k)
+			}
+		}
+		if len(c.applied) >= cacheLimit {
+			c.applied = make(map[string]time.Time)
+		}
+	}
+	c.applied[key] = now.Add(window)
+	return true
+}
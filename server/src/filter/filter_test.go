@@ -0,0 +1,194 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"wisp/src/model"
+)
+
+// fixed dispara sempre com a mesma ação.
+type fixed struct {
+	name   string
+	action Action
+}
+
+func (f fixed) Name() string          { return f.name }
+func (f fixed) Window() time.Duration { return time.Minute }
+func (f fixed) Check(context.Context, *model.Message) Decision {
+	return Decision{Action: f.action, Reason: f.name}
+}
+
+type effects struct {
+	mu        sync.Mutex
+	flags     []string
+	suspended []string
+}
+
+func (e *effects) FlagMessage(ctx context.Context, msg *model.Message, reason string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.flags = append(e.flags, reason)
+	return nil
+}
+
+func (e *effects) AutoSuspend(ctx context.Context, userID, reason string, d time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.suspended = append(e.suspended, userID)
+	return nil
+}
+
+func TestActionOrder(t *testing.T) {
+	order := []Action{Allow, Flag, ShadowDrop, Drop, Suspend}
+	for i := 1; i < len(order); i++ {
+		if order[i-1] >= order[i] {
+			t.Errorf("%s deveria ser menos severa que %s", order[i-1], order[i])
+		}
+	}
+
+	for _, a := range order[1:] {
+		if got, err := ParseAction(a.String()); err != nil || got != a {
+			t.Errorf("ParseAction(%q) = %s, %v", a.String(), got, err)
+		}
+	}
+	if got, _ := ParseAction(""); got != Drop {
+		t.Errorf("ação vazia virou %s, esperava drop", got)
+	}
+	if _, err := ParseAction("ban"); err == nil {
+		t.Error("ação desconhecida aceita")
+	}
+}
+
+func TestChainMostSevereWins(t *testing.T) {
+	cases := []struct {
+		actions []Action
+		want    Action
+		filter  string
+	}{
+		{nil, Allow, ""},
+		{[]Action{Allow, Allow}, Allow, ""},
+		{[]Action{Flag, Allow}, Flag, "f0"},
+		{[]Action{Flag, ShadowDrop}, ShadowDrop, "f1"},
+		{[]Action{Drop, ShadowDrop, Flag}, Drop, "f0"},
+		{[]Action{Flag, Suspend, Drop}, Suspend, "f1"},
+		{[]Action{Drop, Drop}, Drop, "f0"},
+	}
+	for _, c := range cases {
+		var filters []MessageFilter
+		for i, a := range c.actions {
+			filters = append(filters, fixed{name: fmt.Sprintf("f%d", i), action: a})
+		}
+		d := NewChain(nil, nil, time.Hour, filters...).Check(context.Background(), &model.Message{From: "alice01", To: "bob0001"})
+		if d.Action != c.want || d.Filter != c.filter {
+			t.Errorf("%v: %s de %q, esperava %s de %q", c.actions, d.Action, d.Filter, c.want, c.filter)
+		}
+	}
+}
+
+func TestChainEffectsOncePerWindow(t *testing.T) {
+	cases := []struct {
+		action           Action
+		flags, suspended int
+	}{
+		{Flag, 1, 0},
+		{ShadowDrop, 0, 0},
+		{Drop, 0, 0},
+		{Suspend, 1, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.action.String(), func(t *testing.T) {
+			e := &effects{}
+			chain := NewChain(e, e, time.Hour, fixed{name: "f", action: c.action})
+			for range 5 {
+				chain.Check(context.Background(), &model.Message{From: "alice01", To: "bob0001"})
+			}
+			chain.Check(context.Background(), &model.Message{From: "carol01", To: "bob0001"})
+
+			// Um disparo por remetente: alice01 uma vez, carol01 outra.
+			if len(e.flags) != 2*c.flags || len(e.suspended) != 2*c.suspended {
+				t.Fatalf("%d denúncias e %d suspensões", len(e.flags), len(e.suspended))
+			}
+		})
+	}
+}
+
+type fakeDirectory struct {
+	contacts map[string]bool
+	created  map[string]time.Time
+	err      error
+}
+
+func (d fakeDirectory) IsContact(ctx context.Context, ownerID, otherID string) (bool, error) {
+	return d.contacts[ownerID+":"+otherID], d.err
+}
+
+func (d fakeDirectory) CreatedAt(ctx context.Context, userID string) (time.Time, error) {
+	return d.created[userID], nil
+}
+
+// fire envia uma mensagem de alice01 para cada destinatário e devolve a
+// ação da última.
+func fire(f MessageFilter, content string, to ...string) Action {
+	var d Decision
+	for _, recipient := range to {
+		d = f.Check(context.Background(), &model.Message{From: "alice01", To: recipient, Content: content})
+	}
+	return d.Action
+}
+
+func TestRules(t *testing.T) {
+	old := fakeDirectory{
+		contacts: map[string]bool{"alice01:bob0001": true},
+		created:  map[string]time.Time{"alice01": time.Now().Add(-30 * 24 * time.Hour)},
+	}
+	young := fakeDirectory{created: map[string]time.Time{"alice01": time.Now()}}
+	broken := fakeDirectory{err: errors.New("banco fora"), created: young.created}
+	spam := "compre agora na loja do link"
+
+	cases := []struct {
+		name    string
+		filter  MessageFilter
+		content string
+		to      []string
+		want    Action
+	}{
+		{"sender_rate no limite", NewSenderRate(3, time.Minute, Drop), "", []string{"b", "b", "b"}, Allow},
+		{"sender_rate acima", NewSenderRate(3, time.Minute, Drop), "", []string{"b", "b", "b", "b"}, Drop},
+		{"new_recipients repetido", NewNewRecipients(old, 2, time.Minute, Flag), "", []string{"c", "c", "c"}, Allow},
+		{"new_recipients acima", NewNewRecipients(old, 2, time.Minute, Flag), "", []string{"c", "d", "e"}, Flag},
+		{"new_recipients contato", NewNewRecipients(old, 0, time.Minute, Flag), "", []string{"bob0001"}, Allow},
+		{"new_recipients erro no banco", NewNewRecipients(broken, 0, time.Minute, Flag), "", []string{"c"}, Allow},
+		{"first_contact conta antiga", NewFirstContact(old, time.Hour, 1, time.Minute, Suspend), "", []string{"c", "d"}, Allow},
+		{"first_contact conta nova", NewFirstContact(young, time.Hour, 1, time.Minute, Suspend), "", []string{"c", "d"}, Suspend},
+		{"duplicate_content texto curto", NewDuplicateContent(10, 1, time.Minute, ShadowDrop), "oi", []string{"c", "d"}, Allow},
+		{"duplicate_content acima", NewDuplicateContent(10, 1, time.Minute, ShadowDrop), spam, []string{"c", "d"}, ShadowDrop},
+	}
+	for _, c := range cases {
+		if got := fire(c.filter, c.content, c.to...); got != c.want {
+			t.Errorf("%s: %s, esperava %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDuplicateContentIgnoresCaseAndSpacing(t *testing.T) {
+	f := NewDuplicateContent(10, 1, time.Minute, Drop)
+	fire(f, "Compre  AGORA na loja", "c")
+	if got := fire(f, "compre agora na   loja", "d"); got != Drop {
+		t.Fatalf("variação de caixa e espaços passou: %s", got)
+	}
+}
+
+func TestWindowSlides(t *testing.T) {
+	w := newWindow(time.Minute)
+	start := time.Now()
+	for i := range 3 {
+		w.add("k", start.Add(time.Duration(i)*time.Second))
+	}
+	if n := w.add("k", start.Add(61*time.Second)); n != 2 {
+		t.Fatalf("depois de a janela andar: %d eventos, esperava 2", n)
+	}
+}
@@ -0,0 +1,212 @@
+package filter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"wisp/src/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Directory dá aos filtros o que eles precisam saber sobre as contas.
+type Directory interface {
+	IsContact(ctx context.Context, ownerID, otherID string) (bool, error)
+	CreatedAt(ctx context.Context, userID string) (time.Time, error)
+}
+
+// SenderRate limita quantas mensagens um remetente envia por janela.
+type SenderRate struct {
+	limit  int
+	action Action
+	counts *window
+}
+
+func NewSenderRate(limit int, per time.Duration, action Action) *SenderRate {
+	return &SenderRate{limit: limit, action: action, counts: newWindow(per)}
+}
+
+func (f *SenderRate) Name() string { return "sender_rate" }
+
+func (f *SenderRate) Window() time.Duration { return f.counts.size }
+
+func (f *SenderRate) Check(_ context.Context, msg *model.Message) Decision {
+	if n := f.counts.add(msg.From, time.Now()); n > f.limit {
+		return Decision{Action: f.action, Reason: fmt.Sprintf("%d mensagens em %s", n, f.counts.size)}
+	}
+	return Decision{}
+}
+
+// NewRecipients limita para quantas pessoas fora dos contatos um remetente
+// escreve por janela.
+type NewRecipients struct {
+	dir        Directory
+	limit      int
+	action     Action
+	recipients *distinct
+}
+
+func NewNewRecipients(dir Directory, limit int, per time.Duration, action Action) *NewRecipients {
+	return &NewRecipients{dir: dir, limit: limit, action: action, recipients: newDistinct(per)}
+}
+
+func (f *NewRecipients) Name() string { return "new_recipients" }
+
+func (f *NewRecipients) Window() time.Duration { return f.recipients.size }
+
+func (f *NewRecipients) Check(ctx context.Context, msg *model.Message) Decision {
+	if isContact(ctx, f.dir, msg) {
+		return Decision{}
+	}
+	if n := f.recipients.add(msg.From, msg.To, time.Now()); n > f.limit {
+		return Decision{Action: f.action, Reason: fmt.Sprintf("%d destinatários novos em %s", n, f.recipients.size)}
+	}
+	return Decision{}
+}
+
+// FirstContact aplica um limite mais baixo de destinatários fora dos
+// contatos a contas criadas há menos de maxAge.
+type FirstContact struct {
+	dir        Directory
+	maxAge     time.Duration
+	limit      int
+	action     Action
+	recipients *distinct
+}
+
+func NewFirstContact(dir Directory, maxAge time.Duration, limit int, per time.Duration, action Action) *FirstContact {
+	return &FirstContact{dir: dir, maxAge: maxAge, limit: limit, action: action, recipients: newDistinct(per)}
+}
+
+func (f *FirstContact) Name() string { return "first_contact" }
+
+func (f *FirstContact) Window() time.Duration { return f.recipients.size }
+
+func (f *FirstContact) Check(ctx context.Context, msg *model.Message) Decision {
+	created, err := f.dir.CreatedAt(ctx, msg.From)
+	if err != nil || time.Since(created) >= f.maxAge {
+		return Decision{}
+	}
+	if isContact(ctx, f.dir, msg) {
+		return Decision{}
+	}
+	if n := f.recipients.add(msg.From, msg.To, time.Now()); n > f.limit {
+		return Decision{Action: f.action, Reason: fmt.Sprintf("conta nova com %d primeiros contatos em %s", n, f.recipients.size)}
+	}
+	return Decision{}
+}
+
+// DuplicateContent detecta o mesmo texto enviado pelo mesmo remetente a
+// muitos destinatários. Textos curtos ("ok", "oi") são ignorados.
+type DuplicateContent struct {
+	minLength  int
+	limit      int
+	action     Action
+	recipients *distinct
+}
+
+func NewDuplicateContent(minLength, limit int, per time.Duration, action Action) *DuplicateContent {
+	return &DuplicateContent{minLength: minLength, limit: limit, action: action, recipients: newDistinct(per)}
+}
+
+func (f *DuplicateContent) Name() string { return "duplicate_content" }
+
+func (f *DuplicateContent) Window() time.Duration { return f.recipients.size }
+
+func (f *DuplicateContent) Check(_ context.Context, msg *model.Message) Decision {
+	text := strings.Join(strings.Fields(strings.ToLower(msg.Content)), " ")
+	if len(text) < f.minLength {
+		return Decision{}
+	}
+	sum := sha256.Sum256([]byte(text))
+	key := msg.From + ":" + hex.EncodeToString(sum[:8])
+	if n := f.recipients.add(key, msg.To, time.Now()); n > f.limit {
+		return Decision{Action: f.action, Reason: fmt.Sprintf("mesmo conteúdo para %d destinatários em %s", n, f.recipients.size)}
+	}
+	return Decision{}
+}
+
+// Em caso de erro na consulta, o destinatário conta como contato: um
+// problema no banco não deve bloquear conversas legítimas.
+func isContact(ctx context.Context, dir Directory, msg *model.Message) bool {
+	ok, err := dir.IsContact(ctx, msg.From, msg.To)
+	if err != nil {
+		log.Error().Err(err).Str("from", msg.From).Msg("Erro ao consultar contatos no filtro")
+		return true
+	}
+	return ok
+}
+
+// CachedDirectory guarda as respostas de outro Directory por ttl, para que
+// os filtros não consultem o banco a cada mensagem.
+type CachedDirectory struct {
+	dir     Directory
+	ttl     time.Duration
+	mu      sync.Mutex
+	contact map[string]cached[bool]
+	created map[string]cached[time.Time]
+}
+
+type cached[T any] struct {
+	value T
+	at    time.Time
+}
+
+const cacheLimit = 100000
+
+func NewCachedDirectory(dir Directory, ttl time.Duration) *CachedDirectory {
+	return &CachedDirectory{
+		dir:     dir,
+		ttl:     ttl,
+		contact: make(map[string]cached[bool]),
+		created: make(map[string]cached[time.Time]),
+	}
+}
+
+func (d *CachedDirectory) IsContact(ctx context.Context, ownerID, otherID string) (bool, error) {
+	key := ownerID + ":" + otherID
+	d.mu.Lock()
+	c, ok := d.contact[key]
+	d.mu.Unlock()
+	if ok && time.Since(c.at) < d.ttl {
+		return c.value, nil
+	}
+
+	v, err := d.dir.IsContact(ctx, ownerID, otherID)
+	if err != nil {
+		return false, err
+	}
+	d.mu.Lock()
+	if len(d.contact) >= cacheLimit {
+		d.contact = make(map[string]cached[bool])
+	}
+	d.contact[key] = cached[bool]{value: v, at: time.Now()}
+	d.mu.Unlock()
+	return v, nil
+}
+
+// A data de criação não muda, então fica no cache até ele encher.
+func (d *CachedDirectory) CreatedAt(ctx context.Context, userID string) (time.Time, error) {
+	d.mu.Lock()
+	c, ok := d.created[userID]
+	d.mu.Unlock()
+	if ok {
+		return c.value, nil
+	}
+
+	v, err := d.dir.CreatedAt(ctx, userID)
+	if err != nil {
+		return v, err
+	}
+	d.mu.Lock()
+	if len(d.created) >= cacheLimit {
+		d.created = make(map[string]cached[time.Time])
+	}
+	d.created[userID] = cached[time.Time]{value: v, at: time.Now()}
+	d.mu.Unlock()
+	return v, nil
+}
@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"sync"
+	"time"
+)
+
+// window conta eventos por chave numa janela deslizante, em memória. Cada
+// instância conta só o tráfego que passa por ela.
+type window struct {
+	size      time.Duration
+	mu        sync.Mutex
+	events    map[string][]time.Time
+	lastSweep time.Time
+}
+
+func newWindow(size time.Duration) *window {
+	return &window{size: size, events: make(map[string][]time.Time)}
+}
+
+// add registra um evento e devolve quantos a chave tem dentro da janela.
+func (w *window) add(key string, now time.Time) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.sweep(now)
+	ev := prune(w.events[key], now.Add(-w.size))
+	ev = append(ev, now)
+	w.events[key] = ev
+	return len(ev)
+}
+
+func (w *window) sweep(now time.Time) {
+	if now.Sub(w.lastSweep) < w.size {
+		return
+	}
+	w.lastSweep = now
+	cutoff := now.Add(-w.size)
+	for k, ev := range w.events {
+		if ev = prune(ev, cutoff); len(ev) == 0 {
+			delete(w.events, k)
+		} else {
+			w.events[k] = ev
+		}
+	}
+}
+
+func prune(ev []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(ev) && !ev[i].After(cutoff) {
+		i++
+	}
+	return ev[i:]
+}
+
+// distinct conta valores distintos por chave numa janela deslizante.
+type distinct struct {
+	size      time.Duration
+	mu        sync.Mutex
+	seen      map[string]map[string]time.Time
+	lastSweep time.Time
+}
+
+func newDistinct(size time.Duration) *distinct {
+	return &distinct{size: size, seen: make(map[string]map[string]time.Time)}
+}
+
+// add registra value sob key e devolve quantos valores distintos a chave tem
+// dentro da janela.
+func (d *distinct) add(key, value string, now time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep(now)
+	set, ok := d.seen[key]
+	if !ok {
+		set = make(map[string]time.Time)
+		d.seen[key] = set
+	}
+	cutoff := now.Add(-d.size)
+	for v, at := range set {
+		if !at.After(cutoff) {
+			delete(set, v)
+		}
+	}
+	set[value] = now
+	return len(set)
+}
+
+func (d *distinct) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.size {
+		return
+	}
+	d.lastSweep = now
+	cutoff := now.Add(-d.size)
+	for k, set := range d.seen {
+		for v, at := range set {
+			if !at.After(cutoff) {
+				delete(set, v)
+			}
+		}
+		if len(set) == 0 {
+			delete(d.seen, k)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"wisp/src/middleware"
+	"wisp/src/model"
+	"wisp/src/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	audit      *service.AuditService
+	moderation *service.ModerationService
+	reports    *service.ReportService
+}
+
+func NewAdminHandler(a *service.AuditService, m *service.ModerationService, r *service.ReportService) *AdminHandler {
+	return &AdminHandler{audit: a, moderation: m, reports: r}
+}
+
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	uid := c.Param("userId")
+	var body struct {
+		Reason   string     `json:"reason"   binding:"required"`
+		Until    *time.Time `json:"until"`
+		Duration string     `json:"duration"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkTarget(c, uid) {
+		return
+	}
+
+	until, err := suspensionEnd(body.Until, body.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := h.moderation.Suspend(c.Request.Context(), uid, body.Reason, until)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditUserSuspend, "user", uid)
+	ev.Reason = body.Reason
+	ev.Before = statusSnapshot(before)
+	ev.After = map[string]any{"status": model.UserSuspended, "suspendedUntil": until}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) BanUser(c *gin.Context) {
+	uid := c.Param("userId")
+	var body struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkTarget(c, uid) {
+		return
+	}
+
+	before, err := h.moderation.Ban(c.Request.Context(), uid, body.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditUserBan, "user", uid)
+	ev.Reason = body.Reason
+	ev.Before = statusSnapshot(before)
+	ev.After = map[string]any{"status": model.UserBanned}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ReinstateUser(c *gin.Context) {
+	uid := c.Param("userId")
+
+	before, err := h.moderation.Reinstate(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditUserReinstate, "user", uid)
+	ev.Before = statusSnapshot(before)
+	ev.After = map[string]any{"status": model.UserActive}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ForceLogout(c *gin.Context) {
+	uid := c.Param("userId")
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	// O corpo é opcional.
+	c.ShouldBindJSON(&body)
+
+	if err := h.moderation.ForceLogout(c.Request.Context(), uid, body.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditUserForceLogout, "user", uid)
+	ev.Reason = body.Reason
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.Status(http.StatusNoContent)
+}
+
+// checkTarget impede que o admin bloqueie a própria conta.
+func (h *AdminHandler) checkTarget(c *gin.Context, uid string) bool {
+	if uid == c.GetString("userId") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "não é possível moderar a própria conta"})
+		return false
+	}
+	return true
+}
+
+// suspensionEnd aceita o fim da suspensão como horário ou como duração
+// ("72h") a partir de agora.
+func suspensionEnd(until *time.Time, duration string) (time.Time, error) {
+	if until != nil {
+		return *until, nil
+	}
+	if duration == "" {
+		return time.Time{}, errors.New("informe until ou duration")
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return time.Time{}, errors.New("duração inválida")
+	}
+	return time.Now().Add(d), nil
+}
+
+func statusSnapshot(u *model.User) map[string]any {
+	status := u.Status
+	if status == "" {
+		status = model.UserActive
+	}
+	snap := map[string]any{"status": status}
+	if u.StatusReason != "" {
+		snap["statusReason"] = u.StatusReason
+	}
+	if u.SuspendedUntil != nil {
+		snap["suspendedUntil"] = *u.SuspendedUntil
+	}
+	return snap
+}
+
+func (h *AdminHandler) ListAudit(c *gin.Context) {
+	f, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, limit := pagination(c)
+
+	events, total, err := h.audit.Query(c.Request.Context(), f, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+		"events": events,
+	})
+}
+
+// ExportAudit envia todos os eventos do filtro em CSV ou JSON, em streaming.
+func (h *AdminHandler) ExportAudit(c *gin.Context) {
+	f, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "formato deve ser json ou csv"})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditAdminAuditExport, "audit", "")
+	ev.After = map[string]any{"format": format, "query": c.Request.URL.RawQuery}
+	h.audit.Record(c.Request.Context(), ev)
+
+	name := "audit-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+
+	if format == "csv" {
+		err = h.exportCSV(c, f)
+	} else {
+		err = h.exportJSON(c, f)
+	}
+	if err != nil {
+		// O status já foi enviado; resta registrar o corte.
+		middleware.Logger(c).Error().Err(err).Msg("Erro ao exportar auditoria")
+	}
+}
+
+func (h *AdminHandler) exportCSV(c *gin.Context, f model.AuditFilter) error {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"createdAt", "action", "actorId", "adminAction", "targetType", "targetId", "ip", "userAgent", "requestId", "success", "reason", "before", "after"})
+	err := h.audit.Export(c.Request.Context(), f, func(ev *model.AuditEvent) error {
+		before, _ := json.Marshal(ev.Before)
+		after, _ := json.Marshal(ev.After)
+		return w.Write([]string{
+			ev.CreatedAt.UTC().Format(time.RFC3339),
+			ev.Action,
+			ev.ActorID,
+			strconv.FormatBool(ev.AdminAction),
+			ev.TargetType,
+			ev.TargetID,
+			ev.IP,
+			ev.UserAgent,
+			ev.RequestID,
+			strconv.FormatBool(ev.Success),
+			ev.Reason,
+			string(before),
+			string(after),
+		})
+	})
+	w.Flush()
+	return errors.Join(err, w.Error())
+}
+
+func (h *AdminHandler) exportJSON(c *gin.Context, f model.AuditFilter) error {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	first := true
+	c.Writer.WriteString("[")
+	err := h.audit.Export(c.Request.Context(), f, func(ev *model.AuditEvent) error {
+		if !first {
+			c.Writer.WriteString(",")
+		}
+		first = false
+		return enc.Encode(ev)
+	})
+	c.Writer.WriteString("]")
+	return err
+}
+
+func auditFilter(c *gin.Context) (model.AuditFilter, error) {
+	f := model.AuditFilter{
+		Action:   c.Query("action"),
+		ActorID:  c.Query("actorId"),
+		TargetID: c.Query("targetId"),
+	}
+	var err error
+	if v := c.Query("from"); v != "" {
+		if f.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, errors.New("from deve estar em RFC 3339")
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if f.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, errors.New("to deve estar em RFC 3339")
+		}
+	}
+	return f, nil
+}
+
+func (h *AdminHandler) ListReports(c *gin.Context) {
+	page, limit := pagination(c)
+	f := model.ReportFilter{
+		Status:       c.DefaultQuery("status", model.ReportOpen),
+		AssigneeID:   c.Query("assigneeId"),
+		TargetUserID: c.Query("targetUserId"),
+	}
+	if f.Status == "all" {
+		f.Status = ""
+	}
+
+	reports, total, err := h.reports.List(c.Request.Context(), f, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+		"reports": reports,
+	})
+}
+
+func (h *AdminHandler) GetReport(c *gin.Context) {
+	rep, err := h.reports.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "denúncia não encontrada"})
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
+
+func (h *AdminHandler) AssignReport(c *gin.Context) {
+	var body struct {
+		AssigneeID string `json:"assigneeId"`
+	}
+	c.ShouldBindJSON(&body)
+	if body.AssigneeID == "" {
+		body.AssigneeID = c.GetString("userId")
+	}
+
+	before, err := h.reports.Assign(c.Request.Context(), c.Param("id"), body.AssigneeID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditReportUpdate, "report", c.Param("id"))
+	ev.Before = map[string]any{"assigneeId": before.AssigneeID, "status": before.Status}
+	ev.After = map[string]any{"assigneeId": body.AssigneeID}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) SetReportStatus(c *gin.Context) {
+	var body struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := h.reports.SetStatus(c.Request.Context(), c.Param("id"), body.Status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditReportUpdate, "report", c.Param("id"))
+	ev.Before = map[string]any{"status": before.Status}
+	ev.After = map[string]any{"status": body.Status}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) AddReportNote(c *gin.Context) {
+	var body struct {
+		Text string `json:"text" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.reports.AddNote(c.Request.Context(), c.Param("id"), c.GetString("userId"), body.Text); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ActOnReport suspende ou bane o denunciado e conclui a denúncia.
+func (h *AdminHandler) ActOnReport(c *gin.Context) {
+	var body struct {
+		Action   string     `json:"action"   binding:"required,oneof=suspend ban"`
+		Reason   string     `json:"reason"   binding:"required"`
+		Until    *time.Time `json:"until"`
+		Duration string     `json:"duration"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var until time.Time
+	if body.Action == "suspend" {
+		var err error
+		if until, err = suspensionEnd(body.Until, body.Duration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	rep, before, err := h.reports.Act(c.Request.Context(), c.Param("id"), c.GetString("userId"), body.Action, body.Reason, until)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	action, status := model.AuditUserBan, model.UserBanned
+	if body.Action == "suspend" {
+		action, status = model.AuditUserSuspend, model.UserSuspended
+	}
+	ev := auditEvent(c, action, "user", rep.TargetUserID)
+	ev.Reason = body.Reason
+	ev.Before = statusSnapshot(before)
+	ev.After = map[string]any{"status": status, "reportId": rep.ID.Hex()}
+	if body.Action == "suspend" {
+		ev.After["suspendedUntil"] = until
+	}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"wisp/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditEvent monta o evento com o autor e os dados da requisição atual.
+// AdminAction fica marcado quando um admin age sobre outra conta.
+func auditEvent(c *gin.Context, action, targetType, targetID string) *model.AuditEvent {
+	actor := c.GetString("userId")
+	return &model.AuditEvent{
+		Action:      action,
+		ActorID:     actor,
+		AdminAction: c.GetBool("isAdmin") && targetType == "user" && targetID != actor,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		RequestID:   c.GetString("requestId"),
+		Success:     true,
+	}
+}
+
+// userDiff devolve os valores antigos e novos apenas dos campos alterados.
+func userDiff(u *model.User, upd map[string]any) (before, after map[string]any) {
+	current := map[string]any{
+		"name":    u.Name,
+		"email":   u.Email,
+		"isAdmin": u.IsAdmin,
+	}
+	before = make(map[string]any)
+	after = make(map[string]any)
+	for k, v := range upd {
+		if current[k] != v {
+			before[k] = current[k]
+			after[k] = v
+		}
+	}
+	return before, after
+}
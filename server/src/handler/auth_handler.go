@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"time"
 	"wisp/src/model"
@@ -12,10 +13,12 @@ import (
 type AuthHandler struct {
 	authSvc *service.AuthService
 	userSvc *service.UserService
+	audit   *service.AuditService
+	hooks   *service.WebhookService
 }
 
-func NewAuthHandler(a *service.AuthService, u *service.UserService) *AuthHandler {
-	return &AuthHandler{authSvc: a, userSvc: u}
+func NewAuthHandler(a *service.AuthService, u *service.UserService, audit *service.AuditService, hooks *service.WebhookService) *AuthHandler {
+	return &AuthHandler{authSvc: a, userSvc: u, audit: audit, hooks: hooks}
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -31,6 +34,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
+	h.hooks.Emit(c.Request.Context(), model.EventUserRegistered, "", gin.H{
+		"userId":    u.UserID,
+		"name":      u.Name,
+		"createdAt": u.CreatedAt,
+	})
 
 	c.JSON(http.StatusCreated, u)
 }
@@ -48,9 +56,27 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	token, err := h.authSvc.Login(c.Request.Context(), body.Email, body.Password, body.DeviceID)
 	if err != nil {
+		ev := auditEvent(c, model.AuditLoginFailure, "email", body.Email)
+		ev.Success = false
+		ev.Reason = err.Error()
+		h.audit.Record(c.Request.Context(), ev)
+
+		var blocked *service.AccountBlockedError
+		if errors.As(err, &blocked) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":  err.Error(),
+				"status": blocked.Status,
+				"reason": blocked.Reason,
+				"until":  blocked.Until,
+			})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	ev := auditEvent(c, model.AuditLoginSuccess, "email", body.Email)
+	ev.After = map[string]any{"deviceId": body.DeviceID}
+	h.audit.Record(c.Request.Context(), ev)
 
 	http.SetCookie(c.Writer, &http.Cookie{
 		Name:     "token",
@@ -72,6 +98,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditLogout, "session", sid))
 
 	http.SetCookie(c.Writer, &http.Cookie{
 		Name:     "token",
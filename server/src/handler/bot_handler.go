@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wisp/src/model"
+	"wisp/src/service"
+	"wisp/src/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BotHandler struct {
+	svc   *service.BotService
+	hub   *ws.Hub
+	audit *service.AuditService
+}
+
+func NewBotHandler(s *service.BotService, hub *ws.Hub, audit *service.AuditService) *BotHandler {
+	return &BotHandler{svc: s, hub: hub, audit: audit}
+}
+
+func (h *BotHandler) CreateBot(c *gin.Context) {
+	var body struct {
+		UserID string `json:"userId" binding:"required,len=7"`
+		Name   string `json:"name"   binding:"required,min=3"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bot, err := h.svc.Create(c.Request.Context(), c.GetString("userId"), body.UserID, body.Name)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ev := auditEvent(c, model.AuditBotCreate, "user", bot.UserID)
+	ev.After = map[string]any{"name": bot.Name}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.JSON(http.StatusCreated, bot)
+}
+
+func (h *BotHandler) ListBots(c *gin.Context) {
+	bots, err := h.svc.List(c.Request.Context(), c.GetString("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, bots)
+}
+
+func (h *BotHandler) DeleteBot(c *gin.Context) {
+	err := h.svc.Delete(c.Request.Context(), c.GetString("userId"), c.Param("botId"))
+	if errors.Is(err, service.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditBotDelete, "user", c.Param("botId")))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *BotHandler) CreateToken(c *gin.Context) {
+	var body struct {
+		Name string `json:"name" binding:"required,max=100"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t, raw, err := h.svc.CreateToken(c.Request.Context(), c.GetString("userId"), c.Param("botId"), body.Name)
+	if errors.Is(err, service.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ev := auditEvent(c, model.AuditBotTokenCreate, "api_token", t.ID.Hex())
+	ev.After = map[string]any{"botId": t.BotID, "name": t.Name, "prefix": t.Prefix}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.JSON(http.StatusCreated, gin.H{"token": raw, "apiToken": t})
+}
+
+func (h *BotHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.svc.ListTokens(c.Request.Context(), c.GetString("userId"), c.Param("botId"))
+	if errors.Is(err, service.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *BotHandler) RevokeToken(c *gin.Context) {
+	err := h.svc.RevokeToken(c.Request.Context(), c.GetString("userId"), c.Param("botId"), c.Param("tokenId"))
+	if errors.Is(err, service.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditBotTokenRevoke, "api_token", c.Param("tokenId")))
+	c.Status(http.StatusNoContent)
+}
+
+// SendMessage é o envio REST para bots que não mantêm um WebSocket. A
+// mensagem segue o mesmo caminho das enviadas pelo socket, inclusive os
+// filtros, e a resposta traz o resultado do aceite. Um reenvio com o mesmo
+// clientId devolve o id da mensagem original com duplicate true.
+func (h *BotHandler) SendMessage(c *gin.Context) {
+	if !c.GetBool("isBot") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "endpoint exclusivo para bots"})
+		return
+	}
+	if h.hub.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Servidor reiniciando, tente novamente em instantes"})
+		return
+	}
+
+	var body struct {
+		To       string `json:"to"       binding:"required"`
+		Content  string `json:"content"  binding:"required,max=65536"`
+		ClientID string `json:"clientId" binding:"max=64"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg := &model.Message{
+		Type:      "message",
+		From:      c.GetString("userId"),
+		To:        body.To,
+		Content:   body.Content,
+		Timestamp: time.Now().Unix(),
+		ClientID:  body.ClientID,
+		Bot:       true,
+	}
+	switch frame := h.hub.Send(c.Request.Context(), msg).(type) {
+	case *model.SendConfirmation:
+		c.JSON(http.StatusAccepted, gin.H{"id": frame.MessageID, "to": frame.To, "timestamp": frame.Timestamp, "duplicate": frame.Duplicate})
+	case *model.SendRejected:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": frame.Reason})
+	default:
+		// A requisição acabou antes do aceite; a mensagem segue no hub.
+		c.JSON(http.StatusAccepted, gin.H{"id": msg.ID, "to": msg.To, "timestamp": msg.Timestamp})
+	}
+}
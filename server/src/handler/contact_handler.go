@@ -2,17 +2,20 @@ package handler
 
 import (
 	"net/http"
+	"wisp/src/model"
 	"wisp/src/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ContactHandler struct {
-	svc *service.ContactService
+	svc   *service.ContactService
+	audit *service.AuditService
+	hooks *service.WebhookService
 }
 
-func NewContactHandler(s *service.ContactService) *ContactHandler {
-	return &ContactHandler{svc: s}
+func NewContactHandler(s *service.ContactService, audit *service.AuditService, hooks *service.WebhookService) *ContactHandler {
+	return &ContactHandler{svc: s, audit: audit, hooks: hooks}
 }
 
 func (h *ContactHandler) GetContacts(c *gin.Context) {
@@ -92,6 +95,15 @@ func (h *ContactHandler) SendRequest(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	ev := auditEvent(c, model.AuditFriendRequestSend, "friend_request", id.Hex())
+	ev.After = map[string]any{"toUserId": body.ToUserID}
+	h.audit.Record(c.Request.Context(), ev)
+	h.hooks.Emit(c.Request.Context(), model.EventFriendRequestReceived, body.ToUserID, gin.H{
+		"requestId":  id.Hex(),
+		"fromUserId": uid,
+		"toUserId":   body.ToUserID,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{"requestId": id.Hex()})
 }
 
@@ -109,6 +121,7 @@ func (h *ContactHandler) CancelRequest(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditFriendRequestCancel, "friend_request", c.Param("id")))
 	c.Status(http.StatusNoContent)
 }
 
@@ -126,6 +139,7 @@ func (h *ContactHandler) AcceptRequest(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditFriendRequestAccept, "friend_request", c.Param("id")))
 	c.Status(http.StatusNoContent)
 }
 
@@ -143,6 +157,7 @@ func (h *ContactHandler) RejectRequest(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditFriendRequestReject, "friend_request", c.Param("id")))
 	c.Status(http.StatusNoContent)
 }
 
@@ -160,5 +175,6 @@ func (h *ContactHandler) RemoveContact(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditContactRemove, "contact", c.Param("id")))
 	c.Status(http.StatusNoContent)
 }
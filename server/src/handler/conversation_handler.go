@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"wisp/src/model"
+	"wisp/src/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConversationHandler struct {
+	expiry *service.ExpiryService
+	inbox  *service.InboxService
+}
+
+func NewConversationHandler(expiry *service.ExpiryService, inbox *service.InboxService) *ConversationHandler {
+	return &ConversationHandler{expiry: expiry, inbox: inbox}
+}
+
+// ListConversations devolve a lista de conversas por atividade; com
+// ?archived=true, as arquivadas. A próxima página vem com
+// ?cursor=<nextCursor>.
+func (h *ConversationHandler) ListConversations(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	list, err := h.inbox.List(c.Request.Context(), c.GetString("userId"), c.Query("archived") == "true", c.Query("cursor"), limit)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *ConversationHandler) MarkRead(c *gin.Context) {
+	conv, err := h.inbox.MarkRead(c.Request.Context(), c.GetString("userId"), c.Param("userId"))
+	conversationResult(c, conv, err)
+}
+
+// Mute silencia a conversa por seconds, ou sem prazo com seconds 0.
+func (h *ConversationHandler) Mute(c *gin.Context) {
+	var body struct {
+		Muted   bool  `json:"muted"`
+		Seconds int64 `json:"seconds" binding:"min=0"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv, err := h.inbox.Mute(c.Request.Context(), c.GetString("userId"), c.Param("userId"), body.Muted, time.Duration(body.Seconds)*time.Second)
+	conversationResult(c, conv, err)
+}
+
+func (h *ConversationHandler) Pin(c *gin.Context) {
+	var body struct {
+		Pinned bool `json:"pinned"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv, err := h.inbox.Pin(c.Request.Context(), c.GetString("userId"), c.Param("userId"), body.Pinned)
+	conversationResult(c, conv, err)
+}
+
+// ReorderPins recebe os peerIds das fixadas na nova ordem.
+func (h *ConversationHandler) ReorderPins(c *gin.Context) {
+	var body struct {
+		Order []string `json:"order" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	convs, err := h.inbox.ReorderPins(c.Request.Context(), c.GetString("userId"), body.Order)
+	if errors.Is(err, service.ErrPinOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pinned": convs})
+}
+
+func (h *ConversationHandler) Archive(c *gin.Context) {
+	var body struct {
+		Archived bool `json:"archived"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv, err := h.inbox.Archive(c.Request.Context(), c.GetString("userId"), c.Param("userId"), body.Archived)
+	conversationResult(c, conv, err)
+}
+
+func conversationResult(c *gin.Context, conv *model.Conversation, err error) {
+	switch {
+	case errors.Is(err, service.ErrConversationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPinLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMuteDuration):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, conv)
+	}
+}
+
+func (h *ConversationHandler) GetSettings(c *gin.Context) {
+	s, err := h.expiry.Settings(c.Request.Context(), c.GetString("userId"), c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// SetTimer liga, troca ou desliga (seconds 0) as mensagens temporárias da
+// conversa. Os clientes oferecem 1h, 1 dia e 7 dias, mas qualquer valor
+// dentro dos limites é aceito.
+func (h *ConversationHandler) SetTimer(c *gin.Context) {
+	var body struct {
+		Seconds *int64 `json:"seconds" binding:"required,min=0"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, err := h.expiry.SetTimer(c.Request.Context(), c.GetString("userId"), c.Param("userId"), time.Duration(*body.Seconds)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"wisp/src/repository"
+	"wisp/src/ws"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errDraining = errors.New("servidor em drenagem")
+
+type HealthHandler struct {
+	hub     *ws.Hub
+	mongo   *mongo.Client
+	timeout time.Duration
+}
+
+func NewHealthHandler(hub *ws.Hub, mongoClient *mongo.Client, timeout time.Duration) *HealthHandler {
+	return &HealthHandler{hub: hub, mongo: mongoClient, timeout: timeout}
+}
+
+type checkResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Healthz diz se o processo está vivo: responde enquanto os loops do hub
+// aceitarem um ping dentro do prazo.
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	h.respond(c, map[string]func(context.Context) error{
+		"hub": h.hub.Ping,
+	})
+}
+
+// Readyz diz se a instância pode receber tráfego: MongoDB acessível, índices
+// criados e hub fora do modo de drenagem.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	h.respond(c, map[string]func(context.Context) error{
+		"mongo": func(ctx context.Context) error {
+			return h.mongo.Ping(ctx, nil)
+		},
+		"indexes": func(context.Context) error {
+			return repository.IndexesReady()
+		},
+		"hub": func(context.Context) error {
+			if h.hub.Draining() {
+				return errDraining
+			}
+			return nil
+		},
+	})
+}
+
+func (h *HealthHandler) respond(c *gin.Context, checks map[string]func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	status := http.StatusOK
+	results := make(map[string]checkResult, len(checks))
+	for name, check := range checks {
+		start := time.Now()
+		err := check(ctx)
+		res := checkResult{Status: "ok", Latency: time.Since(start).String()}
+		if err != nil {
+			res.Status = "fail"
+			res.Error = err.Error()
+			status = http.StatusServiceUnavailable
+		}
+		results[name] = res
+	}
+
+	overall := "ok"
+	if status != http.StatusOK {
+		overall = "fail"
+	}
+	c.JSON(status, gin.H{"status": overall, "checks": results})
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wisp/src/model"
+	"wisp/src/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IncomingWebhookHandler struct {
+	svc   *service.IncomingWebhookService
+	audit *service.AuditService
+}
+
+func NewIncomingWebhookHandler(s *service.IncomingWebhookService, audit *service.AuditService) *IncomingWebhookHandler {
+	return &IncomingWebhookHandler{svc: s, audit: audit}
+}
+
+func (h *IncomingWebhookHandler) CreateIncomingWebhook(c *gin.Context) {
+	var body struct {
+		Name  string `json:"name"  binding:"required,max=100"`
+		BotID string `json:"botId" binding:"required"`
+		To    string `json:"to"    binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, secret, err := h.svc.Create(c.Request.Context(), c.GetString("userId"), body.BotID, body.To, body.Name)
+	if errors.Is(err, service.ErrBotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ev := auditEvent(c, model.AuditIncomingWebhookCreate, "incoming_webhook", hook.ID.Hex())
+	ev.After = map[string]any{"botId": hook.BotID, "targetId": hook.TargetID, "name": hook.Name}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": hook, "path": "/hooks/in/" + secret})
+}
+
+func (h *IncomingWebhookHandler) ListIncomingWebhooks(c *gin.Context) {
+	hooks, err := h.svc.List(c.Request.Context(), c.GetString("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *IncomingWebhookHandler) DeleteIncomingWebhook(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.GetString("userId"), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditIncomingWebhookDelete, "incoming_webhook", c.Param("id")))
+	c.Status(http.StatusNoContent)
+}
+
+// Post é a URL pública chamada pelas integrações; o segredo no caminho é a
+// autenticação.
+func (h *IncomingWebhookHandler) Post(c *gin.Context) {
+	var body struct {
+		Text        string             `json:"text"        binding:"max=65536"`
+		ID          string             `json:"id"          binding:"max=64"`
+		Attachments []model.Attachment `json:"attachments" binding:"max=10,dive"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.Text == "" && len(body.Attachments) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "text ou attachments é obrigatório"})
+		return
+	}
+
+	msg, err := h.svc.Post(c.Request.Context(), c.Param("secret"), body.Text, body.ID, body.Attachments)
+	var blocked *service.AccountBlockedError
+	if errors.As(err, &blocked) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": msg.ID, "timestamp": msg.Timestamp})
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wisp/src/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PushHandler struct {
+	svc *service.PushService
+}
+
+func NewPushHandler(s *service.PushService) *PushHandler {
+	return &PushHandler{svc: s}
+}
+
+func (h *PushHandler) RegisterToken(c *gin.Context) {
+	var body struct {
+		DeviceID string `json:"deviceId" binding:"required,max=64"`
+		Provider string `json:"provider" binding:"required"`
+		Token    string `json:"token"    binding:"required,max=4096"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.svc.Register(c.Request.Context(), c.GetString("userId"), body.DeviceID, body.Provider, body.Token)
+	if errors.Is(err, service.ErrPushToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar token de push"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PushHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.svc.List(c.Request.Context(), c.GetString("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar tokens de push"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *PushHandler) DeleteToken(c *gin.Context) {
+	if err := h.svc.Unregister(c.Request.Context(), c.GetString("userId"), c.Param("deviceId")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover token de push"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
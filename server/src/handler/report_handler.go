@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wisp/src/model"
+	"wisp/src/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	svc   *service.ReportService
+	audit *service.AuditService
+}
+
+func NewReportHandler(s *service.ReportService, audit *service.AuditService) *ReportHandler {
+	return &ReportHandler{svc: s, audit: audit}
+}
+
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	var body struct {
+		TargetUserID string                 `json:"targetUserId" binding:"required"`
+		Category     string                 `json:"category"     binding:"required,oneof=spam harassment impersonation illegal other"`
+		Description  string                 `json:"description"  binding:"max=2000"`
+		Message      *model.ReportedMessage `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rep, dup, err := h.svc.Create(c.Request.Context(), &model.Report{
+		ReporterID:   c.GetString("userId"),
+		TargetUserID: body.TargetUserID,
+		Category:     body.Category,
+		Description:  body.Description,
+		Message:      body.Message,
+	})
+	if errors.Is(err, service.ErrReportLimit) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if dup {
+		c.JSON(http.StatusOK, gin.H{"reportId": rep.ID.Hex(), "status": rep.Status})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditReportCreate, "report", rep.ID.Hex())
+	ev.After = map[string]any{"targetUserId": rep.TargetUserID, "category": rep.Category}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.JSON(http.StatusCreated, gin.H{"reportId": rep.ID.Hex(), "status": rep.Status})
+}
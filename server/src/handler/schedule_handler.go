@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wisp/src/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleHandler struct {
+	svc *service.ScheduleService
+}
+
+func NewScheduleHandler(s *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{svc: s}
+}
+
+func (h *ScheduleHandler) CreateScheduled(c *gin.Context) {
+	var body struct {
+		To      string    `json:"to"      binding:"required"`
+		Content string    `json:"content" binding:"required,max=65536"`
+		SendAt  time.Time `json:"sendAt"  binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m, err := h.svc.Create(c.Request.Context(), c.GetString("userId"), body.To, body.Content, body.SendAt)
+	if errors.Is(err, service.ErrScheduleLimit) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, m)
+}
+
+func (h *ScheduleHandler) ListScheduled(c *gin.Context) {
+	msgs, err := h.svc.List(c.Request.Context(), c.GetString("userId"), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, msgs)
+}
+
+func (h *ScheduleHandler) UpdateScheduled(c *gin.Context) {
+	var body struct {
+		To      string     `json:"to"`
+		Content string     `json:"content" binding:"max=65536"`
+		SendAt  *time.Time `json:"sendAt"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m, err := h.svc.Update(c.Request.Context(), c.GetString("userId"), c.Param("id"), body.To, body.Content, body.SendAt)
+	if !scheduleError(c, err) {
+		c.JSON(http.StatusOK, m)
+	}
+}
+
+func (h *ScheduleHandler) CancelScheduled(c *gin.Context) {
+	err := h.svc.Cancel(c.Request.Context(), c.GetString("userId"), c.Param("id"))
+	if !scheduleError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func scheduleError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrScheduledNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrScheduledLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"wisp/src/model"
+	"wisp/src/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	history *service.HistoryService
+}
+
+func NewSearchHandler(history *service.HistoryService) *SearchHandler {
+	return &SearchHandler{history: history}
+}
+
+// Search busca no histórico do usuário autenticado. Filtros: peer (outro
+// participante), since e until (RFC 3339), hasAttachment e fromMe.
+func (h *SearchHandler) Search(c *gin.Context) {
+	page, limit := pagination(c)
+	q := &model.SearchQuery{
+		UserID:        c.GetString("userId"),
+		Text:          c.Query("q"),
+		Peer:          c.Query("peer"),
+		HasAttachment: c.Query("hasAttachment") == "true",
+		FromMe:        c.Query("fromMe") == "true",
+		Limit:         limit,
+	}
+	var err error
+	if v := c.Query("since"); v != "" {
+		if q.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since deve estar em RFC 3339"})
+			return
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if q.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until deve estar em RFC 3339"})
+			return
+		}
+	}
+
+	results, err := h.history.Search(c.Request.Context(), q, page)
+	if errors.Is(err, service.ErrSearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"page":    page,
+		"limit":   q.Limit,
+		"results": results,
+	})
+}
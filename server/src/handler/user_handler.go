@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strconv"
 	"wisp/src/helpers"
+	"wisp/src/middleware"
+	"wisp/src/model"
 	"wisp/src/service"
 
 	"github.com/gin-gonic/gin"
@@ -11,10 +13,13 @@ import (
 
 type UserHandler struct {
 	userSvc *service.UserService
+	audit   *service.AuditService
+	hooks   *service.WebhookService
+	bots    *service.BotService
 }
 
-func NewUserHandler(u *service.UserService) *UserHandler {
-	return &UserHandler{userSvc: u}
+func NewUserHandler(u *service.UserService, audit *service.AuditService, hooks *service.WebhookService, bots *service.BotService) *UserHandler {
+	return &UserHandler{userSvc: u, audit: audit, hooks: hooks, bots: bots}
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
@@ -123,10 +128,26 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "nenhum campo para atualizar"})
 		return
 	}
+	current, err := h.userSvc.GetUser(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "usuário não encontrado"})
+		return
+	}
+	before, after := userDiff(current, upd)
+
 	if err := h.userSvc.UpdateUser(c.Request.Context(), uid, upd); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	action := model.AuditUserUpdate
+	if _, ok := after["isAdmin"]; ok {
+		action = model.AuditUserRoleChange
+	}
+	ev := auditEvent(c, action, "user", uid)
+	ev.Before, ev.After = before, after
+	h.audit.Record(c.Request.Context(), ev)
+
 	c.Status(http.StatusNoContent)
 }
 
@@ -135,9 +156,27 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	if !helpers.CheckAdminOrUidPermission(c, uid) {
 		return
 	}
+	current, err := h.userSvc.GetUser(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "usuário não encontrado"})
+		return
+	}
 	if err := h.userSvc.DeleteUser(c.Request.Context(), uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	ev := auditEvent(c, model.AuditUserDelete, "user", uid)
+	ev.Before = map[string]any{"name": current.Name, "email": current.Email, "isAdmin": current.IsAdmin}
+	h.audit.Record(c.Request.Context(), ev)
+
+	h.hooks.Emit(c.Request.Context(), model.EventUserDeleted, "", gin.H{"userId": uid})
+	if err := h.hooks.DeleteOwner(c.Request.Context(), uid); err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Erro ao remover webhooks do usuário")
+	}
+	if err := h.bots.DeleteOwned(c.Request.Context(), uid); err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Erro ao remover bots do usuário")
+	}
+
 	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wisp/src/model"
+	"wisp/src/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler atende tanto os webhooks do próprio usuário quanto, com
+// global, os webhooks globais que só admins cadastram.
+type WebhookHandler struct {
+	svc    *service.WebhookService
+	audit  *service.AuditService
+	global bool
+}
+
+func NewWebhookHandler(s *service.WebhookService, audit *service.AuditService) *WebhookHandler {
+	return &WebhookHandler{svc: s, audit: audit}
+}
+
+func NewAdminWebhookHandler(s *service.WebhookService, audit *service.AuditService) *WebhookHandler {
+	return &WebhookHandler{svc: s, audit: audit, global: true}
+}
+
+func (h *WebhookHandler) owner(c *gin.Context) string {
+	if h.global {
+		return ""
+	}
+	return c.GetString("userId")
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var body struct {
+		URL    string   `json:"url"    binding:"required,max=2048"`
+		Events []string `json:"events" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, secret, err := h.svc.Create(c.Request.Context(), h.owner(c), body.URL, body.Events)
+	if errors.Is(err, service.ErrWebhookLimit) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ev := auditEvent(c, model.AuditWebhookCreate, "webhook", hook.ID.Hex())
+	ev.After = map[string]any{"url": hook.URL, "events": hook.Events}
+	h.audit.Record(c.Request.Context(), ev)
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": hook, "secret": secret})
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.svc.List(c.Request.Context(), h.owner(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	err := h.svc.Delete(c.Request.Context(), h.owner(c), c.Param("id"))
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditWebhookDelete, "webhook", c.Param("id")))
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries é o log de entregas de um webhook, com o resultado de cada
+// tentativa, para o integrador depurar falhas.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	page, limit := pagination(c)
+	deliveries, total, err := h.svc.Deliveries(c.Request.Context(), h.owner(c), c.Param("id"), c.Query("status"), page, limit)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"deliveries": deliveries,
+	})
+}
+
+// ListDeadLetters lista as entregas mortas de todos os webhooks (admin).
+func (h *WebhookHandler) ListDeadLetters(c *gin.Context) {
+	page, limit := pagination(c)
+	deliveries, total, err := h.svc.DeadLetters(c.Request.Context(), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"deliveries": deliveries,
+	})
+}
+
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	err := h.svc.Redeliver(c.Request.Context(), h.owner(c), c.Param("deliveryId"), h.global)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(c.Request.Context(), auditEvent(c, model.AuditWebhookRedeliver, "webhook_delivery", c.Param("deliveryId")))
+	c.Status(http.StatusAccepted)
+}
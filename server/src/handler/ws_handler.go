@@ -2,6 +2,8 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"wisp/src/middleware"
 	"wisp/src/ws"
 
 	"github.com/gin-gonic/gin"
@@ -27,6 +29,12 @@ func NewWSHandler(hub *ws.Hub) *WSHandler {
 	return &WSHandler{hub: hub}
 }
 
+// HandleConnection abre o WebSocket de um dispositivo. Para retomar uma
+// sessão, o cliente informa sessionId e lastSeq na própria URL, em vez de
+// num frame "resume": o replay começa no registro, antes de qualquer frame
+// ser lido, e esperar por um frame seguraria os clientes que não o enviam.
+// Sem retomada, as mensagens não confirmadas da sessão anterior vão para a
+// fila pendente e chegam nesta conexão.
 func (h *WSHandler) HandleConnection(c *gin.Context) {
 	userIdVal, exists := c.Get("userId")
 	if !exists {
@@ -35,6 +43,11 @@ func (h *WSHandler) HandleConnection(c *gin.Context) {
 	}
 	userId := userIdVal.(string)
 
+	if h.hub.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Servidor reiniciando, tente novamente em instantes"})
+		return
+	}
+
 	deviceId := c.Query("deviceId")
 	if deviceId == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do dispositivo é obrigatório"})
@@ -43,19 +56,16 @@ func (h *WSHandler) HandleConnection(c *gin.Context) {
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Error().Err(err).Msg("Falha ao atualizar para WebSocket")
+		middleware.Logger(c).Error().Err(err).Msg("Falha ao atualizar para WebSocket")
 		return
 	}
 
-	client := &ws.Client{
-		Hub:      h.hub,
-		UserID:   userId,
-		DeviceID: deviceId,
-		Conn:     conn,
-		Send:     make(chan []byte, 256),
-	}
+	client := ws.NewClient(h.hub, userId, deviceId, conn, *middleware.Logger(c))
+	client.SessionID = c.Query("sessionId")
+	client.Bot = c.GetBool("isBot")
+	client.LastSeq, _ = strconv.ParseUint(c.Query("lastSeq"), 10, 64)
 
-	h.hub.Register <- client
+	h.hub.Register(client)
 
 	go client.WritePump()
 	go client.ReadPump()
@@ -1,11 +1,14 @@
 package logger
 
 import (
+	"os"
+
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"os"
 )
 
+// NewLogger configura o logger global e o devolve. Em produção a saída é JSON,
+// uma linha por evento; nos outros ambientes, o ConsoleWriter legível.
 func NewLogger(env string) zerolog.Logger {
 	level := zerolog.InfoLevel
 	if env == "development" {
@@ -14,6 +17,13 @@ func NewLogger(env string) zerolog.Logger {
 	zerolog.SetGlobalLevel(level)
 	zerolog.TimestampFieldName = "ts"
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	logger := log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
+
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	if env != "production" {
+		logger = logger.Output(zerolog.ConsoleWriter{Out: os.Stdout})
+	}
+
+	log.Logger = logger
+	zerolog.DefaultContextLogger = &log.Logger
 	return logger
 }
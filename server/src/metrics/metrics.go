@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "wisp_http_requests_total",
+		Help: "Requisições HTTP por rota, método e status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wisp_http_request_duration_seconds",
+		Help:    "Latência das requisições HTTP por rota.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	MessagesRouted = factory.NewCounter(prometheus.CounterOpts{
+		Name: "wisp_messages_routed_total",
+		Help: "Mensagens aceitas pelo hub.",
+	})
+
+	MessagesDuplicated = factory.NewCounter(prometheus.CounterOpts{
+		Name: "wisp_messages_duplicated_total",
+		Help: "Reenvios descartados pela deduplicação.",
+	})
+
+	MessagesDelivered = factory.NewCounter(prometheus.CounterOpts{
+		Name: "wisp_messages_delivered_total",
+		Help: "Mensagens entregues a ao menos um dispositivo conectado.",
+	})
+
+	MessagesQueued = factory.NewCounter(prometheus.CounterOpts{
+		Name: "wisp_messages_queued_total",
+		Help: "Mensagens gravadas na fila pendente.",
+	})
+
+	MessagesAcked = factory.NewCounter(prometheus.CounterOpts{
+		Name: "wisp_messages_acked_total",
+		Help: "ACKs recebidos dos destinatários.",
+	})
+
+	MessagesFiltered = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "wisp_messages_filtered_total",
+		Help: "Mensagens que dispararam um filtro anti-abuso, por filtro e ação.",
+	}, []string{"filter", "action"})
+
+	PushSent = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "wisp_push_notifications_total",
+		Help: "Notificações push por provedor e resultado.",
+	}, []string{"provider", "result"})
+
+	WebhookDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "wisp_webhook_attempts_total",
+		Help: "Tentativas de entrega de webhook por evento e estado resultante.",
+	}, []string{"event", "status"})
+
+	AckLatency = factory.NewHistogram(prometheus.HistogramOpts{
+		Name:    "wisp_ack_latency_seconds",
+		Help:    "Tempo entre a entrega ao dispositivo e o ACK.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	MongoDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wisp_mongo_operation_duration_seconds",
+		Help:    "Latência das operações no MongoDB por método de repositório.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"repo", "method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveMongo mede uma operação de repositório. Uso:
+//
+//	defer metrics.ObserveMongo("UserRepo", "Create")()
+func ObserveMongo(repo, method string) func() {
+	start := time.Now()
+	return func() {
+		MongoDuration.WithLabelValues(repo, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// GaugeFunc registra um gauge calculado na hora da coleta.
+func GaugeFunc(name, help string, fn func() float64) {
+	factory.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+}
+
+// CounterFunc registra um contador lido de outro lugar na hora da coleta.
+func CounterFunc(name, help string, fn func() float64) {
+	factory.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, fn)
+}
+
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		HTTPDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler expõe o registro. Com token, exige "Authorization: Bearer <token>".
+func Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "não autorizado", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminOnly deve vir depois do JWTAuth.
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("isAdmin") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para acessar este recurso"})
+			return
+		}
+		c.Next()
+	}
+}
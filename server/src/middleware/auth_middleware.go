@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,7 +10,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func JWTAuth(authSvc *service.AuthService) gin.HandlerFunc {
+// JWTAuth aceita o JWT de uma sessão ou, com o prefixo wbt_, um token de API
+// de bot. Em ambos os casos preenche userId; bots levam também isBot.
+func JWTAuth(authSvc *service.AuthService, bots *service.BotService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		var tokenStr string
@@ -23,15 +26,63 @@ func JWTAuth(authSvc *service.AuthService) gin.HandlerFunc {
 			}
 		}
 
-		claims, err := authSvc.ValidateToken(tokenStr)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		if strings.HasPrefix(tokenStr, service.BotTokenPrefix) {
+			bot, token, err := bots.Authenticate(c.Request.Context(), tokenStr)
+			if abortAuth(c, err) {
+				return
+			}
+			c.Set("userId", bot.UserID)
+			c.Set("isAdmin", false)
+			c.Set("isBot", true)
+			setLogger(c, Logger(c).With().Str("userId", bot.UserID).Str("tokenId", token.ID.Hex()).Logger())
+			c.Next()
+			return
+		}
+
+		claims, err := authSvc.ValidateToken(c.Request.Context(), tokenStr)
+		if abortAuth(c, err) {
 			return
 		}
 
 		c.Set("userId", claims.UserID)
 		c.Set("isAdmin", claims.IsAdmin)
 		c.Set("sid", claims.ID)
+		setLogger(c, Logger(c).With().Str("userId", claims.UserID).Str("sid", claims.ID).Logger())
+		c.Next()
+	}
+}
+
+// abortAuth responde 403 para conta bloqueada e 401 para os demais erros.
+func abortAuth(c *gin.Context, err error) bool {
+	var blocked *service.AccountBlockedError
+	if errors.As(err, &blocked) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":  err.Error(),
+			"status": blocked.Status,
+			"reason": blocked.Reason,
+			"until":  blocked.Until,
+		})
+		return true
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
+
+// BotScope recusa, para tokens de bot, as rotas fora de allowed, no formato
+// "POST /bot/messages". Precisa vir depois do JWTAuth.
+func BotScope(allowed ...string) gin.HandlerFunc {
+	routes := make(map[string]bool, len(allowed))
+	for _, r := range allowed {
+		routes[r] = true
+	}
+	return func(c *gin.Context) {
+		if c.GetBool("isBot") && !routes[c.Request.Method+" "+c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "rota não disponível para tokens de bot"})
+			return
+		}
 		c.Next()
 	}
 }
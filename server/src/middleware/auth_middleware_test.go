@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBotScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	secure := r.Group("/")
+	secure.Use(func(c *gin.Context) { c.Set("isBot", c.GetHeader("X-Bot") != "") }, BotScope("GET /ws", "POST /bot/messages"))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	secure.GET("/ws", ok)
+	secure.POST("/bot/messages", ok)
+	secure.POST("/webhooks", ok)
+	secure.DELETE("/users/:id", ok)
+
+	cases := []struct {
+		method, path string
+		bot          bool
+		want         int
+	}{
+		{http.MethodGet, "/ws", true, http.StatusNoContent},
+		{http.MethodPost, "/bot/messages", true, http.StatusNoContent},
+		{http.MethodPost, "/webhooks", true, http.StatusForbidden},
+		{http.MethodDelete, "/users/abc1234", true, http.StatusForbidden},
+		{http.MethodPost, "/webhooks", false, http.StatusNoContent},
+		{http.MethodDelete, "/users/abc1234", false, http.StatusNoContent},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.bot {
+			req.Header.Set("X-Bot", "1")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("%s %s (bot %v): %d, esperava %d", c.method, c.path, c.bot, w.Code, c.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"wisp/src/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit aplica as políticas do tipo key (ratelimit.KeyIP ou KeyUser) e
+// responde com os cabeçalhos RateLimit-* da política mais apertada. As de
+// usuário precisam vir depois do JWTAuth. Se o store falhar, a requisição
+// passa.
+func RateLimit(l *ratelimit.Limiter, key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policies := l.For(key, c.Request.Method, c.FullPath())
+		if len(policies) == 0 {
+			c.Next()
+			return
+		}
+
+		id := c.ClientIP()
+		if key == ratelimit.KeyUser {
+			if id = c.GetString("userId"); id == "" {
+				c.Next()
+				return
+			}
+		}
+
+		var tightest *ratelimit.Result
+		var tightestPolicy *ratelimit.Policy
+		for i := range policies {
+			p := &policies[i]
+			res, err := l.Store.Take(c.Request.Context(), p.Name+":"+id, p.Rate)
+			if err != nil {
+				Logger(c).Error().Err(err).Str("policy", p.Name).Msg("Erro ao consultar o rate limit")
+				continue
+			}
+			if tightest == nil || tighter(res, *tightest) {
+				tightest, tightestPolicy = &res, p
+			}
+		}
+		if tightest == nil {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(seconds(tightest.Reset)))
+		c.Header("RateLimit-Policy", strconv.Itoa(tightestPolicy.Limit)+";w="+strconv.Itoa(seconds(tightestPolicy.Period)))
+
+		if !tightest.Allowed {
+			c.Header("Retry-After", strconv.Itoa(seconds(tightest.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "muitas requisições, tente novamente em instantes"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// tighter diz se a é mais restritivo que b: negado vence liberado e, entre
+// dois iguais, vence o com menos fichas.
+func tighter(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	return a.Remaining < b.Remaining
+}
+
+// seconds arredonda para cima, como pedem os cabeçalhos RateLimit-*.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wisp/src/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newLimitedRouter(t *testing.T, policies ...ratelimit.Policy) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(RateLimit(ratelimit.NewLimiter(ratelimit.NewMemoryStore(), policies), ratelimit.KeyIP))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.POST("/auth/login", ok)
+	r.GET("/contacts", ok)
+	return r
+}
+
+func request(r *gin.Engine, method, path, remote, forwarded string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remote
+	if forwarded != "" {
+		req.Header.Set("X-Forwarded-For", forwarded)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	r := newLimitedRouter(t,
+		ratelimit.Policy{Name: "ip", Key: ratelimit.KeyIP, Rate: ratelimit.Rate{Limit: 100, Period: time.Minute}},
+		ratelimit.Policy{Name: "auth", Key: ratelimit.KeyIP, Routes: []string{"POST /auth/login"}, Rate: ratelimit.Rate{Limit: 2, Period: time.Minute}},
+	)
+
+	// Vale a política mais apertada que se aplica à rota.
+	cases := []struct {
+		method, path string
+		status       int
+		limit        string
+		remaining    string
+		reset        string
+		policy       string
+		retryAfter   string
+	}{
+		{http.MethodGet, "/contacts", http.StatusNoContent, "100", "99", "1", "100;w=60", ""},
+		{http.MethodPost, "/auth/login", http.StatusNoContent, "2", "1", "30", "2;w=60", ""},
+		{http.MethodPost, "/auth/login", http.StatusNoContent, "2", "0", "60", "2;w=60", ""},
+		{http.MethodPost, "/auth/login", http.StatusTooManyRequests, "2", "0", "60", "2;w=60", "30"},
+	}
+	for i, c := range cases {
+		w := request(r, c.method, c.path, "203.0.113.7:4000", "")
+		h := w.Header()
+		if w.Code != c.status {
+			t.Fatalf("passo %d: status %d", i, w.Code)
+		}
+		got := []string{h.Get("RateLimit-Limit"), h.Get("RateLimit-Remaining"), h.Get("RateLimit-Reset"), h.Get("RateLimit-Policy"), h.Get("Retry-After")}
+		want := []string{c.limit, c.remaining, c.reset, c.policy, c.retryAfter}
+		for j := range got {
+			if got[j] != want[j] {
+				t.Errorf("passo %d: cabeçalhos %q, esperava %q", i, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestRateLimitIgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	r := newLimitedRouter(t, ratelimit.Policy{Name: "auth", Key: ratelimit.KeyIP, Rate: ratelimit.Rate{Limit: 1, Period: time.Minute}})
+
+	if w := request(r, http.MethodPost, "/auth/login", "203.0.113.7:4000", "198.51.100.1"); w.Code != http.StatusNoContent {
+		t.Fatalf("primeira tentativa: %d", w.Code)
+	}
+	if w := request(r, http.MethodPost, "/auth/login", "203.0.113.7:4001", "198.51.100.2"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("X-Forwarded-For forjado escapou do limite: %d", w.Code)
+	}
+	if w := request(r, http.MethodPost, "/auth/login", "203.0.113.8:4000", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("outro IP barrado: %d", w.Code)
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID reaproveita o X-Request-ID recebido, ou gera um, e coloca no
+// contexto da requisição um logger com o requestId (e o traceId, se houver).
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set("requestId", id)
+		c.Header(RequestIDHeader, id)
+
+		ctx := c.Request.Context()
+		fields := zerolog.Ctx(ctx).With().Str("requestId", id)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = fields.Str("traceId", sc.TraceID().String())
+		}
+		setLogger(c, fields.Logger())
+		c.Next()
+	}
+}
+
+// Logger devolve o logger da requisição, com os campos de correlação.
+func Logger(c *gin.Context) *zerolog.Logger {
+	return zerolog.Ctx(c.Request.Context())
+}
+
+func setLogger(c *gin.Context, l zerolog.Logger) {
+	c.Request = c.Request.WithContext(l.WithContext(c.Request.Context()))
+}
+
+// AccessLog registra cada requisição pelo zerolog, no lugar do gin.Logger.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		var ev *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError:
+			ev = Logger(c).Error()
+		case status >= http.StatusBadRequest:
+			ev = Logger(c).Warn()
+		default:
+			ev = Logger(c).Info()
+		}
+		if len(c.Errors) > 0 {
+			ev = ev.Str("errors", c.Errors.String())
+		}
+		ev.Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Str("route", c.FullPath()).
+			Int("status", status).
+			Int("size", c.Writer.Size()).
+			Dur("latency", time.Since(start)).
+			Str("ip", c.ClientIP()).
+			Msg("Requisição HTTP")
+	}
+}
+
+// Recovery troca o pânico por um 500 e registra a pilha no logger da
+// requisição.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		Logger(c).Error().Interface("panic", err).Bytes("stack", debug.Stack()).Msg("Pânico ao tratar requisição")
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID aceita IDs curtos com caracteres seguros para log e header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIToken autentica um bot. Só o hash fica no banco; o valor é mostrado uma
+// única vez, na criação. Prefix identifica o token nas listagens.
+type APIToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
+	BotID      string             `bson:"botId"                json:"botId"`
+	Name       string             `bson:"name"                 json:"name"`
+	Prefix     string             `bson:"prefix"               json:"prefix"`
+	Hash       string             `bson:"hash"                 json:"-"`
+	CreatedAt  time.Time          `bson:"createdAt"            json:"createdAt"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ações registradas no log de auditoria.
+const (
+	AuditLoginSuccess          = "auth.login.success"
+	AuditLoginFailure          = "auth.login.failure"
+	AuditLogout                = "auth.logout"
+	AuditUserUpdate            = "user.update"
+	AuditUserRoleChange        = "user.role_change"
+	AuditUserDelete            = "user.delete"
+	AuditFriendRequestSend     = "friend_request.send"
+	AuditFriendRequestCancel   = "friend_request.cancel"
+	AuditFriendRequestAccept   = "friend_request.accept"
+	AuditFriendRequestReject   = "friend_request.reject"
+	AuditContactRemove         = "contact.remove"
+	AuditReportCreate          = "report.create"
+	AuditAdminAuditExport      = "admin.audit_export"
+	AuditUserSuspend           = "admin.user_suspend"
+	AuditUserBan               = "admin.user_ban"
+	AuditUserReinstate         = "admin.user_reinstate"
+	AuditUserForceLogout       = "admin.user_force_logout"
+	AuditReportUpdate          = "admin.report_update"
+	AuditWebhookCreate         = "webhook.create"
+	AuditWebhookDelete         = "webhook.delete"
+	AuditWebhookRedeliver      = "webhook.redeliver"
+	AuditBotCreate             = "bot.create"
+	AuditBotDelete             = "bot.delete"
+	AuditBotTokenCreate        = "bot.token_create"
+	AuditBotTokenRevoke        = "bot.token_revoke"
+	AuditIncomingWebhookCreate = "incoming_webhook.create"
+	AuditIncomingWebhookDelete = "incoming_webhook.delete"
+)
+
+// AuditEvent é uma entrada do log de auditoria. A coleção só recebe
+// inserções; nada no código altera ou remove eventos.
+type AuditEvent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"      json:"id"`
+	Action      string             `bson:"action"             json:"action"`
+	ActorID     string             `bson:"actorId,omitempty"  json:"actorId,omitempty"`
+	AdminAction bool               `bson:"adminAction"        json:"adminAction"`
+	TargetType  string             `bson:"targetType"         json:"targetType"`
+	TargetID    string             `bson:"targetId"           json:"targetId"`
+	IP          string             `bson:"ip"                 json:"ip"`
+	UserAgent   string             `bson:"userAgent"          json:"userAgent"`
+	RequestID   string             `bson:"requestId"          json:"requestId"`
+	Success     bool               `bson:"success"            json:"success"`
+	Reason      string             `bson:"reason,omitempty"   json:"reason,omitempty"`
+	Before      map[string]any     `bson:"before,omitempty"   json:"before,omitempty"`
+	After       map[string]any     `bson:"after,omitempty"    json:"after,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt"          json:"createdAt"`
+}
+
+type AuditFilter struct {
+	Action   string
+	ActorID  string
+	TargetID string
+	From     time.Time
+	To       time.Time
+}
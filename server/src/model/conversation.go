@@ -0,0 +1,117 @@
+package model
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConversationSettings guarda o que vale para os dois lados de uma conversa.
+// ID é ConversationKey dos participantes.
+type ConversationSettings struct {
+	ID             string    `bson:"_id"            json:"-"`
+	Members        []string  `bson:"members"        json:"members"`
+	DisappearAfter int64     `bson:"disappearAfter" json:"disappearAfter"`
+	UpdatedBy      string    `bson:"updatedBy"      json:"updatedBy,omitempty"`
+	UpdatedAt      time.Time `bson:"updatedAt"      json:"updatedAt"`
+}
+
+// ConversationKey identifica a conversa entre a e b, na mesma ordem para os
+// dois lados.
+func ConversationKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + ":" + b
+}
+
+// MessageExpiry agenda a remoção de uma mensagem temporária. ID é o id da
+// mensagem.
+type MessageExpiry struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	From      string             `bson:"from"`
+	To        string             `bson:"to"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
+
+// Conversation é a conversa como um dos participantes a vê na lista de
+// conversas. ID é UserID+":"+PeerID; cada lado tem o seu documento, com o
+// próprio estado de silenciada, fixada e arquivada. MutedUntil nulo com Muted
+// silencia até o usuário desfazer; PinOrder ordena as fixadas, a partir de 1.
+type Conversation struct {
+	ID             string               `bson:"_id"                   json:"-"`
+	UserID         string               `bson:"userId"                json:"-"`
+	PeerID         string               `bson:"peerId"                json:"peerId"`
+	Peer           *ConversationPeer    `bson:"-"                     json:"peer,omitempty"`
+	LastMessage    *ConversationPreview `bson:"lastMessage,omitempty" json:"lastMessage,omitempty"`
+	LastActivityAt time.Time            `bson:"lastActivityAt"        json:"lastActivityAt"`
+	Unread         int64                `bson:"unread"                json:"unread"`
+	ReadAt         *time.Time           `bson:"readAt,omitempty"      json:"readAt,omitempty"`
+	Muted          bool                 `bson:"muted"                 json:"muted"`
+	MutedUntil     *time.Time           `bson:"mutedUntil,omitempty" json:"mutedUntil,omitempty"`
+	Pinned         bool                 `bson:"pinned"                json:"pinned"`
+	PinOrder       int                  `bson:"pinOrder,omitempty" json:"pinOrder,omitempty"`
+	Archived       bool                 `bson:"archived"              json:"archived"`
+}
+
+// IsMuted diz se o silêncio ainda vale em now.
+func (c *Conversation) IsMuted(now time.Time) bool {
+	return c.Muted && (c.MutedUntil == nil || c.MutedUntil.After(now))
+}
+
+// ConversationList é uma página da lista de conversas. Pinned vem só na
+// primeira página da caixa de entrada, na ordem do usuário.
+type ConversationList struct {
+	Pinned        []Conversation `json:"pinned,omitempty"`
+	Conversations []Conversation `json:"conversations"`
+	NextCursor    string         `json:"nextCursor"`
+}
+
+type ConversationPeer struct {
+	UserID string `json:"userId"`
+	Name   string `json:"name"`
+	IsBot  bool   `json:"isBot,omitempty"`
+}
+
+// ConversationPreview resume a última mensagem da conversa. Content vem
+// truncado; o cliente busca a mensagem inteira pelo id se precisar.
+type ConversationPreview struct {
+	ID             string        `bson:"id"                       json:"id"`
+	From           string        `bson:"from"                     json:"from"`
+	Content        string        `bson:"content"                  json:"content"`
+	Bot            bool          `bson:"bot,omitempty"            json:"bot,omitempty"`
+	HasAttachments bool          `bson:"hasAttachments,omitempty" json:"hasAttachments,omitempty"`
+	Event          *MessageEvent `bson:"event,omitempty"          json:"event,omitempty"`
+	Timestamp      int64         `bson:"timestamp"                json:"timestamp"`
+}
+
+// previewLength é o tamanho máximo do conteúdo da prévia, em runas.
+const previewLength = 100
+
+// Preview corta o conteúdo para a lista de conversas e para o push.
+func Preview(content string) string {
+	if utf8.RuneCountInString(content) <= previewLength {
+		return content
+	}
+	return string([]rune(content)[:previewLength-1]) + "…"
+}
+
+func NewConversationPreview(hm *HistoryMessage) *ConversationPreview {
+	return &ConversationPreview{
+		ID:             hm.ID.Hex(),
+		From:           hm.From,
+		Content:        Preview(hm.Content),
+		Bot:            hm.Bot,
+		HasAttachments: hm.HasAttachments,
+		Event:          hm.Event,
+		Timestamp:      hm.CreatedAt.Unix(),
+	}
+}
+
+// ConversationUpdate leva aos dispositivos do usuário o novo estado de uma
+// conversa da lista.
+type ConversationUpdate struct {
+	Type         string        `json:"type"`
+	Conversation *Conversation `json:"conversation"`
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HistoryMessage é a cópia permanente de uma mensagem, usada na busca. A
+// fila pendente apaga a mensagem na entrega; o histórico fica. ID é o id da
+// mensagem e Members os dois participantes, base do filtro de acesso.
+type HistoryMessage struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Members        []string           `bson:"members"`
+	From           string             `bson:"from"`
+	To             string             `bson:"to"`
+	Content        string             `bson:"content"`
+	Bot            bool               `bson:"bot,omitempty"`
+	Attachments    []Attachment       `bson:"attachments,omitempty"`
+	HasAttachments bool               `bson:"hasAttachments"`
+	Event          *MessageEvent      `bson:"event,omitempty"`
+	CreatedAt      time.Time          `bson:"createdAt"`
+	ExpiresAt      *time.Time         `bson:"expiresAt,omitempty"`
+	Score          float64            `bson:"score,omitempty"`
+}
+
+func NewHistoryMessage(msg *Message) *HistoryMessage {
+	id, _ := primitive.ObjectIDFromHex(msg.ID)
+	hm := &HistoryMessage{
+		ID:             id,
+		Members:        []string{msg.From, msg.To},
+		From:           msg.From,
+		To:             msg.To,
+		Content:        msg.Content,
+		Bot:            msg.Bot,
+		Attachments:    msg.Attachments,
+		HasAttachments: len(msg.Attachments) > 0,
+		Event:          msg.Event,
+		CreatedAt:      time.Now(),
+	}
+	if msg.ExpiresAt > 0 {
+		t := time.Unix(msg.ExpiresAt, 0)
+		hm.ExpiresAt = &t
+	}
+	return hm
+}
+
+func (hm *HistoryMessage) Message() *Message {
+	msg := &Message{
+		Type:        "message",
+		From:        hm.From,
+		To:          hm.To,
+		Content:     hm.Content,
+		Bot:         hm.Bot,
+		Attachments: hm.Attachments,
+		Event:       hm.Event,
+		Timestamp:   hm.CreatedAt.Unix(),
+		ID:          hm.ID.Hex(),
+	}
+	if hm.ExpiresAt != nil {
+		msg.ExpiresAt = hm.ExpiresAt.Unix()
+	}
+	return msg
+}
+
+// SearchQuery é uma busca no histórico de UserID. Peer restringe a uma
+// conversa, FromMe às mensagens enviadas por ele; datas zeradas não
+// limitam.
+type SearchQuery struct {
+	UserID        string
+	Text          string
+	Peer          string
+	Since         time.Time
+	Until         time.Time
+	HasAttachment bool
+	FromMe        bool
+	Offset        int
+	Limit         int
+}
+
+// SearchResult é uma mensagem encontrada com os trechos que casaram.
+type SearchResult struct {
+	Message  *Message  `json:"message"`
+	Snippets []Snippet `json:"snippets"`
+}
+
+// Snippet é um trecho de um campo da mensagem ("content", "attachment.title"
+// ou "attachment.text"). Matches são os intervalos [início, fim) dos termos
+// encontrados, em runas de Text.
+type Snippet struct {
+	Field   string   `json:"field"`
+	Text    string   `json:"text"`
+	Matches [][2]int `json:"matches"`
+}
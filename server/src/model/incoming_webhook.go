@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IncomingWebhook é uma URL secreta que posta mensagens de BotID para
+// TargetID. Como nos tokens de API, só o hash do segredo fica no banco.
+type IncomingWebhook struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
+	OwnerID    string             `bson:"ownerId"              json:"ownerId"`
+	BotID      string             `bson:"botId"                json:"botId"`
+	TargetID   string             `bson:"targetId"             json:"targetId"`
+	Name       string             `bson:"name"                 json:"name"`
+	Prefix     string             `bson:"prefix"               json:"prefix"`
+	Hash       string             `bson:"hash"                 json:"-"`
+	CreatedAt  time.Time          `bson:"createdAt"            json:"createdAt"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
@@ -1,12 +1,69 @@
 package model
 
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
 type Message struct {
+	Type        string        `json:"type"`
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Content     string        `json:"content"`
+	Timestamp   int64         `json:"timestamp"`
+	ID          string        `json:"id"`
+	ClientID    string        `json:"clientId,omitempty"`
+	Bot         bool          `json:"bot,omitempty"`
+	Attachments []Attachment  `json:"attachments,omitempty"`
+	ExpiresAt   int64         `json:"expiresAt,omitempty"`
+	Event       *MessageEvent `json:"event,omitempty"`
+}
+
+// Mentions diz se o conteúdo menciona o usuário como "@userId".
+func (m *Message) Mentions(userID string) bool {
+	s := m.Content
+	for {
+		i := strings.Index(s, "@"+userID)
+		if i < 0 {
+			return false
+		}
+		s = s[i+1+len(userID):]
+		if r, _ := utf8.DecodeRuneInString(s); !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return true
+		}
+	}
+}
+
+// Tipos de MessageEvent.
+const (
+	MessageEventTimer = "disappearing_timer"
+)
+
+// MessageEvent marca uma mensagem de sistema da conversa, como a troca do
+// timer de mensagens temporárias; Content vem vazio. Só o servidor cria
+// mensagens com Event.
+type MessageEvent struct {
+	Type    string `bson:"type"              json:"type"`
+	Seconds int64  `bson:"seconds,omitempty" json:"seconds,omitempty"`
+}
+
+// MessageExpired pede aos dispositivos conectados que apaguem a cópia local
+// de uma mensagem temporária vencida.
+type MessageExpired struct {
 	Type      string `json:"type"`
+	MessageID string `json:"messageId"`
 	From      string `json:"from"`
 	To        string `json:"to"`
-	Content   string `json:"content"`
-	Timestamp int64  `json:"timestamp"`
-	ID        string `json:"id"`
+}
+
+// Attachment é um cartão com link anexado a uma mensagem, usado pelos
+// webhooks de entrada. O servidor não guarda arquivos.
+type Attachment struct {
+	Title string `bson:"title,omitempty" json:"title,omitempty" binding:"max=256"`
+	Text  string `bson:"text,omitempty"  json:"text,omitempty"  binding:"max=4096"`
+	URL   string `bson:"url,omitempty"   json:"url,omitempty"   binding:"omitempty,url,max=2048"`
 }
 
 type Ack struct {
@@ -15,3 +72,41 @@ type Ack struct {
 	From      string `json:"from"`
 	To        string `json:"to"`
 }
+
+type SendConfirmation struct {
+	Type      string `json:"type"`
+	MessageID string `json:"messageId"`
+	ClientID  string `json:"clientId,omitempty"`
+	To        string `json:"to"`
+	Timestamp int64  `json:"timestamp"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+}
+
+// SendRejected avisa o remetente de que a mensagem não foi aceita.
+type SendRejected struct {
+	Type     string `json:"type"`
+	ClientID string `json:"clientId,omitempty"`
+	To       string `json:"to"`
+	Reason   string `json:"reason"`
+}
+
+type SessionInfo struct {
+	Type      string `json:"type"`
+	SessionID string `json:"sessionId"`
+	Seq       uint64 `json:"seq"`
+	Resumed   bool   `json:"resumed"`
+}
+
+type ServerRestart struct {
+	Type        string `json:"type"`
+	ReconnectIn int64  `json:"reconnectIn"`
+}
+
+// AccountNotice avisa o usuário de que a conta foi suspensa, banida ou teve
+// as sessões encerradas, pouco antes de o servidor fechar a conexão.
+type AccountNotice struct {
+	Type   string     `json:"type"`
+	Status string     `json:"status"`
+	Reason string     `json:"reason,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+}
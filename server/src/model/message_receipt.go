@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MessageReceipt struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	From      string             `bson:"from"`
+	ClientID  string             `bson:"clientId"`
+	MessageID string             `bson:"messageId"`
+	To        string             `bson:"to"`
+	Timestamp int64              `bson:"timestamp"`
+	CreatedAt time.Time          `bson:"createdAt"`
+}
+
+func (r *MessageReceipt) Confirmation(duplicate bool) *SendConfirmation {
+	return &SendConfirmation{
+		Type:      "sent",
+		MessageID: r.MessageID,
+		ClientID:  r.ClientID,
+		To:        r.To,
+		Timestamp: r.Timestamp,
+		Duplicate: duplicate,
+	}
+}
@@ -7,9 +7,61 @@ import (
 )
 
 type PendingMessage struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	From      string             `bson:"from"`
-	To        string             `bson:"to"`
-	Payload   string             `bson:"payload"`
-	CreatedAt time.Time          `bson:"createdAt"`
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	From        string             `bson:"from"`
+	To          string             `bson:"to"`
+	DeviceID    string             `bson:"deviceId,omitempty"`
+	MessageID   string             `bson:"messageId,omitempty"`
+	Payload     string             `bson:"payload"`
+	Bot         bool               `bson:"bot,omitempty"`
+	Attachments []Attachment       `bson:"attachments,omitempty"`
+	ExpiresAt   *time.Time         `bson:"expiresAt,omitempty"`
+	Event       *MessageEvent      `bson:"event,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt"`
+}
+
+// NewPendingMessage copia a mensagem para a fila pendente. _id, deviceId e
+// createdAt ficam com quem chama.
+func NewPendingMessage(msg *Message) *PendingMessage {
+	pm := &PendingMessage{
+		From:        msg.From,
+		To:          msg.To,
+		Payload:     msg.Content,
+		Bot:         msg.Bot,
+		Attachments: msg.Attachments,
+		Event:       msg.Event,
+	}
+	if msg.ExpiresAt > 0 {
+		t := time.Unix(msg.ExpiresAt, 0)
+		pm.ExpiresAt = &t
+	}
+	return pm
+}
+
+// Message remonta a mensagem a entregar a partir da cópia pendente.
+func (pm *PendingMessage) Message() *Message {
+	msg := &Message{
+		Type:        "message",
+		From:        pm.From,
+		To:          pm.To,
+		Content:     pm.Payload,
+		Bot:         pm.Bot,
+		Attachments: pm.Attachments,
+		Event:       pm.Event,
+		Timestamp:   pm.CreatedAt.Unix(),
+		ID:          pm.MessageIDHex(),
+	}
+	if pm.ExpiresAt != nil {
+		msg.ExpiresAt = pm.ExpiresAt.Unix()
+	}
+	return msg
+}
+
+// MessageIDHex devolve o id da mensagem original. Cópias desviadas para um
+// dispositivo específico têm _id próprio e guardam o id original à parte.
+func (pm *PendingMessage) MessageIDHex() string {
+	if pm.MessageID != "" {
+		return pm.MessageID
+	}
+	return pm.ID.Hex()
 }
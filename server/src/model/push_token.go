@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PushToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID    string             `bson:"userId"        json:"userId"`
+	DeviceID  string             `bson:"deviceId"      json:"deviceId"`
+	Provider  string             `bson:"provider"      json:"provider"`
+	Token     string             `bson:"token"         json:"-"`
+	CreatedAt time.Time          `bson:"createdAt"     json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt"     json:"updatedAt"`
+}
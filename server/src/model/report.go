@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Estados de uma denúncia na fila de moderação.
+const (
+	ReportOpen      = "open"
+	ReportReviewing = "reviewing"
+	ReportActioned  = "actioned"
+	ReportDismissed = "dismissed"
+)
+
+type Report struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"          json:"id"`
+	ReporterID   string             `bson:"reporterId"             json:"reporterId"`
+	TargetUserID string             `bson:"targetUserId"           json:"targetUserId"`
+	Category     string             `bson:"category"               json:"category"`
+	Description  string             `bson:"description,omitempty"  json:"description,omitempty"`
+	Message      *ReportedMessage   `bson:"message,omitempty"      json:"message,omitempty"`
+	Status       string             `bson:"status"                 json:"status"`
+	AssigneeID   string             `bson:"assigneeId,omitempty"   json:"assigneeId,omitempty"`
+	Notes        []ReportNote       `bson:"notes"                  json:"notes"`
+	Action       string             `bson:"action,omitempty"       json:"action,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt"              json:"createdAt"`
+	UpdatedAt    time.Time          `bson:"updatedAt"              json:"updatedAt"`
+}
+
+// ReportedMessage é a mensagem que o denunciante decidiu anexar. O cliente
+// manda só o id; conteúdo e horário vêm do histórico do servidor.
+type ReportedMessage struct {
+	ID        string `bson:"id"        json:"id"        binding:"required"`
+	Content   string `bson:"content"   json:"content"`
+	Timestamp int64  `bson:"timestamp" json:"timestamp"`
+}
+
+type ReportNote struct {
+	AuthorID  string    `bson:"authorId"  json:"authorId"`
+	Text      string    `bson:"text"      json:"text"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+type ReportFilter struct {
+	Status       string
+	AssigneeID   string
+	TargetUserID string
+}
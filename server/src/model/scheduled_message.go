@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Estados de um envio agendado.
+const (
+	ScheduledPending  = "scheduled"
+	ScheduledSent     = "sent"
+	ScheduledCanceled = "canceled"
+	ScheduledFailed   = "failed"
+)
+
+type ScheduledMessage struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"       json:"id"`
+	From       string             `bson:"from"                json:"from"`
+	To         string             `bson:"to"                  json:"to"`
+	Content    string             `bson:"content"             json:"content"`
+	SendAt     time.Time          `bson:"sendAt"              json:"sendAt"`
+	Status     string             `bson:"status"              json:"status"`
+	Slot       int                `bson:"slot,omitempty"      json:"-"`
+	LeaseUntil time.Time          `bson:"leaseUntil"          json:"-"`
+	MessageID  string             `bson:"messageId,omitempty" json:"messageId,omitempty"`
+	Error      string             `bson:"error,omitempty"     json:"error,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt"           json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updatedAt"           json:"updatedAt"`
+	SentAt     *time.Time         `bson:"sentAt,omitempty"    json:"sentAt,omitempty"`
+}
+
+// ClientID é o id de cliente usado no envio. Se uma instância cair entre o
+// envio e a marcação como enviada, a próxima reenvia com o mesmo id e o hub
+// descarta a duplicata.
+func (m *ScheduledMessage) ClientID() string {
+	return "sched:" + m.ID.Hex()
+}
@@ -7,14 +7,38 @@ import (
 )
 
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty"  json:"-"`
-	UserID       string             `bson:"userId"         json:"userId"        validate:"required,len=7,unique"`
-	Name         string             `bson:"name"           json:"name"          validate:"required,min=3"`
-	Email        string             `bson:"email"          json:"email"         validate:"required,email"`
-	PasswordHash string             `bson:"passwordHash"   json:"-"`
-	IsAdmin      bool               `bson:"isAdmin"        json:"isAdmin"`
-	CreatedAt    time.Time          `bson:"createdAt"      json:"createdAt"`
-	UpdatedAt    time.Time          `bson:"updatedAt"      json:"updatedAt"`
+	ID             primitive.ObjectID `bson:"_id,omitempty"            json:"-"`
+	UserID         string             `bson:"userId"                   json:"userId"        validate:"required,len=7,unique"`
+	Name           string             `bson:"name"                     json:"name"          validate:"required,min=3"`
+	Email          string             `bson:"email"                    json:"email"         validate:"required,email"`
+	PasswordHash   string             `bson:"passwordHash"             json:"-"`
+	IsAdmin        bool               `bson:"isAdmin"                  json:"isAdmin"`
+	IsBot          bool               `bson:"isBot,omitempty"          json:"isBot,omitempty"`
+	OwnerID        string             `bson:"ownerId,omitempty"        json:"ownerId,omitempty"`
+	Status         string             `bson:"status,omitempty"         json:"status,omitempty"`
+	StatusReason   string             `bson:"statusReason,omitempty"   json:"statusReason,omitempty"`
+	SuspendedUntil *time.Time         `bson:"suspendedUntil,omitempty" json:"suspendedUntil,omitempty"`
+	CreatedAt      time.Time          `bson:"createdAt"                json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updatedAt"                json:"updatedAt"`
+}
+
+// Estados da conta. Documentos antigos sem status contam como ativos.
+const (
+	UserActive    = "active"
+	UserSuspended = "suspended"
+	UserBanned    = "banned"
+)
+
+// Blocked informa se a conta está impedida de usar o serviço em now. Uma
+// suspensão vencida deixa de bloquear sem precisar ser revertida.
+func (u *User) Blocked(now time.Time) bool {
+	switch u.Status {
+	case UserBanned:
+		return true
+	case UserSuspended:
+		return u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil)
+	}
+	return false
 }
 
 type RegisterRequest struct {
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Eventos que podem ser assinados por webhooks. Webhooks de usuário só
+// recebem os eventos que dizem respeito ao próprio dono.
+const (
+	EventBotMessage            = "message.bot_received"
+	EventFriendRequestReceived = "friend_request.received"
+	EventUserRegistered        = "user.registered"
+	EventUserDeleted           = "user.deleted"
+)
+
+// Estados de uma entrega. Uma entrega vai para "dead" quando esgota as
+// tentativas e só volta à fila por um reenvio manual.
+const (
+	DeliveryPending   = "pending"
+	DeliveryRetrying  = "retrying"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook sem OwnerID é global, cadastrado por um admin.
+type Webhook struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"     json:"id"`
+	OwnerID   string             `bson:"ownerId,omitempty" json:"ownerId,omitempty"`
+	URL       string             `bson:"url"               json:"url"`
+	Secret    string             `bson:"secret"            json:"-"`
+	Events    []string           `bson:"events"            json:"events"`
+	CreatedAt time.Time          `bson:"createdAt"         json:"createdAt"`
+}
+
+// WebhookDelivery guarda o corpo exatamente como foi assinado, para que um
+// reenvio mande os mesmos bytes, e o resultado de cada tentativa.
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"          json:"id"`
+	WebhookID      primitive.ObjectID `bson:"webhookId"              json:"webhookId"`
+	OwnerID        string             `bson:"ownerId,omitempty"      json:"-"`
+	Event          string             `bson:"event"                  json:"event"`
+	Payload        string             `bson:"payload"                json:"payload"`
+	Status         string             `bson:"status"                 json:"status"`
+	Attempts       int                `bson:"attempts"               json:"attempts"`
+	NextAttemptAt  time.Time          `bson:"nextAttemptAt"          json:"nextAttemptAt"`
+	LeaseUntil     time.Time          `bson:"leaseUntil"             json:"-"`
+	LastStatusCode int                `bson:"lastStatusCode"         json:"lastStatusCode,omitempty"`
+	LastError      string             `bson:"lastError,omitempty"    json:"lastError,omitempty"`
+	Log            []WebhookAttempt   `bson:"log"                    json:"log"`
+	CreatedAt      time.Time          `bson:"createdAt"              json:"createdAt"`
+	DeliveredAt    *time.Time         `bson:"deliveredAt,omitempty"  json:"deliveredAt,omitempty"`
+}
+
+type WebhookAttempt struct {
+	At         time.Time `bson:"at"                   json:"at"`
+	StatusCode int       `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	Error      string    `bson:"error,omitempty"      json:"error,omitempty"`
+	DurationMs int64     `bson:"durationMs"           json:"durationMs"`
+}
@@ -0,0 +1,128 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// O token de provedor da Apple vale uma hora e não pode ser renovado mais de
+// uma vez a cada 20 minutos.
+const apnsTokenTTL = 50 * time.Minute
+
+// APNs envia pela API HTTP/2 da Apple com autenticação por token (.p8).
+type APNs struct {
+	client *http.Client
+	host   string
+	topic  string
+	keyID  string
+	teamID string
+	key    *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	bearer   string
+	issuedAt time.Time
+}
+
+func NewAPNs(keyFile, keyID, teamID, topic string, sandbox bool) (*APNs, error) {
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("chave do APNs inválida: %w", err)
+	}
+
+	host := "https://api.push.apple.com"
+	if sandbox {
+		host = "https://api.sandbox.push.apple.com"
+	}
+	return &APNs{
+		client: &http.Client{Timeout: 10 * time.Second},
+		host:   host,
+		topic:  topic,
+		keyID:  keyID,
+		teamID: teamID,
+		key:    key,
+	}, nil
+}
+
+func (a *APNs) token() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.bearer != "" && time.Since(a.issuedAt) < apnsTokenTTL {
+		return a.bearer, nil
+	}
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Issuer:   a.teamID,
+		IssuedAt: jwt.NewNumericDate(now),
+	})
+	t.Header["kid"] = a.keyID
+	signed, err := t.SignedString(a.key)
+	if err != nil {
+		return "", err
+	}
+	a.bearer, a.issuedAt = signed, now
+	return signed, nil
+}
+
+func (a *APNs) Push(ctx context.Context, token string, n *Notification) error {
+	payload := map[string]any{
+		"aps": map[string]any{
+			"alert": map[string]string{"title": n.Title, "body": n.Body},
+			"sound": "default",
+			// Deixa a extensão do app decifrar o conteúdo no modo hint.
+			"mutable-content": 1,
+		},
+	}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	bearer, err := a.token()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.host+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	if n.CollapseKey != "" {
+		req.Header.Set("apns-collapse-id", n.CollapseKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	// 410 Unregistered e 400 BadDeviceToken: o token não serve mais.
+	if resp.StatusCode == http.StatusGone || bytes.Contains(msg, []byte("BadDeviceToken")) {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("apns respondeu %d: %s", resp.StatusCode, msg)
+}
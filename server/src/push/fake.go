@@ -0,0 +1,43 @@
+package push
+
+import (
+	"context"
+	"sync"
+)
+
+// Sent é uma notificação registrada pelo Fake.
+type Sent struct {
+	Token        string
+	Notification Notification
+}
+
+// Fake guarda as notificações em memória, para testes e desenvolvimento.
+// Tokens em Invalid são recusados com ErrInvalidToken.
+type Fake struct {
+	mu      sync.Mutex
+	sent    []Sent
+	Invalid map[string]bool
+}
+
+func NewFake() *Fake {
+	return &Fake{Invalid: make(map[string]bool)}
+}
+
+func (f *Fake) Push(_ context.Context, token string, n *Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Invalid[token] {
+		return ErrInvalidToken
+	}
+	f.sent = append(f.sent, Sent{Token: token, Notification: *n})
+	return nil
+}
+
+// Sent devolve uma cópia do que foi enviado até agora.
+func (f *Fake) Sent() []Sent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Sent(nil), f.sent...)
+}
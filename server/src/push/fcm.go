@@ -0,0 +1,93 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2/jwt"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCM usa a API HTTP v1 do Firebase Cloud Messaging, autenticada com a conta
+// de serviço do projeto.
+type FCM struct {
+	client   *http.Client
+	endpoint string
+}
+
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+func NewFCM(credentialsFile string) (*FCM, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	var sa serviceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("credenciais do FCM inválidas: %w", err)
+	}
+
+	conf := &jwt.Config{
+		Email:      sa.ClientEmail,
+		PrivateKey: []byte(sa.PrivateKey),
+		TokenURL:   sa.TokenURI,
+		Scopes:     []string{fcmScope},
+	}
+	return &FCM{
+		client:   conf.Client(context.Background()),
+		endpoint: "https://fcm.googleapis.com/v1/projects/" + sa.ProjectID + "/messages:send",
+	}, nil
+}
+
+func (f *FCM) Push(ctx context.Context, token string, n *Notification) error {
+	body, err := json.Marshal(map[string]any{
+		"message": map[string]any{
+			"token": token,
+			"notification": map[string]string{
+				"title": n.Title,
+				"body":  n.Body,
+			},
+			"data": n.Data,
+			"android": map[string]any{
+				"collapse_key": n.CollapseKey,
+				"priority":     "high",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	// 404 UNREGISTERED: o app saiu do aparelho.
+	if resp.StatusCode == http.StatusNotFound || bytes.Contains(msg, []byte("UNREGISTERED")) {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("fcm respondeu %d: %s", resp.StatusCode, msg)
+}
@@ -0,0 +1,35 @@
+// Package push entrega notificações a dispositivos offline pelos serviços de
+// push de cada plataforma.
+package push
+
+import (
+	"context"
+	"errors"
+)
+
+// Provedores aceitos no registro de tokens.
+const (
+	ProviderFCM         = "fcm"
+	ProviderAPNs        = "apns"
+	ProviderUnifiedPush = "unifiedpush"
+	ProviderFake        = "fake"
+)
+
+// ErrInvalidToken indica que o provedor recusou o token de vez (app
+// desinstalado, token expirado); quem chamou deve apagá-lo.
+var ErrInvalidToken = errors.New("token de push inválido")
+
+// Notification é o que chega ao aparelho. No modo "hint" Title e Body vêm
+// genéricos e Data não carrega conteúdo, para clientes com criptografia de
+// ponta a ponta.
+type Notification struct {
+	Title       string
+	Body        string
+	CollapseKey string
+	Data        map[string]string
+}
+
+// Pusher envia uma notificação para um token de um provedor.
+type Pusher interface {
+	Push(ctx context.Context, token string, n *Notification) error
+}
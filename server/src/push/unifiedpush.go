@@ -0,0 +1,76 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// UnifiedPush entrega ao endpoint que o distribuidor do aparelho registrou;
+// o token é a própria URL. Serve também para qualquer webhook que aceite o
+// mesmo corpo JSON. Como a URL vem do usuário, client deve recusar
+// redirecionamentos e endereços internos, como o de webhook.NewClient.
+type UnifiedPush struct {
+	client *http.Client
+}
+
+func NewUnifiedPush(client *http.Client) *UnifiedPush {
+	return &UnifiedPush{client: client}
+}
+
+// ValidEndpoint aceita só URLs https absolutas com nome de host; IPs
+// literais e localhost são recusados já no registro. Nomes que resolvem
+// para a rede interna são barrados na conexão.
+func ValidEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if net.ParseIP(host) != nil || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	return true
+}
+
+func (u *UnifiedPush) Push(ctx context.Context, endpoint string, n *Notification) error {
+	body, err := json.Marshal(map[string]any{
+		"title":       n.Title,
+		"body":        n.Body,
+		"collapseKey": n.CollapseKey,
+		"data":        n.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.CollapseKey != "" {
+		// Cabeçalho do Web Push, respeitado pelos distribuidores UnifiedPush.
+		req.Header.Set("Topic", n.CollapseKey)
+	}
+	req.Header.Set("Urgency", "high")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("endpoint respondeu %d", resp.StatusCode)
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"strings"
+)
+
+// Chaves de identificação de uma política.
+const (
+	KeyIP   = "ip"
+	KeyUser = "user"
+)
+
+// Policy aplica Rate por IP ou por usuário às rotas listadas, no formato
+// "MÉTODO /caminho" ou só "/caminho", com o caminho como registrado no gin
+// ("/users/:userId"). Sem rotas, a política vale para todas. As rotas de
+// uma política dividem o mesmo bucket.
+type Policy struct {
+	Name   string
+	Key    string
+	Routes []string
+	Rate
+}
+
+func (p *Policy) Matches(method, route string) bool {
+	if len(p.Routes) == 0 {
+		return true
+	}
+	for _, r := range p.Routes {
+		m, path, ok := strings.Cut(r, " ")
+		if !ok {
+			path, m = r, ""
+		}
+		if path == route && (m == "" || strings.EqualFold(m, method)) {
+			return true
+		}
+	}
+	return false
+}
+
+type Limiter struct {
+	Store    Store
+	policies []Policy
+}
+
+func NewLimiter(store Store, policies []Policy) *Limiter {
+	return &Limiter{Store: store, policies: policies}
+}
+
+// For devolve as políticas do tipo de chave key que valem para a rota.
+func (l *Limiter) For(key, method, route string) []Policy {
+	var out []Policy
+	for _, p := range l.policies {
+		if p.Key == key && p.Matches(method, route) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memBucket
+	lastSweep time.Time
+}
+
+type memBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // quando o bucket estará cheio e pode ser descartado
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memBucket)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, rate Rate) (Result, error) {
+	return s.take(key, rate, time.Now()), nil
+}
+
+func (s *MemoryStore) take(key string, rate Rate, now time.Time) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memBucket{tokens: rate.capacity(), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = min(rate.capacity(), b.tokens+now.Sub(b.last).Seconds()*rate.perSecond())
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	res := result(rate, b.tokens, allowed)
+	b.full = now.Add(res.Reset)
+	return res
+}
+
+// sweep descarta, no máximo uma vez por minuto, buckets que já encheram:
+// recriá-los cheios dá no mesmo.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if now.After(b.full) {
+			delete(s.buckets, k)
+		}
+	}
+}
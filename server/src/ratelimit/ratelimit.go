@@ -0,0 +1,78 @@
+// Package ratelimit implementa token buckets com armazenamento em memória
+// (uma instância) ou no Redis (compartilhado entre instâncias).
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Rate é a política de um bucket: limit fichas a cada period, acumulando no
+// máximo burst.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+func (r Rate) perSecond() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+func (r Rate) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Limit)
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // até o bucket encher de novo
+	RetryAfter time.Duration // só quando Allowed é false
+}
+
+// Store consome uma ficha do bucket de key.
+type Store interface {
+	Take(ctx context.Context, key string, rate Rate) (Result, error)
+}
+
+// result traduz o estado do bucket depois da tentativa.
+func result(rate Rate, tokens float64, allowed bool) Result {
+	perSec := rate.perSecond()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     int(rate.capacity()),
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((rate.capacity() - tokens) / perSec * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / perSec * float64(time.Second))
+	}
+	return res
+}
+
+// Bucket é um token bucket local, sem trava, para quem consome de uma só
+// goroutine (o loop de leitura de uma conexão, por exemplo).
+type Bucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+func NewBucket(rate Rate) *Bucket {
+	return &Bucket{rate: rate, tokens: rate.capacity(), last: time.Now()}
+}
+
+func (b *Bucket) Allow(now time.Time) bool {
+	b.tokens = min(b.rate.capacity(), b.tokens+now.Sub(b.last).Seconds()*b.rate.perSecond())
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRefill(t *testing.T) {
+	s := NewMemoryStore()
+	rate := Rate{Limit: 2, Period: time.Second, Burst: 4}
+	start := time.Now()
+
+	steps := []struct {
+		at        time.Duration
+		allowed   bool
+		remaining int
+		reset     time.Duration
+		retry     time.Duration
+	}{
+		{0, true, 3, 500 * time.Millisecond, 0},
+		{0, true, 2, time.Second, 0},
+		{0, true, 1, 1500 * time.Millisecond, 0},
+		{0, true, 0, 2 * time.Second, 0},
+		{0, false, 0, 2 * time.Second, 500 * time.Millisecond},
+		// Meio segundo repõe uma ficha, que é consumida na hora.
+		{500 * time.Millisecond, true, 0, 2 * time.Second, 0},
+		{750 * time.Millisecond, false, 0, 1750 * time.Millisecond, 250 * time.Millisecond},
+		// Parado por muito tempo, o bucket enche só até o burst.
+		{time.Hour, true, 3, 500 * time.Millisecond, 0},
+	}
+	for i, st := range steps {
+		res := s.take("k", rate, start.Add(st.at))
+		if res.Allowed != st.allowed || res.Remaining != st.remaining || res.Limit != 4 {
+			t.Fatalf("passo %d: allowed %v, remaining %d, limit %d", i, res.Allowed, res.Remaining, res.Limit)
+		}
+		if !near(res.Reset, st.reset) || !near(res.RetryAfter, st.retry) {
+			t.Fatalf("passo %d: reset %s, retry %s; esperava %s e %s", i, res.Reset, res.RetryAfter, st.reset, st.retry)
+		}
+	}
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	s := NewMemoryStore()
+	rate := Rate{Limit: 1, Period: time.Minute}
+	now := time.Now()
+
+	if !s.take("a", rate, now).Allowed || s.take("a", rate, now).Allowed {
+		t.Fatal("limite de a não aplicado")
+	}
+	if !s.take("b", rate, now).Allowed {
+		t.Fatal("b dividiu o bucket com a")
+	}
+}
+
+func TestBucket(t *testing.T) {
+	start := time.Now()
+	b := NewBucket(Rate{Limit: 10, Period: time.Second, Burst: 3})
+	b.last = start
+
+	for i := range 3 {
+		if !b.Allow(start) {
+			t.Fatalf("frame %d do burst recusado", i)
+		}
+	}
+	if b.Allow(start) {
+		t.Fatal("frame acima do burst aceito")
+	}
+	if !b.Allow(start.Add(100 * time.Millisecond)) {
+		t.Fatal("ficha não reposta depois de 100ms")
+	}
+}
+
+func TestPolicyMatches(t *testing.T) {
+	p := Policy{Routes: []string{"POST /auth/login", "/check"}}
+	cases := []struct {
+		method, route string
+		want          bool
+	}{
+		{"POST", "/auth/login", true},
+		{"post", "/auth/login", true},
+		{"GET", "/auth/login", false},
+		{"GET", "/check", true},
+		{"DELETE", "/check", true},
+		{"POST", "/auth/register", false},
+	}
+	for _, c := range cases {
+		if got := p.Matches(c.method, c.route); got != c.want {
+			t.Errorf("%s %s: %v", c.method, c.route, got)
+		}
+	}
+	if all := (Policy{}); !all.Matches("GET", "/qualquer") {
+		t.Error("política sem rotas deveria valer para todas")
+	}
+}
+
+// TestRedisStore roda o script Lua contra um Redis de verdade, indicado em
+// WISP_TEST_REDIS (ex.: localhost:6379).
+func TestRedisStore(t *testing.T) {
+	addr := os.Getenv("WISP_TEST_REDIS")
+	if addr == "" {
+		t.Skip("WISP_TEST_REDIS não definido")
+	}
+	s, err := NewRedisStore(addr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	key := "test:" + time.Now().Format(time.RFC3339Nano)
+	rate := Rate{Limit: 2, Period: time.Minute}
+	for i, want := range []bool{true, true, false} {
+		res, err := s.Take(context.Background(), key, rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != want || res.Remaining != max(1-i, 0) {
+			t.Fatalf("tentativa %d: allowed %v, remaining %d", i, res.Allowed, res.Remaining)
+		}
+		if !want && !near(res.RetryAfter, 30*time.Second) {
+			t.Fatalf("retry %s, esperava uns 30s", res.RetryAfter)
+		}
+	}
+}
+
+func near(a, b time.Duration) bool {
+	d := a - b
+	return d > -10*time.Millisecond && d < 10*time.Millisecond
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "wisp:rl:"
+
+// O bucket fica num hash com as fichas e o horário da última leitura, usando
+// o relógio do Redis para que instâncias com relógios diferentes concordem.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil then
+  tokens = capacity
+  ts = now
+end
+
+tokens = math.min(capacity, tokens + (now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('EXPIRE', KEYS[1], math.ceil(capacity / rate) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(addr, password string) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+	return &RedisStore{client: client}, nil
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, rate Rate) (Result, error) {
+	out, err := takeScript.Run(ctx, s.client, []string{redisKeyPrefix + key},
+		rate.perSecond(), rate.capacity()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	allowed, _ := out[0].(int64)
+	tokens, _ := strconv.ParseFloat(out[1].(string), 64)
+	return result(rate, tokens, allowed == 1), nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APITokenRepo struct{ col *mongo.Collection }
+
+func NewAPITokenRepo(db *mongo.Database) *APITokenRepo {
+	col := db.Collection("api_tokens")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "botId", Value: 1}}},
+	})
+	return &APITokenRepo{col: col}
+}
+
+func (r *APITokenRepo) Create(ctx context.Context, t *model.APIToken) error {
+	defer metrics.ObserveMongo("APITokenRepo", "Create")()
+
+	t.ID = primitive.NewObjectID()
+	t.CreatedAt = time.Now()
+	_, err := r.col.InsertOne(ctx, t)
+	return err
+}
+
+func (r *APITokenRepo) FindByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	defer metrics.ObserveMongo("APITokenRepo", "FindByHash")()
+
+	var t model.APIToken
+	err := r.col.FindOne(ctx, bson.M{"hash": hash}).Decode(&t)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	return &t, err
+}
+
+func (r *APITokenRepo) ListByBot(ctx context.Context, botID string) ([]model.APIToken, error) {
+	defer metrics.ObserveMongo("APITokenRepo", "ListByBot")()
+
+	cur, err := r.col.Find(ctx, bson.M{"botId": botID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	tokens := []model.APIToken{}
+	err = cur.All(ctx, &tokens)
+	return tokens, err
+}
+
+func (r *APITokenRepo) Delete(ctx context.Context, botID string, id primitive.ObjectID) error {
+	defer metrics.ObserveMongo("APITokenRepo", "Delete")()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "botId": botID})
+	if err == nil && res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *APITokenRepo) DeleteByBot(ctx context.Context, botID string) error {
+	defer metrics.ObserveMongo("APITokenRepo", "DeleteByBot")()
+
+	_, err := r.col.DeleteMany(ctx, bson.M{"botId": botID})
+	return err
+}
+
+// Touch marca o uso do token, no máximo uma vez por minuto para não gravar
+// a cada requisição.
+func (r *APITokenRepo) Touch(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	defer metrics.ObserveMongo("APITokenRepo", "Touch")()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"lastUsedAt": bson.M{"$exists": false}},
+			bson.M{"lastUsedAt": bson.M{"$lt": now.Add(-time.Minute)}},
+		},
+	}, bson.M{"$set": bson.M{"lastUsedAt": now}})
+	return err
+}
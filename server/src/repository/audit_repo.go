@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditRepo struct{ col *mongo.Collection }
+
+func NewAuditRepo(db *mongo.Database) *AuditRepo {
+	col := db.Collection("audit_events")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "actorId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "targetId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return &AuditRepo{col: col}
+}
+
+func (r *AuditRepo) Insert(ctx context.Context, ev *model.AuditEvent) error {
+	defer metrics.ObserveMongo("AuditRepo", "Insert")()
+
+	_, err := r.col.InsertOne(ctx, ev)
+	return err
+}
+
+func (r *AuditRepo) Find(ctx context.Context, f model.AuditFilter, page, limit int) ([]model.AuditEvent, int64, error) {
+	defer metrics.ObserveMongo("AuditRepo", "Find")()
+
+	filter := auditQuery(f)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	events := []model.AuditEvent{}
+	if err := cur.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	total, err := r.col.CountDocuments(ctx, filter)
+	return events, total, err
+}
+
+// Each percorre todos os eventos do filtro, do mais recente ao mais antigo,
+// sem carregar o resultado inteiro em memória.
+func (r *AuditRepo) Each(ctx context.Context, f model.AuditFilter, fn func(*model.AuditEvent) error) error {
+	defer metrics.ObserveMongo("AuditRepo", "Each")()
+
+	cur, err := r.col.Find(ctx, auditQuery(f), options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var ev model.AuditEvent
+		if err := cur.Decode(&ev); err != nil {
+			return err
+		}
+		if err := fn(&ev); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+func auditQuery(f model.AuditFilter) bson.M {
+	filter := bson.M{}
+	if f.Action != "" {
+		filter["action"] = f.Action
+	}
+	if f.ActorID != "" {
+		filter["actorId"] = f.ActorID
+	}
+	if f.TargetID != "" {
+		filter["targetId"] = f.TargetID
+	}
+	created := bson.M{}
+	if !f.From.IsZero() {
+		created["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		created["$lt"] = f.To
+	}
+	if len(created) > 0 {
+		filter["createdAt"] = created
+	}
+	return filter
+}
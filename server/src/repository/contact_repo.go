@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"time"
+	"wisp/src/metrics"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -16,6 +17,8 @@ func NewContactRepo(db *mongo.Database) *ContactRepo {
 }
 
 func (r *ContactRepo) EnsureDoc(ctx context.Context, ownerID string) error {
+	defer metrics.ObserveMongo("ContactRepo", "EnsureDoc")()
+
 	now := time.Now()
 	_, err := r.col.UpdateOne(ctx,
 		bson.M{"ownerId": ownerID},
@@ -26,6 +29,8 @@ func (r *ContactRepo) EnsureDoc(ctx context.Context, ownerID string) error {
 }
 
 func (r *ContactRepo) AddContact(ctx context.Context, ownerID, contactID string) error {
+	defer metrics.ObserveMongo("ContactRepo", "AddContact")()
+
 	if err := r.EnsureDoc(ctx, ownerID); err != nil {
 		return err
 	}
@@ -38,6 +43,8 @@ func (r *ContactRepo) AddContact(ctx context.Context, ownerID, contactID string)
 }
 
 func (r *ContactRepo) RemoveContact(ctx context.Context, ownerID, contactID string) error {
+	defer metrics.ObserveMongo("ContactRepo", "RemoveContact")()
+
 	_, err := r.col.UpdateOne(ctx,
 		bson.M{"ownerId": ownerID},
 		bson.M{"$pull": bson.M{"contactIds": contactID},
@@ -45,3 +52,10 @@ func (r *ContactRepo) RemoveContact(ctx context.Context, ownerID, contactID stri
 	)
 	return err
 }
+
+func (r *ContactRepo) IsContact(ctx context.Context, ownerID, contactID string) (bool, error) {
+	defer metrics.ObserveMongo("ContactRepo", "IsContact")()
+
+	n, err := r.col.CountDocuments(ctx, bson.M{"ownerId": ownerID, "contactIds": contactID})
+	return n > 0, err
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ConversationRepo struct{ col *mongo.Collection }
+
+func NewConversationRepo(db *mongo.Database) *ConversationRepo {
+	return &ConversationRepo{col: db.Collection("conversation_settings")}
+}
+
+// Get devolve as configurações da conversa entre a e b; sem documento,
+// devolve os padrões.
+func (r *ConversationRepo) Get(ctx context.Context, a, b string) (*model.ConversationSettings, error) {
+	defer metrics.ObserveMongo("ConversationRepo", "Get")()
+
+	key := model.ConversationKey(a, b)
+	var s model.ConversationSettings
+	err := r.col.FindOne(ctx, bson.M{"_id": key}).Decode(&s)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &model.ConversationSettings{ID: key, Members: []string{a, b}}, nil
+	}
+	return &s, err
+}
+
+func (r *ConversationRepo) SetTimer(ctx context.Context, by, other string, seconds int64) (*model.ConversationSettings, error) {
+	defer metrics.ObserveMongo("ConversationRepo", "SetTimer")()
+
+	key := model.ConversationKey(by, other)
+	var s model.ConversationSettings
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{
+		"members":        []string{by, other},
+		"disappearAfter": seconds,
+		"updatedBy":      by,
+		"updatedAt":      time.Now(),
+	}}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&s)
+	return &s, err
+}
+
+type ExpiryRepo struct{ col *mongo.Collection }
+
+func NewExpiryRepo(db *mongo.Database) *ExpiryRepo {
+	col := db.Collection("message_expiries")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}},
+	})
+	return &ExpiryRepo{col: col}
+}
+
+func (r *ExpiryRepo) Insert(ctx context.Context, e *model.MessageExpiry) error {
+	defer metrics.ObserveMongo("ExpiryRepo", "Insert")()
+
+	_, err := r.col.InsertOne(ctx, e)
+	return err
+}
+
+// TakeDue remove e devolve a próxima mensagem vencida, ou nil. A remoção
+// atômica garante que cada uma seja processada por uma instância só.
+func (r *ExpiryRepo) TakeDue(ctx context.Context, now time.Time) (*model.MessageExpiry, error) {
+	defer metrics.ObserveMongo("ExpiryRepo", "TakeDue")()
+
+	var e model.MessageExpiry
+	err := r.col.FindOneAndDelete(ctx, bson.M{"expiresAt": bson.M{"$lte": now}},
+		options.FindOneAndDelete().SetSort(bson.D{{Key: "expiresAt", Value: 1}})).Decode(&e)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return &e, err
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"time"
+	"wisp/src/metrics"
 	"wisp/src/model"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -17,6 +18,8 @@ func NewFriendRequestRepo(db *mongo.Database) *FriendRequestRepo {
 }
 
 func (r *FriendRequestRepo) Create(ctx context.Context, from, to string) (primitive.ObjectID, error) {
+	defer metrics.ObserveMongo("FriendRequestRepo", "Create")()
+
 	fr := model.FriendRequest{
 		ID:         primitive.NewObjectID(),
 		FromUserID: from,
@@ -33,6 +36,8 @@ func (r *FriendRequestRepo) Create(ctx context.Context, from, to string) (primit
 }
 
 func (r *FriendRequestRepo) GetIncoming(ctx context.Context, to string) ([]model.FriendRequest, error) {
+	defer metrics.ObserveMongo("FriendRequestRepo", "GetIncoming")()
+
 	cur, err := r.col.Find(ctx, bson.M{"toUserId": to})
 	if err != nil {
 		return nil, err
@@ -48,6 +53,8 @@ func (r *FriendRequestRepo) GetIncoming(ctx context.Context, to string) ([]model
 }
 
 func (r *FriendRequestRepo) GetSent(ctx context.Context, from string) ([]model.FriendRequest, error) {
+	defer metrics.ObserveMongo("FriendRequestRepo", "GetSent")()
+
 	cur, err := r.col.Find(ctx, bson.M{"fromUserId": from})
 	if err != nil {
 		return nil, err
@@ -66,11 +73,15 @@ func (r *FriendRequestRepo) GetSent(ctx context.Context, from string) ([]model.F
 }
 
 func (r *FriendRequestRepo) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	defer metrics.ObserveMongo("FriendRequestRepo", "DeleteByID")()
+
 	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
 
 func (r *FriendRequestRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.FriendRequest, error) {
+	defer metrics.ObserveMongo("FriendRequestRepo", "FindByID")()
+
 	var fr model.FriendRequest
 	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&fr)
 	if err != nil {
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type HistoryRepo struct{ col *mongo.Collection }
+
+func NewHistoryRepo(db *mongo.Database) *HistoryRepo {
+	col := db.Collection("messages")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "members", Value: 1}, {Key: "createdAt", Value: -1}}},
+		// Sem idioma padrão: as conversas misturam línguas, então não há
+		// stemming nem stop words, só a busca por palavras inteiras.
+		{
+			Keys: bson.D{
+				{Key: "content", Value: "text"},
+				{Key: "attachments.title", Value: "text"},
+				{Key: "attachments.text", Value: "text"},
+			},
+			Options: options.Index().SetDefaultLanguage("none").SetName("content_text"),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return &HistoryRepo{col: col}
+}
+
+// InsertMany grava um lote. Mensagens já gravadas são ignoradas, para que um
+// lote reenviado depois de uma falha parcial não dê erro.
+func (r *HistoryRepo) InsertMany(ctx context.Context, msgs []*model.HistoryMessage) error {
+	defer metrics.ObserveMongo("HistoryRepo", "InsertMany")()
+
+	docs := make([]any, len(msgs))
+	for i, m := range msgs {
+		docs[i] = m
+	}
+	_, err := r.col.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) && bwe.WriteConcernError == nil {
+		for _, we := range bwe.WriteErrors {
+			if !mongo.IsDuplicateKeyError(we) {
+				return err
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+func (r *HistoryRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer metrics.ObserveMongo("HistoryRepo", "Delete")()
+
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// FindByIDs devolve, das mensagens pedidas, as que userID pode ver e que não
+// venceram, na ordem de ids.
+func (r *HistoryRepo) FindByIDs(ctx context.Context, userID string, ids []primitive.ObjectID) ([]model.HistoryMessage, error) {
+	defer metrics.ObserveMongo("HistoryRepo", "FindByIDs")()
+
+	cur, err := r.col.Find(ctx, bson.M{
+		"_id":       bson.M{"$in": ids},
+		"members":   userID,
+		"expiresAt": bson.M{"$not": bson.M{"$lte": time.Now()}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	byID := make(map[primitive.ObjectID]model.HistoryMessage, len(ids))
+	for cur.Next(ctx) {
+		var m model.HistoryMessage
+		if err := cur.Decode(&m); err == nil {
+			byID[m.ID] = m
+		}
+	}
+	msgs := make([]model.HistoryMessage, 0, len(byID))
+	for _, id := range ids {
+		if m, ok := byID[id]; ok {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs, cur.Err()
+}
+
+// Search usa o índice de texto. O filtro por members vem sempre do usuário
+// autenticado; mensagens de sistema e vencidas ficam de fora.
+func (r *HistoryRepo) Search(ctx context.Context, q *model.SearchQuery) ([]model.HistoryMessage, error) {
+	defer metrics.ObserveMongo("HistoryRepo", "Search")()
+
+	filter := bson.M{
+		"$text":     bson.M{"$search": q.Text},
+		"members":   q.UserID,
+		"event":     bson.M{"$exists": false},
+		"expiresAt": bson.M{"$not": bson.M{"$lte": time.Now()}},
+	}
+	if q.Peer != "" {
+		filter["members"] = bson.M{"$all": bson.A{q.UserID, q.Peer}}
+	}
+	if q.FromMe {
+		filter["from"] = q.UserID
+	}
+	if q.HasAttachment {
+		filter["hasAttachments"] = true
+	}
+	created := bson.M{}
+	if !q.Since.IsZero() {
+		created["$gte"] = q.Since
+	}
+	if !q.Until.IsZero() {
+		created["$lte"] = q.Until
+	}
+	if len(created) > 0 {
+		filter["createdAt"] = created
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "createdAt", Value: -1}}).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit))
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var msgs []model.HistoryMessage
+	for cur.Next(ctx) {
+		var m model.HistoryMessage
+		if err := cur.Decode(&m); err == nil {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs, cur.Err()
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InboxRepo guarda a lista de conversas de cada usuário, um documento por
+// lado da conversa.
+type InboxRepo struct{ col *mongo.Collection }
+
+func NewInboxRepo(db *mongo.Database) *InboxRepo {
+	col := db.Collection("conversations")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "lastActivityAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "lastMessage.id", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	return &InboxRepo{col: col}
+}
+
+func inboxID(userID, peerID string) string {
+	return userID + ":" + peerID
+}
+
+// Apply registra um lote de mensagens, em ordem, como última mensagem das
+// conversas dos dois lados, somando ao não lido do destinatário. Devolve os
+// ids dos documentos alterados.
+func (r *InboxRepo) Apply(ctx context.Context, msgs []*model.HistoryMessage) ([]string, error) {
+	defer metrics.ObserveMongo("InboxRepo", "Apply")()
+
+	var writes []mongo.WriteModel
+	seen := make(map[string]bool)
+	var ids []string
+	add := func(userID, peerID string, set bson.M, unread bool) {
+		id := inboxID(userID, peerID)
+		update := bson.M{"$set": set, "$setOnInsert": bson.M{"userId": userID, "peerId": peerID}}
+		if unread {
+			update["$inc"] = bson.M{"unread": 1}
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).SetUpdate(update).SetUpsert(true))
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, m := range msgs {
+		set := bson.M{"lastMessage": model.NewConversationPreview(m), "lastActivityAt": m.CreatedAt}
+		add(m.From, m.To, set, false)
+		if m.To != m.From {
+			add(m.To, m.From, set, m.Event == nil)
+		}
+	}
+	if len(writes) == 0 {
+		return nil, nil
+	}
+
+	_, err := r.col.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true))
+	return ids, err
+}
+
+func (r *InboxRepo) FindByIDs(ctx context.Context, ids []string) ([]model.Conversation, error) {
+	defer metrics.ObserveMongo("InboxRepo", "FindByIDs")()
+
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}}, nil)
+}
+
+// List pagina as conversas do usuário da mais para a menos recente, as
+// arquivadas ou as da caixa de entrada, sem as fixadas. O cursor é a última
+// conversa da página anterior; zerado, começa do início.
+func (r *InboxRepo) List(ctx context.Context, userID string, archived bool, before time.Time, beforeID string, limit int) ([]model.Conversation, error) {
+	defer metrics.ObserveMongo("InboxRepo", "List")()
+
+	filter := bson.M{"userId": userID, "pinned": bson.M{"$ne": true}}
+	if archived {
+		filter["archived"] = true
+	} else {
+		filter["archived"] = bson.M{"$ne": true}
+	}
+	if !before.IsZero() {
+		filter["$or"] = bson.A{
+			bson.M{"lastActivityAt": bson.M{"$lt": before}},
+			bson.M{"lastActivityAt": before, "_id": bson.M{"$lt": beforeID}},
+		}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "lastActivityAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	return r.find(ctx, filter, opts)
+}
+
+// ListPinned devolve as fixadas na ordem do usuário.
+func (r *InboxRepo) ListPinned(ctx context.Context, userID string) ([]model.Conversation, error) {
+	defer metrics.ObserveMongo("InboxRepo", "ListPinned")()
+
+	return r.find(ctx, bson.M{"userId": userID, "pinned": true}, options.Find().SetSort(bson.D{{Key: "pinOrder", Value: 1}}))
+}
+
+func (r *InboxRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]model.Conversation, error) {
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	convs := []model.Conversation{}
+	err = cur.All(ctx, &convs)
+	return convs, err
+}
+
+// MarkRead zera o não lido. Devolve nil se a conversa não existe.
+func (r *InboxRepo) MarkRead(ctx context.Context, userID, peerID string) (*model.Conversation, error) {
+	defer metrics.ObserveMongo("InboxRepo", "MarkRead")()
+
+	return r.updateState(ctx, userID, peerID, bson.M{"$set": bson.M{"unread": 0, "readAt": time.Now()}})
+}
+
+// SetMuted silencia (até until, ou sem prazo se nil) ou volta a notificar.
+// Devolve nil se a conversa não existe.
+func (r *InboxRepo) SetMuted(ctx context.Context, userID, peerID string, muted bool, until *time.Time) (*model.Conversation, error) {
+	defer metrics.ObserveMongo("InboxRepo", "SetMuted")()
+
+	update := bson.M{"$set": bson.M{"muted": true, "mutedUntil": until}}
+	if !muted {
+		update = bson.M{"$set": bson.M{"muted": false}, "$unset": bson.M{"mutedUntil": ""}}
+	} else if until == nil {
+		update = bson.M{"$set": bson.M{"muted": true}, "$unset": bson.M{"mutedUntil": ""}}
+	}
+	return r.updateState(ctx, userID, peerID, update)
+}
+
+// SetPinned fixa a conversa na posição order; order 0 desafixa. Fixar
+// tira a conversa do arquivo.
+func (r *InboxRepo) SetPinned(ctx context.Context, userID, peerID string, order int) (*model.Conversation, error) {
+	defer metrics.ObserveMongo("InboxRepo", "SetPinned")()
+
+	update := bson.M{"$set": bson.M{"pinned": true, "pinOrder": order, "archived": false}}
+	if order == 0 {
+		update = bson.M{"$set": bson.M{"pinned": false}, "$unset": bson.M{"pinOrder": ""}}
+	}
+	return r.updateState(ctx, userID, peerID, update)
+}
+
+// SetArchived arquiva ou desarquiva. Arquivar desafixa a conversa.
+func (r *InboxRepo) SetArchived(ctx context.Context, userID, peerID string, archived bool) (*model.Conversation, error) {
+	defer metrics.ObserveMongo("InboxRepo", "SetArchived")()
+
+	update := bson.M{"$set": bson.M{"archived": false}}
+	if archived {
+		update = bson.M{"$set": bson.M{"archived": true, "pinned": false}, "$unset": bson.M{"pinOrder": ""}}
+	}
+	return r.updateState(ctx, userID, peerID, update)
+}
+
+// ReorderPins grava a nova ordem das fixadas, a partir de 1.
+func (r *InboxRepo) ReorderPins(ctx context.Context, userID string, peerIDs []string) error {
+	defer metrics.ObserveMongo("InboxRepo", "ReorderPins")()
+
+	writes := make([]mongo.WriteModel, len(peerIDs))
+	for i, peerID := range peerIDs {
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": inboxID(userID, peerID), "pinned": true}).
+			SetUpdate(bson.M{"$set": bson.M{"pinOrder": i + 1}})
+	}
+	_, err := r.col.BulkWrite(ctx, writes)
+	return err
+}
+
+// IsMuted diz se o usuário silenciou a conversa e o prazo não passou.
+func (r *InboxRepo) IsMuted(ctx context.Context, userID, peerID string) (bool, error) {
+	defer metrics.ObserveMongo("InboxRepo", "IsMuted")()
+
+	n, err := r.col.CountDocuments(ctx, bson.M{
+		"_id":        inboxID(userID, peerID),
+		"muted":      true,
+		"mutedUntil": bson.M{"$not": bson.M{"$lte": time.Now()}},
+	})
+	return n > 0, err
+}
+
+func (r *InboxRepo) updateState(ctx context.Context, userID, peerID string, update bson.M) (*model.Conversation, error) {
+	var c model.Conversation
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": inboxID(userID, peerID)}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&c)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return &c, err
+}
+
+// ClearLastMessage tira a prévia da mensagem das conversas em que ela era a
+// última, quando ela some.
+func (r *InboxRepo) ClearLastMessage(ctx context.Context, messageID string) error {
+	defer metrics.ObserveMongo("InboxRepo", "ClearLastMessage")()
+
+	_, err := r.col.UpdateMany(ctx, bson.M{"lastMessage.id": messageID}, bson.M{"$unset": bson.M{"lastMessage": ""}})
+	return err
+}
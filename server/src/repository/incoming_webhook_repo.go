@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type IncomingWebhookRepo struct{ col *mongo.Collection }
+
+func NewIncomingWebhookRepo(db *mongo.Database) *IncomingWebhookRepo {
+	col := db.Collection("incoming_webhooks")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "ownerId", Value: 1}}},
+		{Keys: bson.D{{Key: "botId", Value: 1}}},
+	})
+	return &IncomingWebhookRepo{col: col}
+}
+
+func (r *IncomingWebhookRepo) Create(ctx context.Context, h *model.IncomingWebhook) error {
+	defer metrics.ObserveMongo("IncomingWebhookRepo", "Create")()
+
+	h.ID = primitive.NewObjectID()
+	h.CreatedAt = time.Now()
+	_, err := r.col.InsertOne(ctx, h)
+	return err
+}
+
+func (r *IncomingWebhookRepo) FindByHash(ctx context.Context, hash string) (*model.IncomingWebhook, error) {
+	defer metrics.ObserveMongo("IncomingWebhookRepo", "FindByHash")()
+
+	var h model.IncomingWebhook
+	err := r.col.FindOne(ctx, bson.M{"hash": hash}).Decode(&h)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	return &h, err
+}
+
+func (r *IncomingWebhookRepo) ListByOwner(ctx context.Context, ownerID string) ([]model.IncomingWebhook, error) {
+	defer metrics.ObserveMongo("IncomingWebhookRepo", "ListByOwner")()
+
+	cur, err := r.col.Find(ctx, bson.M{"ownerId": ownerID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	hooks := []model.IncomingWebhook{}
+	err = cur.All(ctx, &hooks)
+	return hooks, err
+}
+
+func (r *IncomingWebhookRepo) CountByOwner(ctx context.Context, ownerID string) (int64, error) {
+	defer metrics.ObserveMongo("IncomingWebhookRepo", "CountByOwner")()
+
+	return r.col.CountDocuments(ctx, bson.M{"ownerId": ownerID})
+}
+
+func (r *IncomingWebhookRepo) Delete(ctx context.Context, ownerID string, id primitive.ObjectID) error {
+	defer metrics.ObserveMongo("IncomingWebhookRepo", "Delete")()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "ownerId": ownerID})
+	if err == nil && res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *IncomingWebhookRepo) DeleteByBot(ctx context.Context, botID string) error {
+	defer metrics.ObserveMongo("IncomingWebhookRepo", "DeleteByBot")()
+
+	_, err := r.col.DeleteMany(ctx, bson.M{"botId": botID})
+	return err
+}
+
+// Touch marca o uso, no máximo uma vez por minuto.
+func (r *IncomingWebhookRepo) Touch(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	defer metrics.ObserveMongo("IncomingWebhookRepo", "Touch")()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"lastUsedAt": bson.M{"$exists": false}},
+			bson.M{"lastUsedAt": bson.M{"$lt": now.Add(-time.Minute)}},
+		},
+	}, bson.M{"$set": bson.M{"lastUsedAt": now}})
+	return err
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// indexes guarda o resultado da criação de índices de cada coleção, para o
+// readiness não liberar tráfego com índice único ou TTL faltando.
+var indexes = struct {
+	mu   sync.Mutex
+	errs map[string]error
+}{errs: make(map[string]error)}
+
+// Espera entre as tentativas de criar os índices de uma coleção que falhou,
+// dobrando a cada erro até indexRetryMax.
+var (
+	indexRetryBase = time.Second
+	indexRetryMax  = time.Minute
+)
+
+func createIndexes(col *mongo.Collection, models []mongo.IndexModel) {
+	ensureIndexes(col.Name(), func(ctx context.Context) error {
+		_, err := col.Indexes().CreateMany(ctx, models)
+		return err
+	})
+}
+
+// ensureIndexes tenta criar os índices na hora e, se falhar, continua
+// tentando em segundo plano com backoff até conseguir. Enquanto isso o
+// readiness fica fora.
+func ensureIndexes(name string, create func(ctx context.Context) error) {
+	err := setIndexResult(name, tryIndexes(create))
+	if err == nil {
+		return
+	}
+
+	base, limit := indexRetryBase, indexRetryMax
+	go func() {
+		for delay := base; err != nil; delay = min(delay*2, limit) {
+			log.Error().Err(err).Str("collection", name).Dur("retryIn", delay).Msg("Erro ao criar índices")
+			time.Sleep(delay)
+			err = setIndexResult(name, tryIndexes(create))
+		}
+		log.Info().Str("collection", name).Msg("Índices criados depois de nova tentativa")
+	}()
+}
+
+func tryIndexes(create func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return create(ctx)
+}
+
+func setIndexResult(name string, err error) error {
+	indexes.mu.Lock()
+	indexes.errs[name] = err
+	indexes.mu.Unlock()
+	return err
+}
+
+// IndexesReady retorna nil quando todos os índices foram criados.
+func IndexesReady() error {
+	indexes.mu.Lock()
+	defer indexes.mu.Unlock()
+
+	var errs []error
+	for name, err := range indexes.errs {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIndexesRetriedUntilCreated(t *testing.T) {
+	base, maxDelay := indexRetryBase, indexRetryMax
+	t.Cleanup(func() { indexRetryBase, indexRetryMax = base, maxDelay })
+	indexRetryBase, indexRetryMax = time.Millisecond, 4*time.Millisecond
+
+	var calls atomic.Int32
+	ensureIndexes("test_retry", func(ctx context.Context) error {
+		if calls.Add(1) < 4 {
+			return errors.New("servidor indisponível")
+		}
+		return nil
+	})
+	if IndexesReady() == nil {
+		t.Fatal("readiness liberado com índices faltando")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for IndexesReady() != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("índices não criados depois de %d tentativas", calls.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Fatalf("%d tentativas, esperava 4", n)
+	}
+}
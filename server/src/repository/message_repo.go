@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
+	"wisp/src/metrics"
 	"wisp/src/model"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -17,15 +19,28 @@ type MessageRepo struct {
 
 func NewMessageRepo(db *mongo.Database) *MessageRepo {
 	col := db.Collection("pending_messages")
-	idx := mongo.IndexModel{
-		Keys:    bson.D{{Key: "createdAt", Value: 1}},
-		Options: options.Index().SetExpireAfterSeconds(7 * 24 * 3600),
-	}
-	col.Indexes().CreateOne(context.Background(), idx)
+	createIndexes(col, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(7 * 24 * 3600),
+		},
+		{
+			Keys:    bson.D{{Key: "messageId", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		// Mensagens temporárias somem no prazo delas mesmo se o
+		// ExpiryService estiver parado.
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
 	return &MessageRepo{col: col}
 }
 
 func (r *MessageRepo) Insert(ctx context.Context, pm *model.PendingMessage) (primitive.ObjectID, error) {
+	defer metrics.ObserveMongo("MessageRepo", "Insert")()
+
 	pm.CreatedAt = time.Now()
 	res, err := r.col.InsertOne(ctx, pm)
 	if err != nil {
@@ -35,12 +50,48 @@ func (r *MessageRepo) Insert(ctx context.Context, pm *model.PendingMessage) (pri
 }
 
 func (r *MessageRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer metrics.ObserveMongo("MessageRepo", "Delete")()
+
 	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
 
-func (r *MessageRepo) GetPendingFor(ctx context.Context, to string) ([]model.PendingMessage, error) {
-	cur, err := r.col.Find(ctx, bson.M{"to": to})
+// DeleteMessage remove a mensagem e todas as cópias desviadas dela.
+func (r *MessageRepo) DeleteMessage(ctx context.Context, id primitive.ObjectID) error {
+	defer metrics.ObserveMongo("MessageRepo", "DeleteMessage")()
+
+	_, err := r.col.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"_id": id},
+		bson.M{"messageId": id.Hex()},
+	}})
+	return err
+}
+
+// DeleteAcked remove a mensagem confirmada e a cópia desviada para o
+// dispositivo que confirmou, se houver.
+func (r *MessageRepo) DeleteAcked(ctx context.Context, id primitive.ObjectID, to, deviceID string) error {
+	defer metrics.ObserveMongo("MessageRepo", "DeleteAcked")()
+
+	_, err := r.col.DeleteMany(ctx, bson.M{
+		"to": to,
+		"$or": bson.A{
+			bson.M{"_id": id},
+			bson.M{"messageId": id.Hex(), "deviceId": deviceID},
+		},
+	})
+	return err
+}
+
+func (r *MessageRepo) GetPendingFor(ctx context.Context, to, deviceID string) ([]model.PendingMessage, error) {
+	defer metrics.ObserveMongo("MessageRepo", "GetPendingFor")()
+
+	cur, err := r.col.Find(ctx, bson.M{
+		"to": to,
+		"$or": bson.A{
+			bson.M{"deviceId": bson.M{"$exists": false}},
+			bson.M{"deviceId": deviceID},
+		},
+	}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
 	if err != nil {
 		return nil, err
 	}
@@ -55,3 +106,49 @@ func (r *MessageRepo) GetPendingFor(ctx context.Context, to string) ([]model.Pen
 	}
 	return msgs, nil
 }
+
+func (r *MessageRepo) CountPending(ctx context.Context) (int64, error) {
+	defer metrics.ObserveMongo("MessageRepo", "CountPending")()
+
+	return r.col.EstimatedDocumentCount(ctx)
+}
+
+// WatchInserts acompanha as inserções na fila pendente via change stream,
+// retomando de resumeAfter quando informado. Se o token já saiu do oplog o
+// stream recomeça do ponto atual. Exige replica set.
+func (r *MessageRepo) WatchInserts(ctx context.Context, resumeAfter []byte, fn func(pm *model.PendingMessage, token []byte)) error {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+
+	opts := options.ChangeStream()
+	if len(resumeAfter) > 0 {
+		opts.SetResumeAfter(bson.Raw(resumeAfter))
+	}
+
+	stream, err := r.col.Watch(ctx, pipeline, opts)
+	if err != nil && len(resumeAfter) > 0 && isHistoryLost(err) {
+		stream, err = r.col.Watch(ctx, pipeline)
+	}
+	if err != nil {
+		return err
+	}
+	defer stream.Close(ctx)
+
+	for stream.Next(ctx) {
+		var ev struct {
+			FullDocument model.PendingMessage `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&ev); err != nil {
+			continue
+		}
+		fn(&ev.FullDocument, stream.ResumeToken())
+	}
+	return stream.Err()
+}
+
+func isHistoryLost(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == 286 || cmdErr.Code == 280
+	}
+	return false
+}
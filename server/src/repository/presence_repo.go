@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wisp/src/metrics"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Entradas de instâncias que pararam de mandar heartbeat somem pelo TTL.
+const presenceTTL = 90 * time.Second
+
+type PresenceRepo struct {
+	col *mongo.Collection
+}
+
+func NewPresenceRepo(db *mongo.Database) *PresenceRepo {
+	col := db.Collection("presence")
+	createIndexes(col, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "deviceId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "updatedAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(presenceTTL.Seconds())),
+		},
+		{
+			Keys: bson.D{{Key: "node", Value: 1}},
+		},
+	})
+	return &PresenceRepo{col: col}
+}
+
+func (r *PresenceRepo) Register(ctx context.Context, userID, deviceID, node string) error {
+	defer metrics.ObserveMongo("PresenceRepo", "Register")()
+
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"userId": userID, "deviceId": deviceID},
+		bson.M{"$set": bson.M{"node": node, "updatedAt": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *PresenceRepo) Unregister(ctx context.Context, userID, deviceID, node string) error {
+	defer metrics.ObserveMongo("PresenceRepo", "Unregister")()
+
+	_, err := r.col.DeleteOne(ctx, bson.M{"userId": userID, "deviceId": deviceID, "node": node})
+	return err
+}
+
+func (r *PresenceRepo) Lookup(ctx context.Context, userID string) (map[string]string, error) {
+	defer metrics.ObserveMongo("PresenceRepo", "Lookup")()
+
+	cur, err := r.col.Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make(map[string]string)
+	for cur.Next(ctx) {
+		var doc struct {
+			DeviceID string `bson:"deviceId"`
+			Node     string `bson:"node"`
+		}
+		if err := cur.Decode(&doc); err == nil {
+			out[doc.DeviceID] = doc.Node
+		}
+	}
+	return out, nil
+}
+
+func (r *PresenceRepo) Heartbeat(ctx context.Context, node string) error {
+	defer metrics.ObserveMongo("PresenceRepo", "Heartbeat")()
+
+	_, err := r.col.UpdateMany(ctx,
+		bson.M{"node": node},
+		bson.M{"$set": bson.M{"updatedAt": time.Now()}},
+	)
+	return err
+}
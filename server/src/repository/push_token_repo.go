@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PushTokenRepo struct{ col *mongo.Collection }
+
+func NewPushTokenRepo(db *mongo.Database) *PushTokenRepo {
+	col := db.Collection("push_tokens")
+	createIndexes(col, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "deviceId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "token", Value: 1}}},
+	})
+	return &PushTokenRepo{col: col}
+}
+
+// Upsert grava o token do dispositivo. O mesmo token registrado antes por
+// outra conta ou outro dispositivo é removido, para que um aparelho trocado
+// de dono não receba notificações do anterior.
+func (r *PushTokenRepo) Upsert(ctx context.Context, t *model.PushToken) error {
+	defer metrics.ObserveMongo("PushTokenRepo", "Upsert")()
+
+	_, err := r.col.DeleteMany(ctx, bson.M{
+		"provider": t.Provider,
+		"token":    t.Token,
+		"$or":      bson.A{bson.M{"userId": bson.M{"$ne": t.UserID}}, bson.M{"deviceId": bson.M{"$ne": t.DeviceID}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = r.col.UpdateOne(ctx,
+		bson.M{"userId": t.UserID, "deviceId": t.DeviceID},
+		bson.M{
+			"$set":         bson.M{"provider": t.Provider, "token": t.Token, "updatedAt": now},
+			"$setOnInsert": bson.M{"createdAt": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *PushTokenRepo) ListByUser(ctx context.Context, userID string) ([]model.PushToken, error) {
+	defer metrics.ObserveMongo("PushTokenRepo", "ListByUser")()
+
+	cur, err := r.col.Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	tokens := []model.PushToken{}
+	err = cur.All(ctx, &tokens)
+	return tokens, err
+}
+
+func (r *PushTokenRepo) Delete(ctx context.Context, userID, deviceID string) error {
+	defer metrics.ObserveMongo("PushTokenRepo", "Delete")()
+
+	_, err := r.col.DeleteOne(ctx, bson.M{"userId": userID, "deviceId": deviceID})
+	return err
+}
+
+func (r *PushTokenRepo) DeleteToken(ctx context.Context, provider, token string) error {
+	defer metrics.ObserveMongo("PushTokenRepo", "DeleteToken")()
+
+	_, err := r.col.DeleteMany(ctx, bson.M{"provider": provider, "token": token})
+	return err
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReceiptRepo struct {
+	col *mongo.Collection
+}
+
+func NewReceiptRepo(db *mongo.Database, window time.Duration) *ReceiptRepo {
+	col := db.Collection("message_receipts")
+	createIndexes(col, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "from", Value: 1}, {Key: "clientId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(window.Seconds())),
+		},
+	})
+	return &ReceiptRepo{col: col}
+}
+
+// Reserve grava o recibo do envio. Se o remetente já usou o mesmo clientId
+// dentro da janela, retorna o recibo original e true.
+func (r *ReceiptRepo) Reserve(ctx context.Context, rc *model.MessageReceipt) (*model.MessageReceipt, bool, error) {
+	defer metrics.ObserveMongo("ReceiptRepo", "Reserve")()
+
+	rc.CreatedAt = time.Now()
+	_, err := r.col.InsertOne(ctx, rc)
+	if err == nil {
+		return rc, false, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, err
+	}
+
+	var existing model.MessageReceipt
+	if err := r.col.FindOne(ctx, bson.M{"from": rc.From, "clientId": rc.ClientID}).Decode(&existing); err != nil {
+		return nil, false, err
+	}
+	return &existing, true, nil
+}
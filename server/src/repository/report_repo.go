@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReportRepo struct{ col *mongo.Collection }
+
+func NewReportRepo(db *mongo.Database) *ReportRepo {
+	col := db.Collection("reports")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "reporterId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "targetUserId", Value: 1}}},
+	})
+	return &ReportRepo{col: col}
+}
+
+func (r *ReportRepo) Create(ctx context.Context, rep *model.Report) error {
+	defer metrics.ObserveMongo("ReportRepo", "Create")()
+
+	now := time.Now()
+	rep.ID = primitive.NewObjectID()
+	rep.CreatedAt = now
+	rep.UpdatedAt = now
+	if rep.Notes == nil {
+		rep.Notes = []model.ReportNote{}
+	}
+	_, err := r.col.InsertOne(ctx, rep)
+	return err
+}
+
+func (r *ReportRepo) CountSince(ctx context.Context, reporterID string, since time.Time) (int64, error) {
+	defer metrics.ObserveMongo("ReportRepo", "CountSince")()
+
+	return r.col.CountDocuments(ctx, bson.M{"reporterId": reporterID, "createdAt": bson.M{"$gte": since}})
+}
+
+// FindPending devolve a denúncia ainda em aberto do mesmo denunciante sobre o
+// mesmo alvo e a mesma mensagem, se houver.
+func (r *ReportRepo) FindPending(ctx context.Context, reporterID, targetUserID, messageID string) (*model.Report, error) {
+	defer metrics.ObserveMongo("ReportRepo", "FindPending")()
+
+	filter := bson.M{
+		"reporterId":   reporterID,
+		"targetUserId": targetUserID,
+		"status":       bson.M{"$in": bson.A{model.ReportOpen, model.ReportReviewing}},
+	}
+	if messageID != "" {
+		filter["message.id"] = messageID
+	} else {
+		filter["message"] = bson.M{"$exists": false}
+	}
+
+	var rep model.Report
+	err := r.col.FindOne(ctx, filter).Decode(&rep)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return &rep, err
+}
+
+func (r *ReportRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Report, error) {
+	defer metrics.ObserveMongo("ReportRepo", "FindByID")()
+
+	var rep model.Report
+	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&rep)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	return &rep, err
+}
+
+// List devolve a fila do mais antigo para o mais novo, que é a ordem em que
+// os moderadores devem atender.
+func (r *ReportRepo) List(ctx context.Context, f model.ReportFilter, page, limit int) ([]model.Report, int64, error) {
+	defer metrics.ObserveMongo("ReportRepo", "List")()
+
+	filter := bson.M{}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.AssigneeID != "" {
+		filter["assigneeId"] = f.AssigneeID
+	}
+	if f.TargetUserID != "" {
+		filter["targetUserId"] = f.TargetUserID
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	reports := []model.Report{}
+	if err := cur.All(ctx, &reports); err != nil {
+		return nil, 0, err
+	}
+	total, err := r.col.CountDocuments(ctx, filter)
+	return reports, total, err
+}
+
+func (r *ReportRepo) Update(ctx context.Context, id primitive.ObjectID, upd bson.M) error {
+	defer metrics.ObserveMongo("ReportRepo", "Update")()
+
+	upd["updatedAt"] = time.Now()
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": upd})
+	if err == nil && res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// AddNoteCapped acrescenta a nota só se a denúncia tiver menos de max notas.
+// Devolve false quando o limite já foi atingido.
+func (r *ReportRepo) AddNoteCapped(ctx context.Context, id primitive.ObjectID, note model.ReportNote, max int) (bool, error) {
+	defer metrics.ObserveMongo("ReportRepo", "AddNoteCapped")()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":                          id,
+		"notes." + strconv.Itoa(max-1): bson.M{"$exists": false},
+	}, bson.M{
+		"$push": bson.M{"notes": note},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (r *ReportRepo) AddNote(ctx context.Context, id primitive.ObjectID, note model.ReportNote) error {
+	defer metrics.ObserveMongo("ReportRepo", "AddNote")()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$push": bson.M{"notes": note},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	if err == nil && res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Tentativas de Create quando outra criação ocupa o mesmo slot.
+const createAttempts = 5
+
+type ScheduledMessageRepo struct{ col *mongo.Collection }
+
+func NewScheduledMessageRepo(db *mongo.Database) *ScheduledMessageRepo {
+	col := db.Collection("scheduled_messages")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "sendAt", Value: 1}}},
+		{Keys: bson.D{{Key: "from", Value: 1}, {Key: "sendAt", Value: 1}}},
+		// Um slot só pode estar ocupado por um pendente do remetente.
+		{
+			Keys: bson.D{{Key: "from", Value: 1}, {Key: "slot", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": model.ScheduledPending,
+				"slot":   bson.M{"$exists": true},
+			}),
+		},
+	})
+	return &ScheduledMessageRepo{col: col}
+}
+
+// Create grava o agendamento se o remetente tiver menos de max pendentes e
+// informa se gravou. Cada pendente ocupa um slot de 1 a max, único por
+// remetente pelo índice, então duas criações simultâneas não passam juntas
+// do limite; o slot se libera quando o agendamento deixa de estar pendente.
+func (r *ScheduledMessageRepo) Create(ctx context.Context, m *model.ScheduledMessage, max int) (bool, error) {
+	defer metrics.ObserveMongo("ScheduledMessageRepo", "Create")()
+
+	for range createAttempts {
+		slot, err := r.freeSlot(ctx, m.From, max)
+		if err != nil || slot == 0 {
+			return false, err
+		}
+
+		now := time.Now()
+		m.ID = primitive.NewObjectID()
+		m.Status = model.ScheduledPending
+		m.Slot = slot
+		m.CreatedAt = now
+		m.UpdatedAt = now
+		_, err = r.col.InsertOne(ctx, m)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		return err == nil, err
+	}
+	return false, errors.New("muitas criações simultâneas de agendamento")
+}
+
+// freeSlot devolve o menor slot livre do remetente, ou 0 se ele já tem max
+// pendentes. Pendentes antigos, sem slot, contam para o limite.
+func (r *ScheduledMessageRepo) freeSlot(ctx context.Context, from string, max int) (int, error) {
+	filter := bson.M{"from": from, "status": model.ScheduledPending}
+	cur, err := r.col.Find(ctx, filter, options.Find().SetProjection(bson.M{"slot": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var pending []struct {
+		Slot int `bson:"slot"`
+	}
+	if err := cur.All(ctx, &pending); err != nil {
+		return 0, err
+	}
+	if len(pending) >= max {
+		return 0, nil
+	}
+
+	used := make(map[int]bool, len(pending))
+	for _, p := range pending {
+		used[p.Slot] = true
+	}
+	for slot := 1; slot <= max; slot++ {
+		if !used[slot] {
+			return slot, nil
+		}
+	}
+	return 0, nil
+}
+
+// ListByUser devolve os agendamentos do remetente pela data de envio.
+func (r *ScheduledMessageRepo) ListByUser(ctx context.Context, from, status string) ([]model.ScheduledMessage, error) {
+	defer metrics.ObserveMongo("ScheduledMessageRepo", "ListByUser")()
+
+	filter := bson.M{"from": from}
+	if status != "" {
+		filter["status"] = status
+	}
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "sendAt", Value: 1}}).SetLimit(500))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	msgs := []model.ScheduledMessage{}
+	err = cur.All(ctx, &msgs)
+	return msgs, err
+}
+
+// UpdatePending altera um agendamento que ainda não foi enviado nem está
+// sendo enviado agora. Devolve o documento atualizado, ou nil se ele não
+// estava mais editável.
+func (r *ScheduledMessageRepo) UpdatePending(ctx context.Context, id primitive.ObjectID, from string, upd bson.M) (*model.ScheduledMessage, error) {
+	defer metrics.ObserveMongo("ScheduledMessageRepo", "UpdatePending")()
+
+	now := time.Now()
+	upd["updatedAt"] = now
+	filter := bson.M{
+		"_id":        id,
+		"from":       from,
+		"status":     model.ScheduledPending,
+		"leaseUntil": bson.M{"$lte": now},
+	}
+	var m model.ScheduledMessage
+	err := r.col.FindOneAndUpdate(ctx, filter, bson.M{"$set": upd}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return &m, err
+}
+
+func (r *ScheduledMessageRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.ScheduledMessage, error) {
+	defer metrics.ObserveMongo("ScheduledMessageRepo", "FindByID")()
+
+	var m model.ScheduledMessage
+	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	return &m, err
+}
+
+// Claim reserva o próximo agendamento vencido por lease, para que só uma
+// instância o envie. Devolve nil quando não há nenhum.
+func (r *ScheduledMessageRepo) Claim(ctx context.Context, now time.Time, lease time.Duration) (*model.ScheduledMessage, error) {
+	defer metrics.ObserveMongo("ScheduledMessageRepo", "Claim")()
+
+	filter := bson.M{
+		"status":     model.ScheduledPending,
+		"sendAt":     bson.M{"$lte": now},
+		"leaseUntil": bson.M{"$lte": now},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "sendAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var m model.ScheduledMessage
+	err := r.col.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"leaseUntil": now.Add(lease)}}, opts).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return &m, err
+}
+
+// Finish grava o resultado do envio e libera o lease.
+func (r *ScheduledMessageRepo) Finish(ctx context.Context, m *model.ScheduledMessage) error {
+	defer metrics.ObserveMongo("ScheduledMessageRepo", "Finish")()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": m.ID}, bson.M{"$set": bson.M{
+		"status":     m.Status,
+		"messageId":  m.MessageID,
+		"error":      m.Error,
+		"sentAt":     m.SentAt,
+		"leaseUntil": time.Time{},
+		"updatedAt":  time.Now(),
+	}})
+	return err
+}
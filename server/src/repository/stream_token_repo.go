@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wisp/src/metrics"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StreamTokenRepo struct {
+	col *mongo.Collection
+}
+
+func NewStreamTokenRepo(db *mongo.Database) *StreamTokenRepo {
+	return &StreamTokenRepo{col: db.Collection("stream_tokens")}
+}
+
+func (r *StreamTokenRepo) Load(ctx context.Context, node string) ([]byte, error) {
+	defer metrics.ObserveMongo("StreamTokenRepo", "Load")()
+
+	var doc struct {
+		Token bson.Raw `bson:"token"`
+	}
+	err := r.col.FindOne(ctx, bson.M{"_id": node}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.Token, nil
+}
+
+func (r *StreamTokenRepo) Save(ctx context.Context, node string, token []byte) error {
+	defer metrics.ObserveMongo("StreamTokenRepo", "Save")()
+
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": node},
+		bson.M{"$set": bson.M{"token": bson.Raw(token), "updatedAt": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
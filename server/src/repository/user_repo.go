@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"wisp/src/metrics"
 	"wisp/src/model"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,6 +14,10 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrNotFound indica que o documento procurado não existe, para quem precisa
+// separar isso de uma falha do banco.
+var ErrNotFound = errors.New("not found")
+
 type UserRepo struct{ col *mongo.Collection }
 
 func NewUserRepo(db *mongo.Database) *UserRepo {
@@ -20,6 +25,8 @@ func NewUserRepo(db *mongo.Database) *UserRepo {
 }
 
 func (r *UserRepo) Create(ctx context.Context, u *model.User) error {
+	defer metrics.ObserveMongo("UserRepo", "Create")()
+
 	now := time.Now()
 
 	u.ID = primitive.NewObjectID()
@@ -30,6 +37,8 @@ func (r *UserRepo) Create(ctx context.Context, u *model.User) error {
 }
 
 func (r *UserRepo) UpdateByUserID(ctx context.Context, userID string, upd map[string]interface{}) error {
+	defer metrics.ObserveMongo("UserRepo", "UpdateByUserID")()
+
 	upd["updatedAt"] = time.Now()
 	_, err := r.col.UpdateOne(ctx,
 		bson.M{"userId": userID},
@@ -39,11 +48,15 @@ func (r *UserRepo) UpdateByUserID(ctx context.Context, userID string, upd map[st
 }
 
 func (r *UserRepo) DeleteByUserID(ctx context.Context, userID string) error {
+	defer metrics.ObserveMongo("UserRepo", "DeleteByUserID")()
+
 	_, err := r.col.DeleteOne(ctx, bson.M{"userId": userID})
 	return err
 }
 
 func (r *UserRepo) List(ctx context.Context, page, limit int, q, sortField string, desc bool) ([]model.User, int64, error) {
+	defer metrics.ObserveMongo("UserRepo", "List")()
+
 	filter := bson.M{}
 	if q != "" {
 		regex := bson.M{"$regex": q, "$options": "i"}
@@ -79,6 +92,8 @@ func (r *UserRepo) List(ctx context.Context, page, limit int, q, sortField strin
 }
 
 func (r *UserRepo) IsAvailable(ctx context.Context, email, userID string) (bool, error) {
+	defer metrics.ObserveMongo("UserRepo", "IsAvailable")()
+
 	count, err := r.col.CountDocuments(ctx, bson.M{
 		"$or": []bson.M{{"email": email}, {"userId": userID}},
 	})
@@ -89,10 +104,40 @@ func (r *UserRepo) IsAvailable(ctx context.Context, email, userID string) (bool,
 }
 
 func (r *UserRepo) FindByUserID(ctx context.Context, userID string) (*model.User, error) {
+	defer metrics.ObserveMongo("UserRepo", "FindByUserID")()
+
 	var u model.User
 	err := r.col.FindOne(ctx, bson.M{"userId": userID}).Decode(&u)
 	if err == mongo.ErrNoDocuments {
-		return nil, errors.New("not found")
+		return nil, ErrNotFound
 	}
 	return &u, err
 }
+
+func (r *UserRepo) ListByOwner(ctx context.Context, ownerID string) ([]model.User, error) {
+	defer metrics.ObserveMongo("UserRepo", "ListByOwner")()
+
+	cur, err := r.col.Find(ctx, bson.M{"ownerId": ownerID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	users := []model.User{}
+	err = cur.All(ctx, &users)
+	return users, err
+}
+
+func (r *UserRepo) FindByUserIDs(ctx context.Context, userIDs []string) ([]model.User, error) {
+	defer metrics.ObserveMongo("UserRepo", "FindByUserIDs")()
+
+	cur, err := r.col.Find(ctx, bson.M{"userId": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	users := []model.User{}
+	err = cur.All(ctx, &users)
+	return users, err
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookRepo struct{ col *mongo.Collection }
+
+func NewWebhookRepo(db *mongo.Database) *WebhookRepo {
+	col := db.Collection("webhooks")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "ownerId", Value: 1}}},
+		{Keys: bson.D{{Key: "events", Value: 1}}},
+	})
+	return &WebhookRepo{col: col}
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, h *model.Webhook) error {
+	defer metrics.ObserveMongo("WebhookRepo", "Create")()
+
+	h.ID = primitive.NewObjectID()
+	h.CreatedAt = time.Now()
+	_, err := r.col.InsertOne(ctx, h)
+	return err
+}
+
+// FindByID devolve nil, sem erro, quando o webhook não existe.
+func (r *WebhookRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Webhook, error) {
+	defer metrics.ObserveMongo("WebhookRepo", "FindByID")()
+
+	var h model.Webhook
+	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&h)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return &h, err
+}
+
+// ListByOwner devolve os webhooks do usuário; ownerID vazio lista os globais.
+func (r *WebhookRepo) ListByOwner(ctx context.Context, ownerID string) ([]model.Webhook, error) {
+	defer metrics.ObserveMongo("WebhookRepo", "ListByOwner")()
+
+	cur, err := r.col.Find(ctx, ownerFilter(ownerID), options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	hooks := []model.Webhook{}
+	err = cur.All(ctx, &hooks)
+	return hooks, err
+}
+
+func (r *WebhookRepo) CountByOwner(ctx context.Context, ownerID string) (int64, error) {
+	defer metrics.ObserveMongo("WebhookRepo", "CountByOwner")()
+
+	return r.col.CountDocuments(ctx, ownerFilter(ownerID))
+}
+
+// Subscribed devolve os webhooks globais e os do usuário que assinam o
+// evento.
+func (r *WebhookRepo) Subscribed(ctx context.Context, event, userID string) ([]model.Webhook, error) {
+	defer metrics.ObserveMongo("WebhookRepo", "Subscribed")()
+
+	owners := bson.A{bson.M{"ownerId": bson.M{"$exists": false}}}
+	if userID != "" {
+		owners = append(owners, bson.M{"ownerId": userID})
+	}
+	cur, err := r.col.Find(ctx, bson.M{"events": event, "$or": owners})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var hooks []model.Webhook
+	err = cur.All(ctx, &hooks)
+	return hooks, err
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer metrics.ObserveMongo("WebhookRepo", "Delete")()
+
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// DeleteByOwner remove os webhooks do usuário e devolve os ids removidos.
+func (r *WebhookRepo) DeleteByOwner(ctx context.Context, ownerID string) ([]primitive.ObjectID, error) {
+	defer metrics.ObserveMongo("WebhookRepo", "DeleteByOwner")()
+
+	hooks, err := r.ListByOwner(ctx, ownerID)
+	if err != nil || len(hooks) == 0 {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(hooks))
+	for i, h := range hooks {
+		ids[i] = h.ID
+	}
+	_, err = r.col.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return ids, err
+}
+
+func ownerFilter(ownerID string) bson.M {
+	if ownerID == "" {
+		return bson.M{"ownerId": bson.M{"$exists": false}}
+	}
+	return bson.M{"ownerId": ownerID}
+}
+
+type WebhookDeliveryRepo struct{ col *mongo.Collection }
+
+// NewWebhookDeliveryRepo guarda o log de entregas por retention, contado da
+// criação.
+func NewWebhookDeliveryRepo(db *mongo.Database, retention time.Duration) *WebhookDeliveryRepo {
+	col := db.Collection("webhook_deliveries")
+	createIndexes(col, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+		{Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+	})
+	return &WebhookDeliveryRepo{col: col}
+}
+
+func (r *WebhookDeliveryRepo) Insert(ctx context.Context, d *model.WebhookDelivery) error {
+	defer metrics.ObserveMongo("WebhookDeliveryRepo", "Insert")()
+
+	_, err := r.col.InsertOne(ctx, d)
+	return err
+}
+
+func (r *WebhookDeliveryRepo) Claim(ctx context.Context, now time.Time, lease time.Duration) (*model.WebhookDelivery, error) {
+	defer metrics.ObserveMongo("WebhookDeliveryRepo", "Claim")()
+
+	filter := bson.M{
+		"status":        bson.M{"$in": bson.A{model.DeliveryPending, model.DeliveryRetrying}},
+		"nextAttemptAt": bson.M{"$lte": now},
+		"leaseUntil":    bson.M{"$lte": now},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var d model.WebhookDelivery
+	err := r.col.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"leaseUntil": now.Add(lease)}}, opts).Decode(&d)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return &d, err
+}
+
+// Finish grava o resultado de uma tentativa e libera o lease.
+func (r *WebhookDeliveryRepo) Finish(ctx context.Context, d *model.WebhookDelivery) error {
+	defer metrics.ObserveMongo("WebhookDeliveryRepo", "Finish")()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": d.ID}, bson.M{"$set": bson.M{
+		"status":         d.Status,
+		"attempts":       d.Attempts,
+		"nextAttemptAt":  d.NextAttemptAt,
+		"leaseUntil":     time.Time{},
+		"lastStatusCode": d.LastStatusCode,
+		"lastError":      d.LastError,
+		"log":            d.Log,
+		"deliveredAt":    d.DeliveredAt,
+	}})
+	return err
+}
+
+func (r *WebhookDeliveryRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*model.WebhookDelivery, error) {
+	defer metrics.ObserveMongo("WebhookDeliveryRepo", "FindByID")()
+
+	var d model.WebhookDelivery
+	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&d)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	return &d, err
+}
+
+// List devolve as entregas mais recentes primeiro. Sem webhookID, lista as
+// de todos os webhooks.
+func (r *WebhookDeliveryRepo) List(ctx context.Context, webhookID primitive.ObjectID, status string, page, limit int) ([]model.WebhookDelivery, int64, error) {
+	defer metrics.ObserveMongo("WebhookDeliveryRepo", "List")()
+
+	filter := bson.M{}
+	if !webhookID.IsZero() {
+		filter["webhookId"] = webhookID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	deliveries := []model.WebhookDelivery{}
+	if err := cur.All(ctx, &deliveries); err != nil {
+		return nil, 0, err
+	}
+	total, err := r.col.CountDocuments(ctx, filter)
+	return deliveries, total, err
+}
+
+// Requeue devolve uma entrega à fila com o orçamento de tentativas zerado.
+func (r *WebhookDeliveryRepo) Requeue(ctx context.Context, id primitive.ObjectID) error {
+	defer metrics.ObserveMongo("WebhookDeliveryRepo", "Requeue")()
+
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":        model.DeliveryPending,
+		"attempts":      0,
+		"nextAttemptAt": time.Now(),
+		"leaseUntil":    time.Time{},
+	}})
+	return err
+}
+
+func (r *WebhookDeliveryRepo) DeleteByWebhooks(ctx context.Context, ids []primitive.ObjectID) error {
+	defer metrics.ObserveMongo("WebhookDeliveryRepo", "DeleteByWebhooks")()
+
+	_, err := r.col.DeleteMany(ctx, bson.M{"webhookId": bson.M{"$in": ids}})
+	return err
+}
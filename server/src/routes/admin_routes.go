@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"wisp/src/handler"
+	"wisp/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(secure *gin.RouterGroup, h *handler.AdminHandler) {
+	admin := secure.Group("/admin")
+	admin.Use(middleware.AdminOnly())
+	{
+		admin.GET("/audit", h.ListAudit)
+		admin.GET("/audit/export", h.ExportAudit)
+
+		admin.POST("/users/:userId/suspend", h.SuspendUser)
+		admin.POST("/users/:userId/ban", h.BanUser)
+		admin.POST("/users/:userId/reinstate", h.ReinstateUser)
+		admin.POST("/users/:userId/logout", h.ForceLogout)
+
+		admin.GET("/reports", h.ListReports)
+		admin.GET("/reports/:id", h.GetReport)
+		admin.POST("/reports/:id/assign", h.AssignReport)
+		admin.POST("/reports/:id/status", h.SetReportStatus)
+		admin.POST("/reports/:id/notes", h.AddReportNote)
+		admin.POST("/reports/:id/action", h.ActOnReport)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"wisp/src/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func BotRoutes(secure *gin.RouterGroup, h *handler.BotHandler) {
+	bots := secure.Group("/bots")
+	{
+		bots.POST("", h.CreateBot)
+		bots.GET("", h.ListBots)
+		bots.DELETE("/:botId", h.DeleteBot)
+		bots.POST("/:botId/tokens", h.CreateToken)
+		bots.GET("/:botId/tokens", h.ListTokens)
+		bots.DELETE("/:botId/tokens/:tokenId", h.RevokeToken)
+	}
+
+	secure.POST("/bot/messages", h.SendMessage)
+}
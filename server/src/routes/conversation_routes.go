@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"wisp/src/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ConversationRoutes(secure *gin.RouterGroup, h *handler.ConversationHandler) {
+	conversations := secure.Group("/conversations")
+	{
+		conversations.GET("", h.ListConversations)
+		conversations.PUT("/pins", h.ReorderPins)
+		conversations.PUT("/:userId/read", h.MarkRead)
+		conversations.PUT("/:userId/mute", h.Mute)
+		conversations.PUT("/:userId/pin", h.Pin)
+		conversations.PUT("/:userId/archive", h.Archive)
+		conversations.GET("/:userId/settings", h.GetSettings)
+		conversations.PUT("/:userId/timer", h.SetTimer)
+	}
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"wisp/src/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRoutes(r *gin.RouterGroup, h *handler.HealthHandler) {
+	r.GET("/healthz", h.Healthz)
+	r.GET("/readyz", h.Readyz)
+}
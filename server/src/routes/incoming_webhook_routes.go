@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"wisp/src/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func IncomingWebhookRoutes(secure *gin.RouterGroup, public *gin.RouterGroup, h *handler.IncomingWebhookHandler) {
+	public.POST("/hooks/in/:secret", h.Post)
+
+	secure.POST("/incoming-webhooks", h.CreateIncomingWebhook)
+	secure.GET("/incoming-webhooks", h.ListIncomingWebhooks)
+	secure.DELETE("/incoming-webhooks/:id", h.DeleteIncomingWebhook)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"wisp/src/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PushRoutes(secure *gin.RouterGroup, h *handler.PushHandler) {
+	secure.POST("/push/tokens", h.RegisterToken)
+	secure.GET("/push/tokens", h.ListTokens)
+	secure.DELETE("/push/tokens/:deviceId", h.DeleteToken)
+}
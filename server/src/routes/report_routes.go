@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"wisp/src/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReportRoutes(secure *gin.RouterGroup, h *handler.ReportHandler) {
+	secure.POST("/reports", h.CreateReport)
+}
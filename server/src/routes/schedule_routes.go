@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"wisp/src/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ScheduleRoutes(secure *gin.RouterGroup, h *handler.ScheduleHandler) {
+	scheduled := secure.Group("/scheduled-messages")
+	{
+		scheduled.POST("", h.CreateScheduled)
+		scheduled.GET("", h.ListScheduled)
+		scheduled.PUT("/:id", h.UpdateScheduled)
+		scheduled.DELETE("/:id", h.CancelScheduled)
+	}
+}
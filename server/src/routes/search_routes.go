@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"wisp/src/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SearchRoutes(secure *gin.RouterGroup, h *handler.SearchHandler) {
+	secure.GET("/messages/search", h.Search)
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"wisp/src/handler"
+	"wisp/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WebhookRoutes(secure *gin.RouterGroup, user, admin *handler.WebhookHandler) {
+	secure.POST("/webhooks", user.CreateWebhook)
+	secure.GET("/webhooks", user.ListWebhooks)
+	secure.DELETE("/webhooks/:id", user.DeleteWebhook)
+	secure.GET("/webhooks/:id/deliveries", user.ListDeliveries)
+	secure.POST("/webhooks/deliveries/:deliveryId/redeliver", user.Redeliver)
+
+	global := secure.Group("/admin/webhooks")
+	global.Use(middleware.AdminOnly())
+	{
+		global.POST("", admin.CreateWebhook)
+		global.GET("", admin.ListWebhooks)
+		global.DELETE("/:id", admin.DeleteWebhook)
+		global.GET("/:id/deliveries", admin.ListDeliveries)
+		global.GET("/dead", admin.ListDeadLetters)
+		global.POST("/deliveries/:deliveryId/redeliver", admin.Redeliver)
+	}
+}
@@ -0,0 +1,184 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"wisp/src/model"
+	"wisp/src/repository"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/char/asciifolding"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// foldedAnalyzer ignora maiúsculas e acentos, como o índice de texto do
+// MongoDB.
+const foldedAnalyzer = "folded"
+
+// Bleve mantém um índice embutido em disco. Cada instância indexa só as
+// mensagens que passaram pelo próprio hub, então serve para implantações de
+// uma instância só.
+type Bleve struct {
+	index   bleve.Index
+	history *repository.HistoryRepo
+}
+
+// bleveDoc é o que vai para o índice; o conteúdo em si é lido do histórico.
+type bleveDoc struct {
+	Members        []string  `json:"members"`
+	From           string    `json:"from"`
+	Content        string    `json:"content"`
+	HasAttachments bool      `json:"hasAttachments"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func NewBleve(path string, history *repository.HistoryRepo) (*Bleve, error) {
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		var m mapping.IndexMapping
+		if m, err = newMapping(); err == nil {
+			index, err = bleve.New(path, m)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Bleve{index: index, history: history}, nil
+}
+
+func newMapping() (mapping.IndexMapping, error) {
+	m := bleve.NewIndexMapping()
+	err := m.AddCustomAnalyzer(foldedAnalyzer, map[string]any{
+		"type":          custom.Name,
+		"char_filters":  []string{asciifolding.Name},
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keyword := bleve.NewKeywordFieldMapping()
+	keyword.Store = false
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = foldedAnalyzer
+	text.Store = false
+	flag := bleve.NewBooleanFieldMapping()
+	flag.Store = false
+	date := bleve.NewDateTimeFieldMapping()
+	date.Store = false
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("members", keyword)
+	doc.AddFieldMappingsAt("from", keyword)
+	doc.AddFieldMappingsAt("content", text)
+	doc.AddFieldMappingsAt("hasAttachments", flag)
+	doc.AddFieldMappingsAt("createdAt", date)
+	m.DefaultMapping = doc
+	return m, nil
+}
+
+func (b *Bleve) Index(_ context.Context, msgs []*model.HistoryMessage) error {
+	batch := b.index.NewBatch()
+	for _, m := range msgs {
+		// Mensagens de sistema não têm texto para buscar.
+		if m.Event != nil {
+			continue
+		}
+		parts := []string{m.Content}
+		for _, a := range m.Attachments {
+			parts = append(parts, a.Title, a.Text)
+		}
+		err := batch.Index(m.ID.Hex(), bleveDoc{
+			Members:        m.Members,
+			From:           m.From,
+			Content:        strings.Join(parts, "\n"),
+			HasAttachments: m.HasAttachments,
+			CreatedAt:      m.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return b.index.Batch(batch)
+}
+
+func (b *Bleve) Delete(_ context.Context, id string) error {
+	return b.index.Delete(id)
+}
+
+func (b *Bleve) Search(ctx context.Context, q *model.SearchQuery) ([]model.HistoryMessage, error) {
+	ids, scores, err := b.hits(ctx, request(q))
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	// O histórico confere de novo o acesso e descarta o que já venceu mas
+	// ainda não saiu do índice.
+	msgs, err := b.history.FindByIDs(ctx, q.UserID, ids)
+	for i := range msgs {
+		msgs[i].Score = scores[msgs[i].ID]
+	}
+	return msgs, err
+}
+
+func request(q *model.SearchQuery) *bleve.SearchRequest {
+	text := bleve.NewMatchQuery(q.Text)
+	text.SetField("content")
+	text.SetOperator(query.MatchQueryOperatorAnd)
+	conjuncts := []query.Query{text, term("members", q.UserID)}
+	if q.Peer != "" {
+		conjuncts = append(conjuncts, term("members", q.Peer))
+	}
+	if q.FromMe {
+		conjuncts = append(conjuncts, term("from", q.UserID))
+	}
+	if q.HasAttachment {
+		has := bleve.NewBoolFieldQuery(true)
+		has.SetField("hasAttachments")
+		conjuncts = append(conjuncts, has)
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		created := bleve.NewDateRangeQuery(q.Since, q.Until)
+		created.SetField("createdAt")
+		conjuncts = append(conjuncts, created)
+	}
+
+	req := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(conjuncts...), q.Limit, q.Offset, false)
+	req.SortBy([]string{"-_score", "-createdAt"})
+	return req
+}
+
+func (b *Bleve) hits(ctx context.Context, req *bleve.SearchRequest) ([]primitive.ObjectID, map[primitive.ObjectID]float64, error) {
+	res, err := b.index.SearchInContext(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(res.Hits))
+	scores := make(map[primitive.ObjectID]float64, len(res.Hits))
+	for _, hit := range res.Hits {
+		id, err := primitive.ObjectIDFromHex(hit.ID)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+		scores[id] = hit.Score
+	}
+	return ids, scores, nil
+}
+
+func (b *Bleve) Close() error {
+	return b.index.Close()
+}
+
+func term(field, value string) *query.TermQuery {
+	q := bleve.NewTermQuery(value)
+	q.SetField(field)
+	return q
+}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"wisp/src/model"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Tamanho dos trechos em runas: contexto antes do primeiro termo e tamanho
+// máximo total.
+const (
+	snippetBefore = 30
+	snippetLength = 160
+	maxSnippets   = 3
+)
+
+// Highlight marca em msg as palavras que começam com algum termo da busca,
+// ignorando maiúsculas e acentos. Devolve no máximo um trecho por campo.
+func Highlight(msg *model.HistoryMessage, text string) []model.Snippet {
+	terms := queryTerms(text)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	snippets := make([]model.Snippet, 0, 1)
+	add := func(field, value string) {
+		if len(snippets) >= maxSnippets {
+			return
+		}
+		if s, ok := snippet(field, value, terms); ok {
+			snippets = append(snippets, s)
+		}
+	}
+	add("content", msg.Content)
+	for _, a := range msg.Attachments {
+		add("attachment.title", a.Title)
+		add("attachment.text", a.Text)
+	}
+	return snippets
+}
+
+// queryTerms separa a busca em palavras normalizadas, descartando a sintaxe
+// do $text (aspas e exclusões com "-").
+func queryTerms(text string) [][]rune {
+	var terms [][]rune
+	for _, f := range strings.FieldsFunc(text, func(r rune) bool { return !isWord(r) && r != '-' }) {
+		if strings.HasPrefix(f, "-") {
+			continue
+		}
+		if t := fold([]rune(strings.Trim(f, "-"))); len(t) > 0 {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+func snippet(field, value string, terms [][]rune) (model.Snippet, bool) {
+	runes := []rune(value)
+	folded := fold(runes)
+
+	var matches [][2]int
+	for i := 0; i < len(folded); i++ {
+		if !isWord(folded[i]) || (i > 0 && isWord(folded[i-1])) {
+			continue
+		}
+		for _, t := range terms {
+			if hasPrefix(folded[i:], t) {
+				matches = append(matches, [2]int{i, i + len(t)})
+				i += len(t) - 1
+				break
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return model.Snippet{}, false
+	}
+
+	start := max(0, matches[0][0]-snippetBefore)
+	end := min(len(runes), start+snippetLength)
+	s := model.Snippet{Field: field, Text: string(runes[start:end])}
+	for _, m := range matches {
+		if m[1] > end {
+			break
+		}
+		s.Matches = append(s.Matches, [2]int{m[0] - start, m[1] - start})
+	}
+	return s, true
+}
+
+// fold troca cada runa pela minúscula sem acento, mantendo as posições.
+func fold(runes []rune) []rune {
+	out := make([]rune, len(runes))
+	for i, r := range runes {
+		r = unicode.ToLower(r)
+		if d := norm.NFD.PropertiesString(string(r)).Decomposition(); len(d) > 0 {
+			r, _ = utf8.DecodeRune(d)
+		}
+		out[i] = r
+	}
+	return out
+}
+
+func hasPrefix(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,27 @@
+package search
+
+import (
+	"context"
+	"wisp/src/model"
+	"wisp/src/repository"
+)
+
+// Mongo usa o índice de texto da própria coleção de histórico, que já é
+// mantido pelo banco; Index e Delete não têm o que fazer.
+type Mongo struct {
+	history *repository.HistoryRepo
+}
+
+func NewMongo(history *repository.HistoryRepo) *Mongo {
+	return &Mongo{history: history}
+}
+
+func (m *Mongo) Index(context.Context, []*model.HistoryMessage) error { return nil }
+
+func (m *Mongo) Delete(context.Context, string) error { return nil }
+
+func (m *Mongo) Search(ctx context.Context, q *model.SearchQuery) ([]model.HistoryMessage, error) {
+	return m.history.Search(ctx, q)
+}
+
+func (m *Mongo) Close() error { return nil }
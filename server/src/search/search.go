@@ -0,0 +1,24 @@
+// Package search implementa a busca de texto no histórico de mensagens.
+package search
+
+import (
+	"context"
+	"wisp/src/model"
+)
+
+// Índices aceitos em search.index.
+const (
+	IndexMongo = "mongo"
+	IndexBleve = "bleve"
+)
+
+// SearchIndex busca no histórico de um usuário. As mensagens ficam no
+// repository.HistoryRepo; o índice só decide quais casam e em que ordem, e
+// nunca devolve mensagens de conversas de que q.UserID não participa.
+type SearchIndex interface {
+	// Index recebe os lotes recém-gravados no histórico.
+	Index(ctx context.Context, msgs []*model.HistoryMessage) error
+	Delete(ctx context.Context, id string) error
+	Search(ctx context.Context, q *model.SearchQuery) ([]model.HistoryMessage, error)
+	Close() error
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"wisp/src/bus"
+	"wisp/src/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+// App junta o roteador HTTP com o que roda em segundo plano, para que o main
+// consiga encerrar tudo na ordem certa.
+type App struct {
+	Router     *gin.Engine
+	Hub        *ws.Hub
+	bus        bus.Bus
+	metricsSrv *http.Server
+	cancel     context.CancelFunc
+
+	closeLimiter func() error
+	closeSearch  func() error
+}
+
+// Shutdown drena o hub, para as tarefas em segundo plano e fecha o bus, o
+// store do rate limit e o índice de busca. O cliente do MongoDB continua aberto; quem o criou o
+// desconecta depois.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.Hub.Drain(ctx)
+	a.cancel()
+	if a.metricsSrv != nil {
+		a.metricsSrv.Shutdown(ctx)
+	}
+	a.closeLimiter()
+	a.closeSearch()
+	return a.bus.Close()
+}
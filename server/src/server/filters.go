@@ -0,0 +1,46 @@
+package server
+
+import (
+	"time"
+	"wisp/config"
+	"wisp/src/filter"
+)
+
+// newFilterChain monta os filtros anti-abuso habilitados na configuração.
+// Um filtro com limit 0 fica de fora.
+func newFilterChain(cfg *config.Config, dir filter.Directory, flagger filter.Flagger, suspender filter.Suspender) (*filter.Chain, error) {
+	fc := cfg.Filters
+	dir = filter.NewCachedDirectory(dir, time.Minute)
+
+	rules := []struct {
+		limit  int
+		action string
+		build  func(filter.Action) filter.MessageFilter
+	}{
+		{fc.SenderRate.Limit, fc.SenderRate.Action, func(a filter.Action) filter.MessageFilter {
+			return filter.NewSenderRate(fc.SenderRate.Limit, fc.SenderRate.Window, a)
+		}},
+		{fc.NewRecipients.Limit, fc.NewRecipients.Action, func(a filter.Action) filter.MessageFilter {
+			return filter.NewNewRecipients(dir, fc.NewRecipients.Limit, fc.NewRecipients.Window, a)
+		}},
+		{fc.FirstContact.Limit, fc.FirstContact.Action, func(a filter.Action) filter.MessageFilter {
+			return filter.NewFirstContact(dir, fc.FirstContact.AccountAge, fc.FirstContact.Limit, fc.FirstContact.Window, a)
+		}},
+		{fc.DuplicateContent.Limit, fc.DuplicateContent.Action, func(a filter.Action) filter.MessageFilter {
+			return filter.NewDuplicateContent(fc.DuplicateContent.MinLength, fc.DuplicateContent.Limit, fc.DuplicateContent.Window, a)
+		}},
+	}
+
+	var filters []filter.MessageFilter
+	for _, r := range rules {
+		if r.limit <= 0 {
+			continue
+		}
+		action, err := filter.ParseAction(r.action)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, r.build(action))
+	}
+	return filter.NewChain(flagger, suspender, fc.SuspendDuration, filters...), nil
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"fmt"
+	"wisp/config"
+	"wisp/src/push"
+	"wisp/src/service"
+	"wisp/src/webhook"
+)
+
+// newPushers monta um Pusher para cada provedor configurado.
+func newPushers(cfg *config.Config) (map[string]push.Pusher, error) {
+	if cfg.Push.Mode != service.PushPreview && cfg.Push.Mode != service.PushHint {
+		return nil, fmt.Errorf("push.mode desconhecido: %s", cfg.Push.Mode)
+	}
+
+	pushers := make(map[string]push.Pusher)
+	if cfg.Push.FCM.CredentialsFile != "" {
+		fcm, err := push.NewFCM(cfg.Push.FCM.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("push.fcm: %w", err)
+		}
+		pushers[push.ProviderFCM] = fcm
+	}
+	if a := cfg.Push.APNs; a.KeyFile != "" {
+		apns, err := push.NewAPNs(a.KeyFile, a.KeyID, a.TeamID, a.Topic, a.Sandbox)
+		if err != nil {
+			return nil, fmt.Errorf("push.apns: %w", err)
+		}
+		pushers[push.ProviderAPNs] = apns
+	}
+	if cfg.Push.UnifiedPush.Enabled {
+		pushers[push.ProviderUnifiedPush] = push.NewUnifiedPush(webhook.NewClient(cfg.Push.UnifiedPush.Timeout, false))
+	}
+	if cfg.Push.Fake && cfg.App.Env != "production" {
+		pushers[push.ProviderFake] = push.NewFake()
+	}
+	return pushers, nil
+}
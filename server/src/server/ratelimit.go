@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"wisp/config"
+	"wisp/src/ratelimit"
+)
+
+// newLimiter monta o limitador a partir da configuração. O segundo retorno
+// fecha a conexão com o Redis, quando houver.
+func newLimiter(cfg *config.Config) (*ratelimit.Limiter, func() error, error) {
+	var store ratelimit.Store
+	closeStore := func() error { return nil }
+
+	switch cfg.RateLimit.Store {
+	case "", "memory":
+		store = ratelimit.NewMemoryStore()
+	case "redis":
+		rs, err := ratelimit.NewRedisStore(cfg.Cluster.RedisAddr, cfg.Cluster.RedisPassword)
+		if err != nil {
+			return nil, nil, err
+		}
+		store, closeStore = rs, rs.Close
+	default:
+		return nil, nil, fmt.Errorf("rateLimit.store desconhecido: %q", cfg.RateLimit.Store)
+	}
+
+	policies := make([]ratelimit.Policy, 0, len(cfg.RateLimit.Policies))
+	for _, p := range cfg.RateLimit.Policies {
+		if p.Key != ratelimit.KeyIP && p.Key != ratelimit.KeyUser {
+			return nil, nil, fmt.Errorf("política %q: key deve ser ip ou user", p.Name)
+		}
+		if p.Limit <= 0 || p.Period <= 0 {
+			return nil, nil, fmt.Errorf("política %q: limit e period devem ser positivos", p.Name)
+		}
+		policies = append(policies, ratelimit.Policy{
+			Name:   p.Name,
+			Key:    p.Key,
+			Routes: p.Routes,
+			Rate:   ratelimit.Rate{Limit: p.Limit, Period: p.Period, Burst: p.Burst},
+		})
+	}
+	return ratelimit.NewLimiter(store, policies), closeStore, nil
+}
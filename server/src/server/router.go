@@ -1,39 +1,61 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"time"
 	"wisp/config"
+	"wisp/src/bus"
 	"wisp/src/handler"
+	"wisp/src/metrics"
 	"wisp/src/middleware"
+	"wisp/src/ratelimit"
 	"wisp/src/repository"
 	"wisp/src/routes"
 	"wisp/src/service"
+	"wisp/src/webhook"
 	"wisp/src/ws"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func NewRouter(cfg *config.Config, logger zerolog.Logger, mongoClient *mongo.Client) *gin.Engine {
+func New(cfg *config.Config, logger zerolog.Logger, mongoClient *mongo.Client) (*App, error) {
 	if cfg.App.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	r := gin.New()
-	r.Use(gin.Logger(), gin.Recovery())
+	// Sem proxies configurados o gin ignora o X-Forwarded-For, que qualquer
+	// cliente pode forjar para escapar do rate limit por IP
+	if err := r.SetTrustedProxies(cfg.App.TrustedProxies); err != nil {
+		return nil, err
+	}
+	r.Use(otelgin.Middleware("wisp"), middleware.RequestID(), middleware.AccessLog(), middleware.Recovery(), metrics.Middleware())
 
 	corsCfg := cors.Config{
 		AllowOrigins:     cfg.CORS.AllowOrigins,
 		AllowMethods:     cfg.CORS.AllowMethods,
 		AllowHeaders:     cfg.CORS.AllowHeaders,
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 	r.Use(cors.New(corsCfg))
 
+	// Rate limit por IP em todas as rotas; o por usuário entra no grupo seguro
+	limiter, closeLimiter, err := newLimiter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.Enabled {
+		r.Use(middleware.RateLimit(limiter, ratelimit.KeyIP))
+	}
+
 	db := mongoClient.Database(cfg.Mongo.DBName)
 
 	// Repositórios
@@ -41,33 +63,199 @@ func NewRouter(cfg *config.Config, logger zerolog.Logger, mongoClient *mongo.Cli
 	contactRepo := repository.NewContactRepo(db)
 	frRepo := repository.NewFriendRequestRepo(db)
 	msgRepo := repository.NewMessageRepo(db)
+	receiptRepo := repository.NewReceiptRepo(db, cfg.Hub.DedupWindow)
+	auditRepo := repository.NewAuditRepo(db)
+	reportRepo := repository.NewReportRepo(db)
+	pushTokenRepo := repository.NewPushTokenRepo(db)
+	webhookRepo := repository.NewWebhookRepo(db)
+	deliveryRepo := repository.NewWebhookDeliveryRepo(db, cfg.Webhooks.Retention)
+	apiTokenRepo := repository.NewAPITokenRepo(db)
+	incomingRepo := repository.NewIncomingWebhookRepo(db)
+	scheduledRepo := repository.NewScheduledMessageRepo(db)
+	conversationRepo := repository.NewConversationRepo(db)
+	expiryRepo := repository.NewExpiryRepo(db)
+	historyRepo := repository.NewHistoryRepo(db)
+	inboxRepo := repository.NewInboxRepo(db)
 
 	// Serviços
 	userSvc := service.NewUserService(userRepo)
 	authSvc := service.NewAuthService(db, cfg)
 	contactSvc := service.NewContactService(userRepo, contactRepo, frRepo, db)
+	auditSvc := service.NewAuditService(auditRepo)
+	webhookSvc := service.NewWebhookService(webhookRepo, deliveryRepo, cfg.Webhooks.Enabled, cfg.Webhooks.AllowHTTP, cfg.Webhooks.MaxPerUser)
 
 	// Handlers
-	authHandler := handler.NewAuthHandler(authSvc, userSvc)
-	userHandler := handler.NewUserHandler(userSvc)
-	contactHandler := handler.NewContactHandler(contactSvc)
+	authHandler := handler.NewAuthHandler(authSvc, userSvc, auditSvc, webhookSvc)
+	contactHandler := handler.NewContactHandler(contactSvc, auditSvc, webhookSvc)
+
+	// Bus entre instâncias
+	msgBus, err := bus.New(cfg)
+	if err != nil {
+		closeLimiter()
+		return nil, err
+	}
+	// Redis e NATS guardam a presença junto com o bus; no modo changestream
+	// ela fica no MongoDB, que faz as vezes de bus.
+	var presence bus.Presence = bus.NewMemoryPresence()
+	if p, ok := msgBus.(bus.Presence); ok {
+		presence = p
+	} else if cfg.Cluster.Bus == "changestream" {
+		presence = repository.NewPresenceRepo(db)
+	}
+
+	// Tarefas em segundo plano, canceladas no Shutdown
+	bgCtx, cancel := context.WithCancel(context.Background())
 
 	// WebSocket Hub
-	hub := ws.NewHub(msgRepo)
+	hub := ws.NewHub(cfg, msgRepo, receiptRepo, msgBus, presence)
+
+	// Moderação, que precisa do hub para derrubar conexões
+	moderationSvc := service.NewModerationService(userRepo, authSvc, hub)
+	reportSvc := service.NewReportService(reportRepo, userRepo, historyRepo, moderationSvc, cfg.Reports.MaxPerHour)
+
+	// Bots: a revogação de tokens também derruba conexões, e as mensagens
+	// para bots viram eventos de webhook
+	botSvc := service.NewBotService(userRepo, apiTokenRepo, incomingRepo, webhookSvc, hub, cfg.Bots.MaxPerUser)
+	hub.SetBotInbox(botSvc)
+	incomingSvc := service.NewIncomingWebhookService(incomingRepo, userRepo, contactRepo, hub, cfg.Bots.MaxIncomingPerUser)
+
+	// Filtros anti-abuso, avaliados pelo hub antes da entrega
+	if cfg.Filters.Enabled {
+		chain, err := newFilterChain(cfg, service.NewDirectory(userRepo, contactRepo), reportSvc, moderationSvc)
+		if err != nil {
+			cancel()
+			closeLimiter()
+			return nil, err
+		}
+		hub.SetFilter(chain)
+	}
+
+	// Push para dispositivos offline
+	pushers, err := newPushers(cfg)
+	if err != nil {
+		cancel()
+		closeLimiter()
+		return nil, err
+	}
+	pushSvc := service.NewPushService(pushTokenRepo, userRepo, inboxRepo, pushers, cfg.Push.Mode, cfg.Push.Workers)
+	if cfg.Push.Enabled {
+		pushSvc.Start(bgCtx)
+		hub.SetNotifier(pushSvc)
+	}
+
+	// Histórico, busca e lista de conversas
+	searchIndex, err := newSearchIndex(cfg, historyRepo)
+	if err != nil {
+		cancel()
+		closeLimiter()
+		return nil, err
+	}
+	inboxSvc := service.NewInboxService(inboxRepo, userRepo, hub)
+	historySvc := service.NewHistoryService(historyRepo, searchIndex, inboxSvc)
+	hub.SetHistory(historySvc)
+	historySvc.Start(bgCtx)
+
+	// Mensagens temporárias, que também somem do histórico
+	expirySvc := service.NewExpiryService(conversationRepo, expiryRepo, msgRepo, userRepo, contactRepo, historySvc, hub)
+	hub.SetExpirer(expirySvc)
+	expirySvc.Start(bgCtx)
+
+	// Mensagens agendadas
+	scheduleSvc := service.NewScheduleService(scheduledRepo, userRepo, contactRepo, hub, cfg.Schedule.MaxPending)
+	if cfg.Schedule.Enabled {
+		scheduleSvc.Start(bgCtx)
+	}
+
+	// Entrega de webhooks
+	if cfg.Webhooks.Enabled {
+		client := webhook.NewClient(cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivate)
+		webhook.NewWorker(webhookRepo, deliveryRepo, client, cfg.Webhooks.MaxAttempts, cfg.Webhooks.BaseDelay, cfg.Webhooks.MaxDelay).
+			Run(bgCtx, cfg.Webhooks.Workers)
+	}
+
 	go hub.Run() // Inicia o hub em uma goroutine separada
 
+	// Métricas
+	hub.RegisterMetrics()
+	metrics.GaugeFunc("wisp_pending_messages", "Mensagens na fila pendente.", func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		n, _ := msgRepo.CountPending(ctx)
+		return float64(n)
+	})
+	metricsSrv := serveMetrics(cfg, r, logger)
+
+	if cfg.Cluster.Bus == "changestream" {
+		if cfg.Cluster.NodeID == "" {
+			logger.Warn().Msg("cluster.nodeId vazio: o token do change stream não será reaproveitado após reiniciar")
+		}
+		go hub.WatchPending(bgCtx, msgRepo, repository.NewStreamTokenRepo(db))
+	}
+
+	adminHandler := handler.NewAdminHandler(auditSvc, moderationSvc, reportSvc)
+	reportHandler := handler.NewReportHandler(reportSvc, auditSvc)
+	pushHandler := handler.NewPushHandler(pushSvc)
+	webhookHandler := handler.NewWebhookHandler(webhookSvc, auditSvc)
+	botHandler := handler.NewBotHandler(botSvc, hub, auditSvc)
+	incomingHandler := handler.NewIncomingWebhookHandler(incomingSvc, auditSvc)
+	scheduleHandler := handler.NewScheduleHandler(scheduleSvc)
+	conversationHandler := handler.NewConversationHandler(expirySvc, inboxSvc)
+	searchHandler := handler.NewSearchHandler(historySvc)
+	userHandler := handler.NewUserHandler(userSvc, auditSvc, webhookSvc, botSvc)
+	adminWebhookHandler := handler.NewAdminWebhookHandler(webhookSvc, auditSvc)
+
 	// WebSocket Handler
 	wsHandler := handler.NewWSHandler(hub)
+	healthHandler := handler.NewHealthHandler(hub, mongoClient, cfg.Health.Timeout)
 
 	public := r.Group("/")
 	secure := r.Group("/")
-	secure.Use(middleware.JWTAuth(authSvc))
+	secure.Use(middleware.JWTAuth(authSvc, botSvc), middleware.BotScope("GET /ws", "POST /bot/messages"))
+	if cfg.RateLimit.Enabled {
+		secure.Use(middleware.RateLimit(limiter, ratelimit.KeyUser))
+	}
 
 	// Configuração das rotas
 	routes.UserRoutes(secure, public, userHandler)
 	routes.AuthRoutes(secure, public, authHandler)
 	routes.ContactRoutes(secure, contactHandler)
 	routes.WSRoutes(secure, wsHandler)
+	routes.ReportRoutes(secure, reportHandler)
+	routes.PushRoutes(secure, pushHandler)
+	routes.WebhookRoutes(secure, webhookHandler, adminWebhookHandler)
+	routes.BotRoutes(secure, botHandler)
+	routes.IncomingWebhookRoutes(secure, public, incomingHandler)
+	routes.ScheduleRoutes(secure, scheduleHandler)
+	routes.ConversationRoutes(secure, conversationHandler)
+	routes.SearchRoutes(secure, searchHandler)
+	routes.AdminRoutes(secure, adminHandler)
+	routes.HealthRoutes(r.Group(cfg.Health.Prefix), healthHandler)
+
+	return &App{Router: r, Hub: hub, bus: msgBus, metricsSrv: metricsSrv, cancel: cancel, closeLimiter: closeLimiter, closeSearch: historySvc.Close}, nil
+}
+
+// serveMetrics sobe um listener só para as métricas quando metrics.addr está
+// configurado; senão monta a rota no roteador principal, protegida por token.
+func serveMetrics(cfg *config.Config, r *gin.Engine, logger zerolog.Logger) *http.Server {
+	handler := metrics.Handler(cfg.Metrics.Token)
+
+	if cfg.Metrics.Addr == "" {
+		if cfg.Metrics.Token == "" {
+			logger.Warn().Msg("Métricas desativadas: configure metrics.addr ou metrics.token")
+			return nil
+		}
+		r.GET(cfg.Metrics.Path, gin.WrapH(handler))
+		return nil
+	}
 
-	return r
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Metrics.Path, handler)
+	srv := &http.Server{Addr: cfg.Metrics.Addr, Handler: mux}
+	go func() {
+		logger.Info().Str("addr", cfg.Metrics.Addr).Msg("Métricas disponíveis")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error().Err(err).Msg("Servidor de métricas falhou")
+		}
+	}()
+	return srv
 }
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"fmt"
+	"wisp/config"
+	"wisp/src/repository"
+	"wisp/src/search"
+)
+
+// newSearchIndex monta o índice configurado em search.index.
+func newSearchIndex(cfg *config.Config, history *repository.HistoryRepo) (search.SearchIndex, error) {
+	switch cfg.Search.Index {
+	case "", search.IndexMongo:
+		return search.NewMongo(history), nil
+	case search.IndexBleve:
+		// O índice embutido só vê as mensagens aceitas pela própria
+		// instância.
+		if cfg.Cluster.Bus != "" && cfg.Cluster.Bus != "local" {
+			return nil, fmt.Errorf("search.index bleve exige cluster.bus local")
+		}
+		idx, err := search.NewBleve(cfg.Search.BlevePath, history)
+		if err != nil {
+			return nil, fmt.Errorf("search.bleve: %w", err)
+		}
+		return idx, nil
+	default:
+		return nil, fmt.Errorf("search.index desconhecido: %s", cfg.Search.Index)
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog"
+)
+
+type AuditService struct {
+	repo *repository.AuditRepo
+}
+
+func NewAuditService(r *repository.AuditRepo) *AuditService {
+	return &AuditService{repo: r}
+}
+
+// Record grava o evento. Uma falha aqui não desfaz a ação auditada; ela vai
+// para o log com os campos de correlação da requisição.
+func (s *AuditService) Record(ctx context.Context, ev *model.AuditEvent) {
+	ctx, span := tracing.Start(ctx, "AuditService.Record", tracing.Attrs("wisp.audit_action", ev.Action))
+	defer span.End()
+
+	ev.CreatedAt = time.Now()
+	if err := s.repo.Insert(ctx, ev); err != nil {
+		tracing.Fail(span, err)
+		zerolog.Ctx(ctx).Error().Err(err).Str("action", ev.Action).Str("targetId", ev.TargetID).Msg("Erro ao gravar evento de auditoria")
+	}
+}
+
+func (s *AuditService) Query(ctx context.Context, f model.AuditFilter, page, limit int) ([]model.AuditEvent, int64, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Query")
+	defer span.End()
+
+	return s.repo.Find(ctx, f, page, limit)
+}
+
+func (s *AuditService) Export(ctx context.Context, f model.AuditFilter, fn func(*model.AuditEvent) error) error {
+	ctx, span := tracing.Start(ctx, "AuditService.Export")
+	defer span.End()
+
+	return s.repo.Each(ctx, f, fn)
+}
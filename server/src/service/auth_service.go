@@ -7,11 +7,13 @@ import (
 
 	"wisp/config"
 	"wisp/src/model"
+	"wisp/src/tracing"
 
 	"github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -38,6 +40,9 @@ func NewAuthService(db *mongo.Database, cfg *config.Config) *AuthService {
 }
 
 func (a *AuthService) Login(ctx context.Context, email, pass, deviceID string) (string, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
 	var u model.User
 	if err := a.usersCol.FindOne(ctx, bson.M{"email": email}).Decode(&u); err != nil {
 		return "", errors.New("credenciais inválidas")
@@ -46,6 +51,9 @@ func (a *AuthService) Login(ctx context.Context, email, pass, deviceID string) (
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(pass)); err != nil {
 		return "", errors.New("credenciais inválidas")
 	}
+	if u.Blocked(time.Now()) {
+		return "", blockedError(&u)
+	}
 
 	a.sessionsCol.DeleteMany(ctx, bson.M{"userId": u.ID.Hex()})
 
@@ -75,11 +83,17 @@ func (a *AuthService) Login(ctx context.Context, email, pass, deviceID string) (
 }
 
 func (a *AuthService) Logout(ctx context.Context, sid string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
 	_, err := a.sessionsCol.DeleteOne(ctx, bson.M{"sid": sid})
 	return err
 }
 
-func (a *AuthService) ValidateToken(tokenStr string) (*Claims, error) {
+func (a *AuthService) ValidateToken(ctx context.Context, tokenStr string) (*Claims, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ValidateToken")
+	defer span.End()
+
 	tok, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (any, error) {
 		return []byte(a.jwtSecret), nil
 	})
@@ -90,9 +104,50 @@ func (a *AuthService) ValidateToken(tokenStr string) (*Claims, error) {
 
 	claims := tok.Claims.(*Claims)
 
-	count, err := a.sessionsCol.CountDocuments(context.Background(), bson.M{"sid": claims.ID})
+	count, err := a.sessionsCol.CountDocuments(ctx, bson.M{"sid": claims.ID})
 	if err != nil || count == 0 {
 		return nil, errors.New("sessão expirada")
 	}
+
+	var u model.User
+	opts := options.FindOne().SetProjection(bson.M{"status": 1, "statusReason": 1, "suspendedUntil": 1})
+	if err := a.usersCol.FindOne(ctx, bson.M{"userId": claims.UserID}, opts).Decode(&u); err != nil {
+		return nil, errors.New("usuário não encontrado")
+	}
+	if u.Blocked(time.Now()) {
+		return nil, blockedError(&u)
+	}
 	return claims, nil
 }
+
+// RevokeSessions apaga todas as sessões do usuário; os tokens emitidos param
+// de valer na próxima validação.
+func (a *AuthService) RevokeSessions(ctx context.Context, u *model.User) error {
+	ctx, span := tracing.Start(ctx, "AuthService.RevokeSessions")
+	defer span.End()
+
+	_, err := a.sessionsCol.DeleteMany(ctx, bson.M{"userId": u.ID.Hex()})
+	return err
+}
+
+// AccountBlockedError é devolvido no login e na validação do token de uma
+// conta suspensa ou banida.
+type AccountBlockedError struct {
+	Status string
+	Reason string
+	Until  *time.Time
+}
+
+func (e *AccountBlockedError) Error() string {
+	if e.Status == model.UserBanned {
+		return "conta banida"
+	}
+	if e.Until != nil {
+		return "conta suspensa até " + e.Until.UTC().Format(time.RFC3339)
+	}
+	return "conta suspensa"
+}
+
+func blockedError(u *model.User) error {
+	return &AccountBlockedError{Status: u.Status, Reason: u.StatusReason, Until: u.SuspendedUntil}
+}
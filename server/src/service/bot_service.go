@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BotTokenPrefix distingue um token de API de um JWT no cabeçalho
+// Authorization.
+const BotTokenPrefix = "wbt_"
+
+var (
+	ErrBotNotFound = errors.New("bot não encontrado")
+	ErrBotLimit    = errors.New("limite de bots atingido")
+)
+
+// cacheLimit limita os caches em memória dos serviços; ao encher, o cache é
+// descartado inteiro.
+const cacheLimit = 100000
+
+// BotService cuida das contas de bot: cada uma pertence a um usuário,
+// não tem senha e se autentica por tokens de API revogáveis.
+type BotService struct {
+	users      *repository.UserRepo
+	tokens     *repository.APITokenRepo
+	incoming   *repository.IncomingWebhookRepo
+	hooks      *WebhookService
+	hub        Disconnector
+	maxPerUser int
+
+	mu     sync.Mutex
+	owners map[string]string // userId -> dono; "" para humanos. Não muda.
+}
+
+func NewBotService(users *repository.UserRepo, tokens *repository.APITokenRepo, incoming *repository.IncomingWebhookRepo, hooks *WebhookService, hub Disconnector, maxPerUser int) *BotService {
+	return &BotService{
+		users:      users,
+		tokens:     tokens,
+		incoming:   incoming,
+		hooks:      hooks,
+		hub:        hub,
+		maxPerUser: maxPerUser,
+		owners:     make(map[string]string),
+	}
+}
+
+func (s *BotService) Create(ctx context.Context, ownerID, userID, name string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "BotService.Create")
+	defer span.End()
+
+	owner, err := s.users.FindByUserID(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner.IsBot {
+		return nil, errors.New("bots não podem criar bots")
+	}
+	bots, err := s.users.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bots) >= s.maxPerUser {
+		return nil, ErrBotLimit
+	}
+	if _, err := s.users.FindByUserID(ctx, userID); err == nil {
+		return nil, errors.New("userId já cadastrado")
+	}
+
+	bot := &model.User{
+		UserID:  userID,
+		Name:    name,
+		IsBot:   true,
+		OwnerID: ownerID,
+	}
+	if err := s.users.Create(ctx, bot); err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
+
+func (s *BotService) List(ctx context.Context, ownerID string) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "BotService.List")
+	defer span.End()
+
+	return s.users.ListByOwner(ctx, ownerID)
+}
+
+// Delete remove o bot, os tokens, os webhooks de saída e de entrada dele e
+// derruba as conexões abertas.
+func (s *BotService) Delete(ctx context.Context, ownerID, botID string) error {
+	ctx, span := tracing.Start(ctx, "BotService.Delete")
+	defer span.End()
+
+	if _, err := s.owned(ctx, ownerID, botID); err != nil {
+		return err
+	}
+	if err := s.tokens.DeleteByBot(ctx, botID); err != nil {
+		return err
+	}
+	if err := s.users.DeleteByUserID(ctx, botID); err != nil {
+		return err
+	}
+	if err := s.hooks.DeleteOwner(ctx, botID); err != nil {
+		return err
+	}
+	if err := s.incoming.DeleteByBot(ctx, botID); err != nil {
+		return err
+	}
+	s.forget(botID)
+	s.hub.DisconnectUser(botID, &model.AccountNotice{Type: "account_status", Status: noticeLoggedOut, Reason: "bot removido"})
+	return nil
+}
+
+// DeleteOwned remove os bots de um usuário que foi apagado.
+func (s *BotService) DeleteOwned(ctx context.Context, ownerID string) error {
+	ctx, span := tracing.Start(ctx, "BotService.DeleteOwned")
+	defer span.End()
+
+	bots, err := s.users.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, bot := range bots {
+		errs = append(errs, s.Delete(ctx, ownerID, bot.UserID))
+	}
+	return errors.Join(errs...)
+}
+
+// CreateToken devolve o token e o valor dele, que não pode ser recuperado
+// depois.
+func (s *BotService) CreateToken(ctx context.Context, ownerID, botID, name string) (*model.APIToken, string, error) {
+	ctx, span := tracing.Start(ctx, "BotService.CreateToken")
+	defer span.End()
+
+	if _, err := s.owned(ctx, ownerID, botID); err != nil {
+		return nil, "", err
+	}
+
+	raw := newToken(BotTokenPrefix)
+	t := &model.APIToken{
+		BotID:  botID,
+		Name:   name,
+		Prefix: raw[:len(BotTokenPrefix)+8],
+		Hash:   hashToken(raw),
+	}
+	if err := s.tokens.Create(ctx, t); err != nil {
+		return nil, "", err
+	}
+	return t, raw, nil
+}
+
+func (s *BotService) ListTokens(ctx context.Context, ownerID, botID string) ([]model.APIToken, error) {
+	ctx, span := tracing.Start(ctx, "BotService.ListTokens")
+	defer span.End()
+
+	if _, err := s.owned(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+	return s.tokens.ListByBot(ctx, botID)
+}
+
+// RevokeToken apaga o token e derruba as conexões do bot, já que o hub não
+// sabe com qual token cada uma foi aberta; as que usam outro token voltam
+// na reconexão.
+func (s *BotService) RevokeToken(ctx context.Context, ownerID, botID, tokenID string) error {
+	ctx, span := tracing.Start(ctx, "BotService.RevokeToken")
+	defer span.End()
+
+	if _, err := s.owned(ctx, ownerID, botID); err != nil {
+		return err
+	}
+	oid, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return ErrBotNotFound
+	}
+	if err := s.tokens.Delete(ctx, botID, oid); err != nil {
+		return ErrBotNotFound
+	}
+	s.hub.DisconnectUser(botID, &model.AccountNotice{Type: "account_status", Status: noticeLoggedOut, Reason: "token revogado"})
+	return nil
+}
+
+// Authenticate troca um token de API pelo bot dono dele. Um bot suspenso ou
+// banido recebe o mesmo AccountBlockedError do login.
+func (s *BotService) Authenticate(ctx context.Context, raw string) (*model.User, *model.APIToken, error) {
+	ctx, span := tracing.Start(ctx, "BotService.Authenticate")
+	defer span.End()
+
+	if !strings.HasPrefix(raw, BotTokenPrefix) {
+		return nil, nil, errors.New("token inválido")
+	}
+	t, err := s.tokens.FindByHash(ctx, hashToken(raw))
+	if err != nil {
+		return nil, nil, errors.New("token inválido")
+	}
+	bot, err := s.users.FindByUserID(ctx, t.BotID)
+	if err != nil || !bot.IsBot {
+		return nil, nil, errors.New("token inválido")
+	}
+	if bot.Blocked(time.Now()) {
+		return nil, nil, blockedError(bot)
+	}
+	if err := s.tokens.Touch(ctx, t.ID, time.Now()); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("Erro ao registrar uso do token de API")
+	}
+	return bot, t, nil
+}
+
+// IsBot implementa ws.BotInbox.
+func (s *BotService) IsBot(ctx context.Context, userID string) bool {
+	return s.ownerOf(ctx, userID) != ""
+}
+
+// Received implementa ws.BotInbox: a mensagem vira o evento de webhook do
+// dono do bot e dos webhooks globais.
+func (s *BotService) Received(ctx context.Context, msg *model.Message) {
+	s.hooks.Emit(ctx, model.EventBotMessage, s.ownerOf(ctx, msg.To), map[string]any{
+		"messageId": msg.ID,
+		"from":      msg.From,
+		"to":        msg.To,
+		"content":   msg.Content,
+		"timestamp": msg.Timestamp,
+		"bot":       msg.Bot,
+	})
+}
+
+func (s *BotService) ownerOf(ctx context.Context, userID string) string {
+	s.mu.Lock()
+	owner, ok := s.owners[userID]
+	s.mu.Unlock()
+	if ok {
+		return owner
+	}
+
+	u, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	s.mu.Lock()
+	if len(s.owners) >= cacheLimit {
+		s.owners = make(map[string]string)
+	}
+	s.owners[userID] = u.OwnerID
+	s.mu.Unlock()
+	return u.OwnerID
+}
+
+func (s *BotService) forget(userID string) {
+	s.mu.Lock()
+	delete(s.owners, userID)
+	s.mu.Unlock()
+}
+
+func (s *BotService) owned(ctx context.Context, ownerID, botID string) (*model.User, error) {
+	bot, err := s.users.FindByUserID(ctx, botID)
+	if err != nil || !bot.IsBot || bot.OwnerID != ownerID {
+		return nil, ErrBotNotFound
+	}
+	return bot, nil
+}
+
+func newToken(prefix string) string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return prefix + hex.EncodeToString(buf)
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
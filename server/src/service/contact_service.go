@@ -6,6 +6,7 @@ import (
 	"errors"
 	"wisp/src/model"
 	"wisp/src/repository"
+	"wisp/src/tracing"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -38,6 +39,9 @@ func NewContactService(
 }
 
 func (s *ContactService) GetContacts(ctx context.Context, userUID string) ([]map[string]any, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.GetContacts")
+	defer span.End()
+
 	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.M{"ownerId": userUID}}},
 		{{Key: "$unwind", Value: "$contactIds"}},
@@ -74,14 +78,23 @@ func (s *ContactService) GetContacts(ctx context.Context, userUID string) ([]map
 }
 
 func (s *ContactService) GetIncomingRequests(ctx context.Context, userUID string) ([]model.FriendRequest, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.GetIncomingRequests")
+	defer span.End()
+
 	return s.frRepo.GetIncoming(ctx, userUID)
 }
 
 func (s *ContactService) GetSentRequests(ctx context.Context, userUID string) ([]model.FriendRequest, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.GetSentRequests")
+	defer span.End()
+
 	return s.frRepo.GetSent(ctx, userUID)
 }
 
 func (s *ContactService) SendFriendRequest(ctx context.Context, fromUID, toUID string) (primitive.ObjectID, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.SendFriendRequest")
+	defer span.End()
+
 	to, err := s.userRepo.FindByUserID(ctx, toUID)
 	if err != nil {
 		return primitive.NilObjectID, errors.New("usuário alvo não existe")
@@ -91,6 +104,9 @@ func (s *ContactService) SendFriendRequest(ctx context.Context, fromUID, toUID s
 }
 
 func (s *ContactService) CancelFriendRequest(ctx context.Context, reqID string, userUID string) error {
+	ctx, span := tracing.Start(ctx, "ContactService.CancelFriendRequest")
+	defer span.End()
+
 	id, _ := primitive.ObjectIDFromHex(reqID)
 	fr, err := s.frRepo.FindByID(ctx, id)
 	if err != nil {
@@ -105,6 +121,9 @@ func (s *ContactService) CancelFriendRequest(ctx context.Context, reqID string,
 }
 
 func (s *ContactService) AcceptFriendRequest(ctx context.Context, reqID, userUID string) error {
+	ctx, span := tracing.Start(ctx, "ContactService.AcceptFriendRequest")
+	defer span.End()
+
 	id, _ := primitive.ObjectIDFromHex(reqID)
 	fr, err := s.frRepo.FindByID(ctx, id)
 	if err != nil {
@@ -124,6 +143,9 @@ func (s *ContactService) AcceptFriendRequest(ctx context.Context, reqID, userUID
 }
 
 func (s *ContactService) RejectFriendRequest(ctx context.Context, reqID, userUID string) error {
+	ctx, span := tracing.Start(ctx, "ContactService.RejectFriendRequest")
+	defer span.End()
+
 	id, _ := primitive.ObjectIDFromHex(reqID)
 	fr, err := s.frRepo.FindByID(ctx, id)
 	if err != nil {
@@ -136,6 +158,9 @@ func (s *ContactService) RejectFriendRequest(ctx context.Context, reqID, userUID
 }
 
 func (s *ContactService) RemoveContact(ctx context.Context, userUID, targetUID string) error {
+	ctx, span := tracing.Start(ctx, "ContactService.RemoveContact")
+	defer span.End()
+
 	me, err := s.userRepo.FindByUserID(ctx, userUID)
 	if err != nil {
 		return err
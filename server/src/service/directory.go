@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+)
+
+// Directory responde aos filtros anti-abuso sobre contatos e idade das
+// contas.
+type Directory struct {
+	users    *repository.UserRepo
+	contacts *repository.ContactRepo
+}
+
+func NewDirectory(users *repository.UserRepo, contacts *repository.ContactRepo) *Directory {
+	return &Directory{users: users, contacts: contacts}
+}
+
+func (d *Directory) IsContact(ctx context.Context, ownerID, otherID string) (bool, error) {
+	return d.contacts.IsContact(ctx, ownerID, otherID)
+}
+
+func (d *Directory) CreatedAt(ctx context.Context, userID string) (time.Time, error) {
+	u, err := d.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return u.CreatedAt, nil
+}
+
+// reachable diz se to existe, não foi banido e é o próprio from ou um
+// contato dele.
+func reachable(ctx context.Context, users *repository.UserRepo, contacts *repository.ContactRepo, from, to string) (bool, error) {
+	if to != from {
+		ok, err := contacts.IsContact(ctx, from, to)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	u, err := users.FindByUserID(ctx, to)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return u.Status != model.UserBanned, nil
+}
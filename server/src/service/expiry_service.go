@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Limites do timer personalizado; 0 desliga.
+const (
+	minDisappearAfter = 30 * time.Second
+	maxDisappearAfter = 365 * 24 * time.Hour
+)
+
+// timerCacheTTL é quanto uma instância pode demorar para ver o timer trocado
+// em outra.
+const timerCacheTTL = 30 * time.Second
+
+var ErrTimerPeer = errors.New("o timer só pode ser trocado em conversas com um contato seu")
+
+// ExpiryHub é o que o serviço usa do hub: mandar o aviso de troca do timer
+// pela conversa e pedir aos dispositivos que apaguem mensagens vencidas.
+type ExpiryHub interface {
+	Broadcaster
+	Notify(userID string, v any)
+}
+
+// ExpiryService cuida das mensagens temporárias: o timer de cada conversa,
+// o carimbo de validade nas mensagens (via ws.Expirer) e a remoção das
+// vencidas da fila pendente, do histórico e dos dispositivos conectados.
+type ExpiryService struct {
+	settings *repository.ConversationRepo
+	expiries *repository.ExpiryRepo
+	messages *repository.MessageRepo
+	users    *repository.UserRepo
+	contacts *repository.ContactRepo
+	history  *HistoryService
+	hub      ExpiryHub
+	poll     time.Duration
+
+	mu     sync.Mutex
+	timers map[string]cached[time.Duration]
+}
+
+type cached[T any] struct {
+	value T
+	at    time.Time
+}
+
+func NewExpiryService(settings *repository.ConversationRepo, expiries *repository.ExpiryRepo, messages *repository.MessageRepo, users *repository.UserRepo, contacts *repository.ContactRepo, history *HistoryService, hub ExpiryHub) *ExpiryService {
+	return &ExpiryService{
+		settings: settings,
+		expiries: expiries,
+		messages: messages,
+		users:    users,
+		contacts: contacts,
+		history:  history,
+		hub:      hub,
+		poll:     time.Second,
+		timers:   make(map[string]cached[time.Duration]),
+	}
+}
+
+func (s *ExpiryService) Settings(ctx context.Context, userID, otherID string) (*model.ConversationSettings, error) {
+	ctx, span := tracing.Start(ctx, "ExpiryService.Settings")
+	defer span.End()
+
+	return s.settings.Get(ctx, userID, otherID)
+}
+
+// SetTimer troca o timer da conversa e avisa os dois lados com uma
+// mensagem de sistema. Vale para as mensagens enviadas daqui em diante.
+func (s *ExpiryService) SetTimer(ctx context.Context, userID, otherID string, after time.Duration) (*model.ConversationSettings, error) {
+	ctx, span := tracing.Start(ctx, "ExpiryService.SetTimer")
+	defer span.End()
+
+	if after != 0 && (after < minDisappearAfter || after > maxDisappearAfter) {
+		return nil, errors.New("o timer deve ser 0 ou estar entre 30s e 365 dias")
+	}
+	if userID == otherID {
+		return nil, errors.New("conversa inválida")
+	}
+	ok, err := reachable(ctx, s.users, s.contacts, userID, otherID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTimerPeer
+	}
+
+	seconds := int64(after / time.Second)
+	settings, err := s.settings.SetTimer(ctx, userID, otherID, seconds)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(model.ConversationKey(userID, otherID), after)
+
+	msg := &model.Message{
+		Type:      "message",
+		From:      userID,
+		To:        otherID,
+		Timestamp: time.Now().Unix(),
+		Event:     &model.MessageEvent{Type: model.MessageEventTimer, Seconds: seconds},
+	}
+	s.hub.Broadcast(context.WithoutCancel(ctx), msg)
+	// Os outros dispositivos de quem trocou também precisam ver o aviso.
+	s.hub.Notify(userID, msg)
+	return settings, nil
+}
+
+// DisappearAfter implementa ws.Expirer.
+func (s *ExpiryService) DisappearAfter(ctx context.Context, from, to string) time.Duration {
+	key := model.ConversationKey(from, to)
+	s.mu.Lock()
+	c, ok := s.timers[key]
+	s.mu.Unlock()
+	if ok && time.Since(c.at) < timerCacheTTL {
+		return c.value
+	}
+
+	settings, err := s.settings.Get(ctx, from, to)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("Erro ao buscar timer da conversa")
+		return c.value
+	}
+	after := time.Duration(settings.DisappearAfter) * time.Second
+	s.remember(key, after)
+	return after
+}
+
+// Track implementa ws.Expirer.
+func (s *ExpiryService) Track(ctx context.Context, msg *model.Message) {
+	id, _ := primitive.ObjectIDFromHex(msg.ID)
+	err := s.expiries.Insert(ctx, &model.MessageExpiry{
+		ID:        id,
+		From:      msg.From,
+		To:        msg.To,
+		ExpiresAt: time.Unix(msg.ExpiresAt, 0),
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("id", msg.ID).Msg("Erro ao agendar expiração de mensagem")
+	}
+}
+
+func (s *ExpiryService) remember(key string, after time.Duration) {
+	s.mu.Lock()
+	if len(s.timers) >= cacheLimit {
+		s.timers = make(map[string]cached[time.Duration])
+	}
+	s.timers[key] = cached[time.Duration]{value: after, at: time.Now()}
+	s.mu.Unlock()
+}
+
+// Start roda a remoção das mensagens vencidas até ctx ser cancelado.
+func (s *ExpiryService) Start(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			done, err := s.expireNext(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Msg("Erro ao expirar mensagens")
+			}
+			if done {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(s.poll):
+			}
+		}
+	}()
+}
+
+func (s *ExpiryService) expireNext(ctx context.Context) (bool, error) {
+	e, err := s.expiries.TakeDue(ctx, time.Now())
+	if err != nil || e == nil {
+		return false, err
+	}
+
+	// Se a remoção falhar, os índices TTL em expiresAt apagam a fila
+	// pendente e o histórico depois, e a busca já ignora o que venceu; os
+	// dispositivos são avisados de qualquer forma.
+	err = errors.Join(s.messages.DeleteMessage(ctx, e.ID), s.history.Delete(ctx, e.ID))
+	frame := &model.MessageExpired{Type: "message_expired", MessageID: e.ID.Hex(), From: e.From, To: e.To}
+	s.hub.Notify(e.From, frame)
+	s.hub.Notify(e.To, frame)
+	return true, err
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/search"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Lotes de gravação do histórico: até historyBatch mensagens ou o que chegou
+// em historyFlush.
+const (
+	historyBatch = 100
+	historyFlush = 200 * time.Millisecond
+)
+
+const (
+	minSearchLength = 2
+	maxSearchLength = 256
+	maxSearchLimit  = 50
+)
+
+var ErrSearchQuery = errors.New("a busca deve ter entre 2 e 256 caracteres")
+
+// HistoryService grava o histórico das mensagens roteadas pelo hub e busca
+// nele. A gravação é em lote, num goroutine próprio, para não segurar o
+// accept do hub; cada lote gravado também atualiza a lista de conversas.
+type HistoryService struct {
+	history *repository.HistoryRepo
+	index   search.SearchIndex
+	inbox   *InboxService
+	jobs    chan *model.HistoryMessage
+	stopped chan struct{}
+}
+
+func NewHistoryService(history *repository.HistoryRepo, index search.SearchIndex, inbox *InboxService) *HistoryService {
+	return &HistoryService{
+		history: history,
+		index:   index,
+		inbox:   inbox,
+		jobs:    make(chan *model.HistoryMessage, 4096),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start grava os lotes até ctx ser cancelado; o que ainda estiver na fila
+// é gravado antes de sair.
+func (s *HistoryService) Start(ctx context.Context) {
+	go func() {
+		defer close(s.stopped)
+		batch := make([]*model.HistoryMessage, 0, historyBatch)
+		ticker := time.NewTicker(historyFlush)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				for len(s.jobs) > 0 {
+					batch = append(batch, <-s.jobs)
+				}
+				s.write(context.WithoutCancel(ctx), batch)
+				return
+			case hm := <-s.jobs:
+				if batch = append(batch, hm); len(batch) < historyBatch {
+					continue
+				}
+			case <-ticker.C:
+			}
+			s.write(ctx, batch)
+			batch = batch[:0]
+		}
+	}()
+}
+
+// Record implementa ws.History. Com a fila cheia a mensagem é gravada no
+// próprio chamador: o histórico não descarta mensagens.
+func (s *HistoryService) Record(ctx context.Context, msg *model.Message) {
+	hm := model.NewHistoryMessage(msg)
+	select {
+	case s.jobs <- hm:
+	default:
+		s.write(ctx, []*model.HistoryMessage{hm})
+	}
+}
+
+func (s *HistoryService) write(ctx context.Context, batch []*model.HistoryMessage) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// A lista de conversas não depende do histórico gravado: as mensagens
+	// já foram entregues.
+	defer s.inbox.Recorded(ctx, batch)
+
+	if err := s.history.InsertMany(ctx, batch); err != nil {
+		log.Error().Err(err).Int("count", len(batch)).Msg("Erro ao gravar histórico de mensagens")
+		return
+	}
+	if err := s.index.Index(ctx, batch); err != nil {
+		log.Error().Err(err).Int("count", len(batch)).Msg("Erro ao indexar mensagens para a busca")
+	}
+}
+
+// Delete apaga a mensagem do histórico, do índice e da prévia da lista de
+// conversas.
+func (s *HistoryService) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "HistoryService.Delete")
+	defer span.End()
+
+	if err := s.history.Delete(ctx, id); err != nil {
+		return err
+	}
+	return errors.Join(s.index.Delete(ctx, id.Hex()), s.inbox.Removed(ctx, id.Hex()))
+}
+
+// Search busca no histórico de q.UserID. Além do filtro do índice, cada
+// resultado é conferido aqui: nada de conversas de que ele não participa.
+func (s *HistoryService) Search(ctx context.Context, q *model.SearchQuery, page int) ([]model.SearchResult, error) {
+	ctx, span := tracing.Start(ctx, "HistoryService.Search")
+	defer span.End()
+
+	q.Text = strings.TrimSpace(q.Text)
+	if n := utf8.RuneCountInString(q.Text); n < minSearchLength || n > maxSearchLength {
+		return nil, ErrSearchQuery
+	}
+	if q.Limit < 1 || q.Limit > maxSearchLimit {
+		q.Limit = maxSearchLimit
+	}
+	q.Offset = (page - 1) * q.Limit
+
+	msgs, err := s.index.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]model.SearchResult, 0, len(msgs))
+	for i := range msgs {
+		m := &msgs[i]
+		if m.From != q.UserID && m.To != q.UserID {
+			continue
+		}
+		results = append(results, model.SearchResult{
+			Message:  m.Message(),
+			Snippets: search.Highlight(m, q.Text),
+		})
+	}
+	return results, nil
+}
+
+// Close espera a última gravação de Start e fecha o índice.
+func (s *HistoryService) Close() error {
+	<-s.stopped
+	return s.index.Close()
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxInboxLimit = 100
+	maxPinned     = 10
+	maxMuteFor    = 365 * 24 * time.Hour
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversa não encontrada")
+	ErrInvalidCursor        = errors.New("cursor inválido")
+	ErrPinLimit             = errors.New("limite de conversas fixadas atingido")
+	ErrPinOrder             = errors.New("a nova ordem deve listar exatamente as conversas fixadas")
+	ErrMuteDuration         = errors.New("o prazo deve estar entre 0 (sem prazo) e 365 dias")
+)
+
+// InboxHub é o que o serviço usa do hub: avisar os dispositivos do usuário.
+type InboxHub interface {
+	Notify(userID string, v any)
+}
+
+// InboxService mantém a lista de conversas de cada usuário, com a última
+// mensagem e o não lido, a partir dos lotes gravados no histórico, e o
+// estado de cada uma (silenciada, fixada, arquivada). Toda mudança vai para
+// os dispositivos do usuário num conversation_update.
+type InboxService struct {
+	inbox *repository.InboxRepo
+	users *repository.UserRepo
+	hub   InboxHub
+}
+
+func NewInboxService(inbox *repository.InboxRepo, users *repository.UserRepo, hub InboxHub) *InboxService {
+	return &InboxService{inbox: inbox, users: users, hub: hub}
+}
+
+// Recorded atualiza as conversas das mensagens do lote e manda um
+// conversation_update para os dois lados de cada uma.
+func (s *InboxService) Recorded(ctx context.Context, msgs []*model.HistoryMessage) {
+	ids, err := s.inbox.Apply(ctx, msgs)
+	if err != nil {
+		log.Error().Err(err).Int("count", len(msgs)).Msg("Erro ao atualizar lista de conversas")
+		return
+	}
+	convs, err := s.inbox.FindByIDs(ctx, ids)
+	if err != nil {
+		log.Error().Err(err).Msg("Erro ao buscar conversas atualizadas")
+		return
+	}
+	s.prepare(ctx, convs)
+	for i := range convs {
+		s.notify(&convs[i])
+	}
+}
+
+// List devolve uma página da caixa de entrada (ou do arquivo), da conversa
+// mais recente para a mais antiga, com o cursor da próxima ("" na última).
+func (s *InboxService) List(ctx context.Context, userID string, archived bool, cursor string, limit int) (*model.ConversationList, error) {
+	ctx, span := tracing.Start(ctx, "InboxService.List")
+	defer span.End()
+
+	if limit < 1 || limit > maxInboxLimit {
+		limit = maxInboxLimit
+	}
+	before, beforeID, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &model.ConversationList{}
+	if cursor == "" && !archived {
+		if list.Pinned, err = s.inbox.ListPinned(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	list.Conversations, err = s.inbox.List(ctx, userID, archived, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	// Um prepare só para as duas listas: uma busca de usuários a menos.
+	all := append(append([]model.Conversation{}, list.Pinned...), list.Conversations...)
+	s.prepare(ctx, all)
+	list.Pinned, list.Conversations = all[:len(list.Pinned)], all[len(list.Pinned):]
+
+	if len(list.Conversations) == limit {
+		last := list.Conversations[len(list.Conversations)-1]
+		list.NextCursor = encodeCursor(last.LastActivityAt, last.ID)
+	}
+	return list, nil
+}
+
+// MarkRead zera o não lido da conversa e sincroniza os outros dispositivos.
+func (s *InboxService) MarkRead(ctx context.Context, userID, peerID string) (*model.Conversation, error) {
+	ctx, span := tracing.Start(ctx, "InboxService.MarkRead")
+	defer span.End()
+
+	c, err := s.inbox.MarkRead(ctx, userID, peerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.changed(ctx, c)
+}
+
+// Mute silencia a conversa por muteFor, ou sem prazo se 0; com muted false
+// volta a notificar. Conversas silenciadas não geram push, a não ser por
+// menção.
+func (s *InboxService) Mute(ctx context.Context, userID, peerID string, muted bool, muteFor time.Duration) (*model.Conversation, error) {
+	ctx, span := tracing.Start(ctx, "InboxService.Mute")
+	defer span.End()
+
+	if muteFor < 0 || muteFor > maxMuteFor {
+		return nil, ErrMuteDuration
+	}
+	var until *time.Time
+	if muted && muteFor > 0 {
+		t := time.Now().Add(muteFor)
+		until = &t
+	}
+	c, err := s.inbox.SetMuted(ctx, userID, peerID, muted, until)
+	if err != nil {
+		return nil, err
+	}
+	return s.changed(ctx, c)
+}
+
+// Pin fixa a conversa no fim das fixadas, ou a desafixa.
+func (s *InboxService) Pin(ctx context.Context, userID, peerID string, pinned bool) (*model.Conversation, error) {
+	ctx, span := tracing.Start(ctx, "InboxService.Pin")
+	defer span.End()
+
+	order := 0
+	if pinned {
+		current, err := s.inbox.ListPinned(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range current {
+			if current[i].PeerID == peerID {
+				return s.changed(ctx, &current[i])
+			}
+		}
+		if len(current) >= maxPinned {
+			return nil, ErrPinLimit
+		}
+		order = 1
+		if n := len(current); n > 0 {
+			order = current[n-1].PinOrder + 1
+		}
+	}
+	c, err := s.inbox.SetPinned(ctx, userID, peerID, order)
+	if err != nil {
+		return nil, err
+	}
+	return s.changed(ctx, c)
+}
+
+// ReorderPins troca a ordem das fixadas; peerIDs deve ter todas elas.
+func (s *InboxService) ReorderPins(ctx context.Context, userID string, peerIDs []string) ([]model.Conversation, error) {
+	ctx, span := tracing.Start(ctx, "InboxService.ReorderPins")
+	defer span.End()
+
+	current, err := s.inbox.ListPinned(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[string]bool, len(current))
+	for _, c := range current {
+		pinned[c.PeerID] = true
+	}
+	if len(peerIDs) != len(current) {
+		return nil, ErrPinOrder
+	}
+	for _, id := range peerIDs {
+		if !pinned[id] {
+			return nil, ErrPinOrder
+		}
+		delete(pinned, id)
+	}
+	if len(peerIDs) == 0 {
+		return []model.Conversation{}, nil
+	}
+
+	if err := s.inbox.ReorderPins(ctx, userID, peerIDs); err != nil {
+		return nil, err
+	}
+	convs, err := s.inbox.ListPinned(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.prepare(ctx, convs)
+	for i := range convs {
+		s.notify(&convs[i])
+	}
+	return convs, nil
+}
+
+// Archive tira a conversa da caixa de entrada (e das fixadas) ou a devolve.
+// Mensagens novas não a desarquivam.
+func (s *InboxService) Archive(ctx context.Context, userID, peerID string, archived bool) (*model.Conversation, error) {
+	ctx, span := tracing.Start(ctx, "InboxService.Archive")
+	defer span.End()
+
+	c, err := s.inbox.SetArchived(ctx, userID, peerID, archived)
+	if err != nil {
+		return nil, err
+	}
+	return s.changed(ctx, c)
+}
+
+// changed sincroniza a conversa alterada com os dispositivos do usuário.
+func (s *InboxService) changed(ctx context.Context, c *model.Conversation) (*model.Conversation, error) {
+	if c == nil {
+		return nil, ErrConversationNotFound
+	}
+	convs := []model.Conversation{*c}
+	s.prepare(ctx, convs)
+	s.notify(&convs[0])
+	return &convs[0], nil
+}
+
+// Removed tira a prévia de uma mensagem que sumiu, como as temporárias
+// vencidas. Os dispositivos já recebem o aviso da remoção.
+func (s *InboxService) Removed(ctx context.Context, messageID string) error {
+	return s.inbox.ClearLastMessage(ctx, messageID)
+}
+
+func (s *InboxService) notify(c *model.Conversation) {
+	s.hub.Notify(c.UserID, &model.ConversationUpdate{Type: "conversation_update", Conversation: c})
+}
+
+// prepare acerta o silêncio vencido e preenche o nome de quem está do outro
+// lado. Sem o usuário (conta apagada ou erro no banco), a conversa vai só
+// com o peerId.
+func (s *InboxService) prepare(ctx context.Context, convs []model.Conversation) {
+	if len(convs) == 0 {
+		return
+	}
+	now := time.Now()
+	ids := make([]string, len(convs))
+	for i := range convs {
+		ids[i] = convs[i].PeerID
+		if !convs[i].IsMuted(now) {
+			convs[i].Muted, convs[i].MutedUntil = false, nil
+		}
+	}
+	users, err := s.users.FindByUserIDs(ctx, ids)
+	if err != nil {
+		log.Error().Err(err).Msg("Erro ao buscar participantes das conversas")
+		return
+	}
+	byID := make(map[string]*model.User, len(users))
+	for i := range users {
+		byID[users[i].UserID] = &users[i]
+	}
+	for i := range convs {
+		if u, ok := byID[convs[i].PeerID]; ok {
+			convs[i].Peer = &model.ConversationPeer{UserID: u.UserID, Name: u.Name, IsBot: u.IsBot}
+		}
+	}
+}
+
+func encodeCursor(t time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(t.UnixMilli(), 10) + "|" + id))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	if cursor == "" {
+		return time.Time{}, "", nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	ms, id, ok := strings.Cut(string(raw), "|")
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if !ok || err != nil || id == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return time.UnixMilli(n), id, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IncomingWebhookPrefix marca os segredos das URLs de webhook de entrada.
+const IncomingWebhookPrefix = "whk_"
+
+var ErrIncomingWebhookNotFound = errors.New("webhook de entrada não encontrado")
+
+// Broadcaster entrega uma mensagem pelo mesmo caminho das enviadas por
+// WebSocket. O ws.Hub o implementa.
+type Broadcaster interface {
+	Broadcast(ctx context.Context, message *model.Message)
+}
+
+// IncomingWebhookService transforma POSTs em URLs secretas em mensagens de
+// um bot do dono. Ainda não há grupos, então o destino é sempre um usuário:
+// o próprio dono ou um contato dele.
+type IncomingWebhookService struct {
+	hooks      *repository.IncomingWebhookRepo
+	users      *repository.UserRepo
+	contacts   *repository.ContactRepo
+	hub        Broadcaster
+	maxPerUser int
+}
+
+func NewIncomingWebhookService(hooks *repository.IncomingWebhookRepo, users *repository.UserRepo, contacts *repository.ContactRepo, hub Broadcaster, maxPerUser int) *IncomingWebhookService {
+	return &IncomingWebhookService{hooks: hooks, users: users, contacts: contacts, hub: hub, maxPerUser: maxPerUser}
+}
+
+// Create devolve o webhook e o segredo que compõe a URL, mostrado só aqui.
+func (s *IncomingWebhookService) Create(ctx context.Context, ownerID, botID, targetID, name string) (*model.IncomingWebhook, string, error) {
+	ctx, span := tracing.Start(ctx, "IncomingWebhookService.Create")
+	defer span.End()
+
+	bot, err := s.users.FindByUserID(ctx, botID)
+	if err != nil || !bot.IsBot || bot.OwnerID != ownerID {
+		return nil, "", ErrBotNotFound
+	}
+	if targetID != ownerID {
+		ok, err := s.contacts.IsContact(ctx, ownerID, targetID)
+		if err != nil {
+			return nil, "", err
+		}
+		if !ok {
+			return nil, "", errors.New("o destino precisa ser você ou um contato seu")
+		}
+	}
+	n, err := s.hooks.CountByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if n >= int64(s.maxPerUser) {
+		return nil, "", errors.New("limite de webhooks de entrada atingido")
+	}
+
+	raw := newToken(IncomingWebhookPrefix)
+	hook := &model.IncomingWebhook{
+		OwnerID:  ownerID,
+		BotID:    botID,
+		TargetID: targetID,
+		Name:     name,
+		Prefix:   raw[:len(IncomingWebhookPrefix)+8],
+		Hash:     hashToken(raw),
+	}
+	if err := s.hooks.Create(ctx, hook); err != nil {
+		return nil, "", err
+	}
+	return hook, raw, nil
+}
+
+func (s *IncomingWebhookService) List(ctx context.Context, ownerID string) ([]model.IncomingWebhook, error) {
+	ctx, span := tracing.Start(ctx, "IncomingWebhookService.List")
+	defer span.End()
+
+	return s.hooks.ListByOwner(ctx, ownerID)
+}
+
+func (s *IncomingWebhookService) Delete(ctx context.Context, ownerID, id string) error {
+	ctx, span := tracing.Start(ctx, "IncomingWebhookService.Delete")
+	defer span.End()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrIncomingWebhookNotFound
+	}
+	if err := s.hooks.Delete(ctx, ownerID, oid); err != nil {
+		return ErrIncomingWebhookNotFound
+	}
+	return nil
+}
+
+// Post monta a mensagem do bot e a entrega ao hub. Um bot suspenso ou banido
+// recebe o mesmo AccountBlockedError do login.
+func (s *IncomingWebhookService) Post(ctx context.Context, secret, text, clientID string, attachments []model.Attachment) (*model.Message, error) {
+	ctx, span := tracing.Start(ctx, "IncomingWebhookService.Post")
+	defer span.End()
+
+	hook, err := s.hooks.FindByHash(ctx, hashToken(secret))
+	if err != nil {
+		return nil, ErrIncomingWebhookNotFound
+	}
+	bot, err := s.users.FindByUserID(ctx, hook.BotID)
+	if err != nil || !bot.IsBot {
+		return nil, ErrIncomingWebhookNotFound
+	}
+	if bot.Blocked(time.Now()) {
+		return nil, blockedError(bot)
+	}
+	if err := s.hooks.Touch(ctx, hook.ID, time.Now()); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("Erro ao registrar uso do webhook de entrada")
+	}
+
+	msg := &model.Message{
+		Type:        "message",
+		From:        hook.BotID,
+		To:          hook.TargetID,
+		Content:     text,
+		Timestamp:   time.Now().Unix(),
+		ClientID:    clientID,
+		Bot:         true,
+		Attachments: attachments,
+	}
+	// O hub processa a mensagem depois da resposta; o contexto só leva o
+	// trace adiante.
+	s.hub.Broadcast(context.WithoutCancel(ctx), msg)
+	return msg, nil
+}
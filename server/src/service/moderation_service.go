@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+)
+
+// Disconnector derruba as conexões WebSocket de um usuário. O ws.Hub o
+// implementa; a interface evita que o serviço dependa do pacote ws.
+type Disconnector interface {
+	DisconnectUser(userID string, notice any)
+}
+
+// Status enviado no aviso de logout forçado, que não muda o estado da conta.
+const noticeLoggedOut = "logged_out"
+
+type ModerationService struct {
+	users *repository.UserRepo
+	auth  *AuthService
+	hub   Disconnector
+}
+
+func NewModerationService(users *repository.UserRepo, auth *AuthService, hub Disconnector) *ModerationService {
+	return &ModerationService{users: users, auth: auth, hub: hub}
+}
+
+// Suspend bloqueia a conta até until, revoga as sessões e desconecta os
+// dispositivos. Retorna o usuário como estava antes.
+func (s *ModerationService) Suspend(ctx context.Context, userID, reason string, until time.Time) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.Suspend")
+	defer span.End()
+
+	if !until.After(time.Now()) {
+		return nil, errors.New("a suspensão precisa terminar no futuro")
+	}
+	return s.block(ctx, userID, model.UserSuspended, reason, &until)
+}
+
+// Ban bloqueia a conta por tempo indeterminado.
+func (s *ModerationService) Ban(ctx context.Context, userID, reason string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.Ban")
+	defer span.End()
+
+	return s.block(ctx, userID, model.UserBanned, reason, nil)
+}
+
+// Reinstate devolve a conta ao estado ativo. As sessões revogadas não voltam;
+// o usuário precisa entrar de novo.
+func (s *ModerationService) Reinstate(ctx context.Context, userID string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.Reinstate")
+	defer span.End()
+
+	u, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.users.UpdateByUserID(ctx, userID, map[string]any{
+		"status":         model.UserActive,
+		"statusReason":   "",
+		"suspendedUntil": nil,
+	})
+	return u, err
+}
+
+// AutoSuspend é a suspensão aplicada pelos filtros anti-abuso.
+func (s *ModerationService) AutoSuspend(ctx context.Context, userID, reason string, d time.Duration) error {
+	ctx, span := tracing.Start(ctx, "ModerationService.AutoSuspend")
+	defer span.End()
+
+	// Mensagens que chegam enquanto a suspensão é aplicada não a estendem.
+	u, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.Blocked(time.Now()) {
+		return nil
+	}
+	until := time.Now().Add(d)
+	_, err = s.block(ctx, userID, model.UserSuspended, reason, &until)
+	return err
+}
+
+// ForceLogout revoga todas as sessões e desconecta os dispositivos sem
+// alterar o estado da conta.
+func (s *ModerationService) ForceLogout(ctx context.Context, userID, reason string) error {
+	ctx, span := tracing.Start(ctx, "ModerationService.ForceLogout")
+	defer span.End()
+
+	u, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.auth.RevokeSessions(ctx, u); err != nil {
+		return err
+	}
+	s.hub.DisconnectUser(userID, &model.AccountNotice{Type: "account_status", Status: noticeLoggedOut, Reason: reason})
+	return nil
+}
+
+func (s *ModerationService) block(ctx context.Context, userID, status, reason string, until *time.Time) (*model.User, error) {
+	u, err := s.users.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsAdmin {
+		return nil, errors.New("remova o papel de admin antes de bloquear a conta")
+	}
+
+	err = s.users.UpdateByUserID(ctx, userID, map[string]any{
+		"status":         status,
+		"statusReason":   reason,
+		"suspendedUntil": until,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.auth.RevokeSessions(ctx, u); err != nil {
+		return nil, err
+	}
+
+	s.hub.DisconnectUser(userID, &model.AccountNotice{Type: "account_status", Status: status, Reason: reason, Until: until})
+	return u, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wisp/src/metrics"
+	"wisp/src/model"
+	"wisp/src/push"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Modos de payload das notificações.
+const (
+	PushPreview = "preview"
+	PushHint    = "hint"
+)
+
+var ErrPushToken = errors.New("provedor de push não habilitado ou token inválido")
+
+// PushTokens guarda os tokens de push. Em produção é o
+// repository.PushTokenRepo.
+type PushTokens interface {
+	Upsert(ctx context.Context, t *model.PushToken) error
+	ListByUser(ctx context.Context, userID string) ([]model.PushToken, error)
+	Delete(ctx context.Context, userID, deviceID string) error
+	DeleteToken(ctx context.Context, provider, token string) error
+}
+
+// PushUsers dá o nome do remetente no modo preview. Em produção é o
+// repository.UserRepo.
+type PushUsers interface {
+	FindByUserID(ctx context.Context, userID string) (*model.User, error)
+}
+
+// PushInbox informa se o destinatário silenciou a conversa. Em produção é o
+// repository.InboxRepo.
+type PushInbox interface {
+	IsMuted(ctx context.Context, userID, peerID string) (bool, error)
+}
+
+// PushService guarda os tokens dos dispositivos e notifica os offline quando
+// uma mensagem cai na fila pendente. O envio roda em workers próprios para
+// não segurar a fila de armazenamento do hub. Conversas silenciadas só
+// notificam quando a mensagem menciona o destinatário.
+type PushService struct {
+	tokens  PushTokens
+	users   PushUsers
+	inbox   PushInbox
+	pushers map[string]push.Pusher
+	mode    string
+	jobs    chan *model.Message
+	workers int
+}
+
+func NewPushService(tokens PushTokens, users PushUsers, inbox PushInbox, pushers map[string]push.Pusher, mode string, workers int) *PushService {
+	return &PushService{
+		tokens:  tokens,
+		users:   users,
+		inbox:   inbox,
+		pushers: pushers,
+		mode:    mode,
+		jobs:    make(chan *model.Message, 1024),
+		workers: workers,
+	}
+}
+
+// Start sobe os workers de envio até ctx ser cancelado.
+func (s *PushService) Start(ctx context.Context) {
+	for range s.workers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-s.jobs:
+					s.notify(ctx, msg)
+				}
+			}
+		}()
+	}
+}
+
+func (s *PushService) Register(ctx context.Context, userID, deviceID, provider, token string) error {
+	ctx, span := tracing.Start(ctx, "PushService.Register")
+	defer span.End()
+
+	if _, ok := s.pushers[provider]; !ok {
+		return ErrPushToken
+	}
+	if provider == push.ProviderUnifiedPush && !push.ValidEndpoint(token) {
+		return ErrPushToken
+	}
+	return s.tokens.Upsert(ctx, &model.PushToken{UserID: userID, DeviceID: deviceID, Provider: provider, Token: token})
+}
+
+func (s *PushService) Unregister(ctx context.Context, userID, deviceID string) error {
+	ctx, span := tracing.Start(ctx, "PushService.Unregister")
+	defer span.End()
+
+	return s.tokens.Delete(ctx, userID, deviceID)
+}
+
+func (s *PushService) List(ctx context.Context, userID string) ([]model.PushToken, error) {
+	ctx, span := tracing.Start(ctx, "PushService.List")
+	defer span.End()
+
+	return s.tokens.ListByUser(ctx, userID)
+}
+
+// MessageQueued enfileira a notificação; se a fila estiver cheia, a
+// notificação é descartada, mas a mensagem continua na fila pendente.
+func (s *PushService) MessageQueued(_ context.Context, msg *model.Message) {
+	select {
+	case s.jobs <- msg:
+	default:
+		metrics.PushSent.WithLabelValues("", "dropped").Inc()
+		log.Warn().Str("to", msg.To).Msg("Fila de push cheia, notificação descartada")
+	}
+}
+
+func (s *PushService) notify(ctx context.Context, msg *model.Message) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "PushService.notify", tracing.Attrs("wisp.message_id", msg.ID, "wisp.to", msg.To))
+	defer span.End()
+
+	if !msg.Mentions(msg.To) {
+		muted, err := s.inbox.IsMuted(ctx, msg.To, msg.From)
+		if err != nil {
+			log.Error().Err(err).Str("to", msg.To).Msg("Erro ao consultar silêncio da conversa")
+		}
+		if muted {
+			metrics.PushSent.WithLabelValues("", "muted").Inc()
+			return
+		}
+	}
+
+	tokens, err := s.tokens.ListByUser(ctx, msg.To)
+	if err != nil {
+		log.Error().Err(err).Str("to", msg.To).Msg("Erro ao buscar tokens de push")
+		return
+	}
+	if len(tokens) == 0 {
+		return
+	}
+
+	n := s.notification(ctx, msg)
+	for _, t := range tokens {
+		pusher, ok := s.pushers[t.Provider]
+		if !ok {
+			continue
+		}
+		err := pusher.Push(ctx, t.Token, n)
+		switch {
+		case err == nil:
+			metrics.PushSent.WithLabelValues(t.Provider, "ok").Inc()
+		case errors.Is(err, push.ErrInvalidToken):
+			metrics.PushSent.WithLabelValues(t.Provider, "invalid_token").Inc()
+			if err := s.tokens.DeleteToken(ctx, t.Provider, t.Token); err != nil {
+				log.Error().Err(err).Msg("Erro ao remover token de push inválido")
+			}
+		default:
+			metrics.PushSent.WithLabelValues(t.Provider, "error").Inc()
+			log.Error().Err(err).Str("provider", t.Provider).Str("to", msg.To).Str("deviceId", t.DeviceID).Msg("Erro ao enviar push")
+		}
+	}
+}
+
+// notification monta o payload no modo configurado. As notificações de uma
+// mesma conversa usam a mesma collapse key, e o aparelho mostra só a última.
+func (s *PushService) notification(ctx context.Context, msg *model.Message) *push.Notification {
+	collapse := "conv-" + msg.From
+	if s.mode == PushHint {
+		return &push.Notification{
+			Title:       "Wisp",
+			Body:        "Nova mensagem",
+			CollapseKey: collapse,
+			Data:        map[string]string{"type": "new_message", "messageId": msg.ID},
+		}
+	}
+
+	title := msg.From
+	if u, err := s.users.FindByUserID(ctx, msg.From); err == nil {
+		title = u.Name
+	}
+	return &push.Notification{
+		Title:       title,
+		Body:        model.Preview(msg.Content),
+		CollapseKey: collapse,
+		Data:        map[string]string{"type": "message", "messageId": msg.ID, "from": msg.From},
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"wisp/src/model"
+	"wisp/src/push"
+	"wisp/src/repository"
+)
+
+type fakePushTokens struct {
+	mu     sync.Mutex
+	tokens []model.PushToken
+}
+
+func (f *fakePushTokens) Upsert(ctx context.Context, t *model.PushToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = append(f.tokens, *t)
+	return nil
+}
+
+func (f *fakePushTokens) ListByUser(ctx context.Context, userID string) ([]model.PushToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var out []model.PushToken
+	for _, t := range f.tokens {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakePushTokens) Delete(ctx context.Context, userID, deviceID string) error {
+	return nil
+}
+
+func (f *fakePushTokens) DeleteToken(ctx context.Context, provider, token string) error {
+	return nil
+}
+
+type fakePushUsers map[string]*model.User
+
+func (f fakePushUsers) FindByUserID(ctx context.Context, userID string) (*model.User, error) {
+	if u, ok := f[userID]; ok {
+		return u, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+// fakePushInbox guarda as conversas silenciadas como "userId|peerId".
+type fakePushInbox map[string]bool
+
+func (f fakePushInbox) IsMuted(ctx context.Context, userID, peerID string) (bool, error) {
+	return f[userID+"|"+peerID], nil
+}
+
+// queueForOffline simula o hub guardando uma mensagem de alice01 com content
+// para bob0001, sem dispositivos conectados, e devolve o que o provedor
+// recebeu.
+func queueForOffline(t *testing.T, mode string, inbox fakePushInbox, content string) []push.Sent {
+	t.Helper()
+
+	fake := push.NewFake()
+	tokens := &fakePushTokens{}
+	users := fakePushUsers{"alice01": {UserID: "alice01", Name: "Alice"}}
+	svc := NewPushService(tokens, users, inbox, map[string]push.Pusher{push.ProviderFake: fake}, mode, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc.Start(ctx)
+
+	for userID, token := range map[string]string{"bob0001": "tok-1", "carol01": "tok-2"} {
+		if err := svc.Register(ctx, userID, "phone", push.ProviderFake, token); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	svc.MessageQueued(ctx, &model.Message{
+		Type:    "message",
+		ID:      "msg-1",
+		From:    "alice01",
+		To:      "bob0001",
+		Content: content,
+	})
+
+	// Com um worker só, o push para carol01 sai depois do processamento da
+	// mensagem para bob0001, tenha ela gerado push ou não.
+	svc.MessageQueued(ctx, &model.Message{Type: "message", ID: "msg-2", From: "alice01", To: "carol01"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var bob []push.Sent
+		done := false
+		for _, sent := range fake.Sent() {
+			switch sent.Token {
+			case "tok-1":
+				bob = append(bob, sent)
+			case "tok-2":
+				done = true
+			}
+		}
+		if done {
+			return bob
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("push não processado a tempo")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPushForOfflineDevice(t *testing.T) {
+	cases := []struct {
+		mode        string
+		title, body string
+		data        map[string]string
+	}{
+		{PushPreview, "Alice", "oi, tudo bem?", map[string]string{"type": "message", "messageId": "msg-1", "from": "alice01"}},
+		{PushHint, "Wisp", "Nova mensagem", map[string]string{"type": "new_message", "messageId": "msg-1"}},
+	}
+	for _, c := range cases {
+		t.Run(c.mode, func(t *testing.T) {
+			sent := queueForOffline(t, c.mode, fakePushInbox{}, "oi, tudo bem?")
+			if len(sent) != 1 {
+				t.Fatalf("esperava 1 push, vieram %d", len(sent))
+			}
+
+			n := sent[0].Notification
+			if sent[0].Token != "tok-1" {
+				t.Errorf("token %q", sent[0].Token)
+			}
+			if n.CollapseKey != "conv-alice01" {
+				t.Errorf("collapse key %q, esperava a da conversa", n.CollapseKey)
+			}
+			if n.Title != c.title || n.Body != c.body {
+				t.Errorf("título %q e corpo %q", n.Title, n.Body)
+			}
+			if len(n.Data) != len(c.data) {
+				t.Errorf("data %v, esperava %v", n.Data, c.data)
+			}
+			for k, v := range c.data {
+				if n.Data[k] != v {
+					t.Errorf("data[%s] = %q, esperava %q", k, n.Data[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestMutedConversation(t *testing.T) {
+	muted := fakePushInbox{"bob0001|alice01": true}
+	cases := map[string]int{
+		"oi, tudo bem?":       0,
+		"@bob0001 me liga":    1,
+		"e aí, @bob0001?":     1,
+		"fala com @bob00012":  0,
+		"manda para bob0001@": 0,
+	}
+	for content, want := range cases {
+		if sent := queueForOffline(t, PushPreview, muted, content); len(sent) != want {
+			t.Errorf("%q em conversa silenciada gerou %d push, esperava %d", content, len(sent), want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SystemReporter é o autor das denúncias abertas pelos filtros anti-abuso.
+const SystemReporter = "system"
+
+var (
+	ErrReportLimit     = errors.New("limite de denúncias atingido, tente mais tarde")
+	ErrReportedMessage = errors.New("mensagem denunciada não encontrada no histórico")
+)
+
+type ReportService struct {
+	repo       *repository.ReportRepo
+	users      *repository.UserRepo
+	history    *repository.HistoryRepo
+	moderation *ModerationService
+	maxPerHour int
+}
+
+func NewReportService(r *repository.ReportRepo, users *repository.UserRepo, history *repository.HistoryRepo, m *ModerationService, maxPerHour int) *ReportService {
+	return &ReportService{repo: r, users: users, history: history, moderation: m, maxPerHour: maxPerHour}
+}
+
+// Create abre uma denúncia. Repetir a mesma denúncia enquanto ela está na
+// fila devolve a original sem contar para o limite.
+func (s *ReportService) Create(ctx context.Context, rep *model.Report) (*model.Report, bool, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.Create")
+	defer span.End()
+
+	if rep.ReporterID == rep.TargetUserID {
+		return nil, false, errors.New("não é possível denunciar a si mesmo")
+	}
+	if _, err := s.users.FindByUserID(ctx, rep.TargetUserID); err != nil {
+		return nil, false, errors.New("usuário denunciado não encontrado")
+	}
+
+	messageID := ""
+	if rep.Message != nil {
+		if err := s.attachStored(ctx, rep); err != nil {
+			return nil, false, err
+		}
+		messageID = rep.Message.ID
+	}
+	existing, err := s.repo.FindPending(ctx, rep.ReporterID, rep.TargetUserID, messageID)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, true, nil
+	}
+
+	n, err := s.repo.CountSince(ctx, rep.ReporterID, time.Now().Add(-time.Hour))
+	if err != nil {
+		return nil, false, err
+	}
+	if n >= int64(s.maxPerHour) {
+		return nil, false, ErrReportLimit
+	}
+
+	rep.Status = model.ReportOpen
+	if err := s.repo.Create(ctx, rep); err != nil {
+		return nil, false, err
+	}
+	return rep, false, nil
+}
+
+// attachStored troca a cópia enviada pelo denunciante pela mensagem do
+// histórico, que precisa ser visível para ele e ter vindo do denunciado.
+func (s *ReportService) attachStored(ctx context.Context, rep *model.Report) error {
+	id, err := primitive.ObjectIDFromHex(rep.Message.ID)
+	if err != nil {
+		return ErrReportedMessage
+	}
+	msgs, err := s.history.FindByIDs(ctx, rep.ReporterID, []primitive.ObjectID{id})
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 || msgs[0].From != rep.TargetUserID {
+		return ErrReportedMessage
+	}
+	rep.Message = &model.ReportedMessage{
+		ID:        rep.Message.ID,
+		Content:   msgs[0].Content,
+		Timestamp: msgs[0].CreatedAt.Unix(),
+	}
+	return nil
+}
+
+func (s *ReportService) List(ctx context.Context, f model.ReportFilter, page, limit int) ([]model.Report, int64, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.List")
+	defer span.End()
+
+	return s.repo.List(ctx, f, page, limit)
+}
+
+func (s *ReportService) Get(ctx context.Context, id string) (*model.Report, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.Get")
+	defer span.End()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("ID de denúncia inválido")
+	}
+	return s.repo.FindByID(ctx, oid)
+}
+
+// Assign entrega a denúncia a um moderador; uma denúncia aberta passa a
+// em revisão.
+func (s *ReportService) Assign(ctx context.Context, id, assigneeID string) (*model.Report, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.Assign")
+	defer span.End()
+
+	rep, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	upd := bson.M{"assigneeId": assigneeID}
+	if rep.Status == model.ReportOpen {
+		upd["status"] = model.ReportReviewing
+	}
+	return rep, s.repo.Update(ctx, rep.ID, upd)
+}
+
+// SetStatus move a denúncia na fila. O estado "actioned" só é alcançado por
+// Act, que aplica a punição junto.
+func (s *ReportService) SetStatus(ctx context.Context, id, status string) (*model.Report, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.SetStatus")
+	defer span.End()
+
+	switch status {
+	case model.ReportOpen, model.ReportReviewing, model.ReportDismissed:
+	case model.ReportActioned:
+		return nil, errors.New("use a ação de suspender ou banir para concluir a denúncia")
+	default:
+		return nil, errors.New("estado de denúncia inválido")
+	}
+
+	rep, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return rep, s.repo.Update(ctx, rep.ID, bson.M{"status": status})
+}
+
+func (s *ReportService) AddNote(ctx context.Context, id, authorID, text string) error {
+	ctx, span := tracing.Start(ctx, "ReportService.AddNote")
+	defer span.End()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("ID de denúncia inválido")
+	}
+	return s.repo.AddNote(ctx, oid, model.ReportNote{AuthorID: authorID, Text: text, CreatedAt: time.Now()})
+}
+
+// Act aplica a punição ao denunciado ("suspend" até until, ou "ban") e
+// conclui a denúncia. Retorna o usuário como estava antes da punição.
+func (s *ReportService) Act(ctx context.Context, id, moderatorID, action, reason string, until time.Time) (*model.Report, *model.User, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.Act", tracing.Attrs("wisp.report_action", action))
+	defer span.End()
+
+	rep, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var before *model.User
+	switch action {
+	case "suspend":
+		before, err = s.moderation.Suspend(ctx, rep.TargetUserID, reason, until)
+	case "ban":
+		before, err = s.moderation.Ban(ctx, rep.TargetUserID, reason)
+	default:
+		return nil, nil, errors.New("ação deve ser suspend ou ban")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upd := bson.M{"status": model.ReportActioned, "action": action}
+	if rep.AssigneeID == "" {
+		upd["assigneeId"] = moderatorID
+	}
+	if err := s.repo.Update(ctx, rep.ID, upd); err != nil {
+		return nil, nil, err
+	}
+	return rep, before, nil
+}
+
+// maxFlagNotes limita as notas automáticas de uma denúncia; depois disso os
+// disparos seguintes só ficam nas métricas dos filtros.
+const maxFlagNotes = 100
+
+// FlagMessage abre uma denúncia automática contra o remetente. Enquanto houver
+// uma pendente, novos disparos viram notas nela em vez de novas denúncias.
+func (s *ReportService) FlagMessage(ctx context.Context, msg *model.Message, reason string) error {
+	ctx, span := tracing.Start(ctx, "ReportService.FlagMessage")
+	defer span.End()
+
+	note := model.ReportNote{
+		AuthorID:  SystemReporter,
+		Text:      reason + " (mensagem " + msg.ID + " para " + msg.To + ")",
+		CreatedAt: time.Now(),
+	}
+	existing, err := s.repo.FindPending(ctx, SystemReporter, msg.From, "")
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		_, err := s.repo.AddNoteCapped(ctx, existing.ID, note, maxFlagNotes)
+		return err
+	}
+	return s.repo.Create(ctx, &model.Report{
+		ReporterID:   SystemReporter,
+		TargetUserID: msg.From,
+		Category:     "spam",
+		Description:  reason,
+		Status:       model.ReportOpen,
+		Notes:        []model.ReportNote{note},
+	})
+}
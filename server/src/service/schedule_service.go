@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrScheduledNotFound = errors.New("agendamento não encontrado")
+	ErrScheduledLocked   = errors.New("o agendamento já foi enviado, cancelado ou está sendo enviado")
+	ErrScheduleLimit     = errors.New("limite de mensagens agendadas atingido")
+	ErrScheduleRecipient = errors.New("o destino precisa ser você ou um contato seu")
+)
+
+// Prazo máximo de um agendamento e do lease de envio.
+const (
+	maxScheduleAhead = 365 * 24 * time.Hour
+	scheduleLease    = time.Minute
+)
+
+// ScheduleHub é o que o agendador precisa do hub: enviar, e saber se a
+// instância está sendo drenada para não tirar nada da fila nesse momento.
+type ScheduleHub interface {
+	Broadcaster
+	Draining() bool
+}
+
+// ScheduleService guarda as mensagens agendadas e, com Start, as envia na
+// hora pelo hub, com o remetente original. Várias instâncias podem rodar o
+// agendador ao mesmo tempo: cada envio é reservado por lease no MongoDB.
+// Como os webhooks de entrada, o envio acontece sem ninguém digitando, então
+// o destino precisa ser o próprio remetente ou um contato dele.
+type ScheduleService struct {
+	repo       *repository.ScheduledMessageRepo
+	users      *repository.UserRepo
+	contacts   *repository.ContactRepo
+	hub        ScheduleHub
+	maxPending int
+	poll       time.Duration
+}
+
+func NewScheduleService(repo *repository.ScheduledMessageRepo, users *repository.UserRepo, contacts *repository.ContactRepo, hub ScheduleHub, maxPending int) *ScheduleService {
+	return &ScheduleService{repo: repo, users: users, contacts: contacts, hub: hub, maxPending: maxPending, poll: time.Second}
+}
+
+func (s *ScheduleService) Create(ctx context.Context, from, to, content string, sendAt time.Time) (*model.ScheduledMessage, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleService.Create")
+	defer span.End()
+
+	if err := validSendAt(sendAt); err != nil {
+		return nil, err
+	}
+	if err := s.checkRecipient(ctx, from, to); err != nil {
+		return nil, err
+	}
+	m := &model.ScheduledMessage{From: from, To: to, Content: content, SendAt: sendAt}
+	ok, err := s.repo.Create(ctx, m, s.maxPending)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrScheduleLimit
+	}
+	return m, nil
+}
+
+func (s *ScheduleService) List(ctx context.Context, from, status string) ([]model.ScheduledMessage, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleService.List")
+	defer span.End()
+
+	return s.repo.ListByUser(ctx, from, status)
+}
+
+// Update troca destino, conteúdo ou horário de um agendamento pendente;
+// campos vazios ficam como estão.
+func (s *ScheduleService) Update(ctx context.Context, from, id, to, content string, sendAt *time.Time) (*model.ScheduledMessage, error) {
+	ctx, span := tracing.Start(ctx, "ScheduleService.Update")
+	defer span.End()
+
+	upd := bson.M{}
+	if to != "" {
+		if err := s.checkRecipient(ctx, from, to); err != nil {
+			return nil, err
+		}
+		upd["to"] = to
+	}
+	if content != "" {
+		upd["content"] = content
+	}
+	if sendAt != nil {
+		if err := validSendAt(*sendAt); err != nil {
+			return nil, err
+		}
+		upd["sendAt"] = *sendAt
+	}
+	if len(upd) == 0 {
+		return nil, errors.New("nenhum campo para atualizar")
+	}
+	return s.updatePending(ctx, from, id, upd)
+}
+
+func (s *ScheduleService) Cancel(ctx context.Context, from, id string) error {
+	ctx, span := tracing.Start(ctx, "ScheduleService.Cancel")
+	defer span.End()
+
+	_, err := s.updatePending(ctx, from, id, bson.M{"status": model.ScheduledCanceled})
+	return err
+}
+
+func (s *ScheduleService) updatePending(ctx context.Context, from, id string, upd bson.M) (*model.ScheduledMessage, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrScheduledNotFound
+	}
+	m, err := s.repo.UpdatePending(ctx, oid, from, upd)
+	if err != nil || m != nil {
+		return m, err
+	}
+	if cur, err := s.repo.FindByID(ctx, oid); err != nil || cur.From != from {
+		return nil, ErrScheduledNotFound
+	}
+	return nil, ErrScheduledLocked
+}
+
+// checkRecipient confere se o destino existe, não foi banido e é o próprio
+// remetente ou um contato dele.
+func (s *ScheduleService) checkRecipient(ctx context.Context, from, to string) error {
+	ok, err := reachable(ctx, s.users, s.contacts, from, to)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrScheduleRecipient
+	}
+	return nil
+}
+
+// Start roda o agendador até ctx ser cancelado.
+func (s *ScheduleService) Start(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			if s.hub.Draining() {
+				return
+			}
+			sent, err := s.sendNext(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Msg("Erro no agendador de mensagens")
+			}
+			if sent {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(s.poll):
+			}
+		}
+	}()
+}
+
+// sendNext envia o próximo agendamento vencido e informa se havia algum.
+func (s *ScheduleService) sendNext(ctx context.Context) (bool, error) {
+	m, err := s.repo.Claim(ctx, time.Now(), scheduleLease)
+	if err != nil || m == nil {
+		return false, err
+	}
+
+	ctx, span := tracing.Start(ctx, "ScheduleService.send", tracing.Attrs("wisp.from", m.From, "wisp.to", m.To))
+	defer span.End()
+
+	// O remetente pode ter sido removido ou bloqueado depois de agendar. Uma
+	// falha do banco não é definitiva: o lease vence e o envio é tentado de
+	// novo.
+	sender, err := s.users.FindByUserID(ctx, m.From)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		m.Status, m.Error = model.ScheduledFailed, "remetente não encontrado"
+	case err != nil:
+		return false, err
+	case sender.Blocked(time.Now()):
+		m.Status, m.Error = model.ScheduledFailed, "conta do remetente bloqueada"
+	default:
+		msg := &model.Message{
+			Type:      "message",
+			From:      m.From,
+			To:        m.To,
+			Content:   m.Content,
+			Timestamp: time.Now().Unix(),
+			ClientID:  m.ClientID(),
+			Bot:       sender.IsBot,
+		}
+		s.hub.Broadcast(ctx, msg)
+		now := time.Now()
+		m.Status, m.MessageID, m.SentAt = model.ScheduledSent, msg.ID, &now
+	}
+	return true, s.repo.Finish(ctx, m)
+}
+
+func validSendAt(t time.Time) error {
+	if !t.After(time.Now()) {
+		return errors.New("sendAt precisa estar no futuro")
+	}
+	if time.Until(t) > maxScheduleAhead {
+		return errors.New("sendAt pode estar no máximo um ano à frente")
+	}
+	return nil
+}
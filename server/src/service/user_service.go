@@ -8,6 +8,7 @@ import (
 
 	"wisp/src/model"
 	"wisp/src/repository"
+	"wisp/src/tracing"
 
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/crypto/bcrypt"
@@ -23,10 +24,15 @@ func NewUserService(r *repository.UserRepo) *UserService {
 }
 
 func (s *UserService) CheckAvailability(ctx context.Context, email, userID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CheckAvailability")
+	defer span.End()
+
 	return s.repo.IsAvailable(ctx, email, userID)
 }
 
 func (s *UserService) Register(ctx context.Context, req model.RegisterRequest) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Register")
+	defer span.End()
 
 	if err := s.validator.Struct(req); err != nil {
 		return nil, fmt.Errorf("validação falhou: %w", err)
@@ -59,21 +65,36 @@ func (s *UserService) Register(ctx context.Context, req model.RegisterRequest) (
 }
 
 func (s *UserService) GetByUserID(ctx context.Context, userID string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByUserID")
+	defer span.End()
+
 	return s.repo.FindByUserID(ctx, userID)
 }
 
 func (s *UserService) ListUsers(ctx context.Context, page, limit int, q, sortField string, desc bool) ([]model.User, int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListUsers")
+	defer span.End()
+
 	return s.repo.List(ctx, page, limit, q, sortField, desc)
 }
 
 func (s *UserService) GetUser(ctx context.Context, userID string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUser")
+	defer span.End()
+
 	return s.repo.FindByUserID(ctx, userID)
 }
 
 func (s *UserService) UpdateUser(ctx context.Context, userID string, upd map[string]any) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
 	return s.repo.UpdateByUserID(ctx, userID, upd)
 }
 
 func (s *UserService) DeleteUser(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
 	return s.repo.DeleteByUserID(ctx, userID)
 }
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"time"
+
+	"wisp/src/model"
+	"wisp/src/repository"
+	"wisp/src/tracing"
+	"wisp/src/webhook"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook não encontrado")
+	ErrWebhookLimit    = errors.New("limite de webhooks atingido")
+)
+
+// userEvents são os eventos que um usuário comum pode assinar; os demais só
+// em webhooks globais.
+var userEvents = []string{model.EventBotMessage, model.EventFriendRequestReceived}
+
+var allEvents = []string{model.EventBotMessage, model.EventFriendRequestReceived, model.EventUserRegistered, model.EventUserDeleted}
+
+// WebhookService cadastra webhooks e enfileira os eventos para o
+// webhook.Worker. Webhooks com ownerID vazio são globais.
+type WebhookService struct {
+	hooks      *repository.WebhookRepo
+	deliveries *repository.WebhookDeliveryRepo
+	enabled    bool
+	allowHTTP  bool
+	maxPerUser int
+}
+
+func NewWebhookService(hooks *repository.WebhookRepo, deliveries *repository.WebhookDeliveryRepo, enabled, allowHTTP bool, maxPerUser int) *WebhookService {
+	return &WebhookService{hooks: hooks, deliveries: deliveries, enabled: enabled, allowHTTP: allowHTTP, maxPerUser: maxPerUser}
+}
+
+// Create devolve o webhook e o segredo de assinatura, que só é mostrado
+// nesse momento.
+func (s *WebhookService) Create(ctx context.Context, ownerID, rawURL string, events []string) (*model.Webhook, string, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Create")
+	defer span.End()
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && !(s.allowHTTP && u.Scheme == "http")) {
+		return nil, "", errors.New("url de webhook inválida")
+	}
+	allowed := allEvents
+	if ownerID != "" {
+		allowed = userEvents
+	}
+	for _, ev := range events {
+		if !slices.Contains(allowed, ev) {
+			return nil, "", errors.New("evento não permitido: " + ev)
+		}
+	}
+	if ownerID != "" {
+		n, err := s.hooks.CountByOwner(ctx, ownerID)
+		if err != nil {
+			return nil, "", err
+		}
+		if n >= int64(s.maxPerUser) {
+			return nil, "", ErrWebhookLimit
+		}
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	hook := &model.Webhook{
+		OwnerID: ownerID,
+		URL:     u.String(),
+		Secret:  secret,
+		Events:  slices.Compact(slices.Sorted(slices.Values(events))),
+	}
+	if err := s.hooks.Create(ctx, hook); err != nil {
+		return nil, "", err
+	}
+	return hook, hook.Secret, nil
+}
+
+func (s *WebhookService) List(ctx context.Context, ownerID string) ([]model.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.List")
+	defer span.End()
+
+	return s.hooks.ListByOwner(ctx, ownerID)
+}
+
+func (s *WebhookService) Delete(ctx context.Context, ownerID, id string) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.Delete")
+	defer span.End()
+
+	hook, err := s.owned(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.hooks.Delete(ctx, hook.ID); err != nil {
+		return err
+	}
+	return s.deliveries.DeleteByWebhooks(ctx, []primitive.ObjectID{hook.ID})
+}
+
+// DeleteOwner apaga os webhooks de um usuário removido.
+func (s *WebhookService) DeleteOwner(ctx context.Context, ownerID string) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteOwner")
+	defer span.End()
+
+	ids, err := s.hooks.DeleteByOwner(ctx, ownerID)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return s.deliveries.DeleteByWebhooks(ctx, ids)
+}
+
+func (s *WebhookService) Deliveries(ctx context.Context, ownerID, id, status string, page, limit int) ([]model.WebhookDelivery, int64, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Deliveries")
+	defer span.End()
+
+	hook, err := s.owned(ctx, ownerID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.deliveries.List(ctx, hook.ID, status, page, limit)
+}
+
+// DeadLetters lista as entregas que esgotaram as tentativas, de todos os
+// webhooks.
+func (s *WebhookService) DeadLetters(ctx context.Context, page, limit int) ([]model.WebhookDelivery, int64, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeadLetters")
+	defer span.End()
+
+	return s.deliveries.List(ctx, primitive.NilObjectID, model.DeliveryDead, page, limit)
+}
+
+// Redeliver põe de volta na fila uma entrega morta ou já entregue. Com
+// anyOwner, vale para entregas de qualquer webhook (admin).
+func (s *WebhookService) Redeliver(ctx context.Context, ownerID, deliveryID string, anyOwner bool) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+
+	oid, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return ErrWebhookNotFound
+	}
+	d, err := s.deliveries.FindByID(ctx, oid)
+	if err != nil || (!anyOwner && d.OwnerID != ownerID) {
+		return ErrWebhookNotFound
+	}
+	if d.Status != model.DeliveryDead && d.Status != model.DeliveryDelivered {
+		return errors.New("entrega ainda está na fila")
+	}
+	return s.deliveries.Requeue(ctx, oid)
+}
+
+// Emit enfileira o evento para os webhooks globais e os de userID que o
+// assinam. Como o registro de auditoria, uma falha aqui não desfaz a ação
+// que gerou o evento; ela vai para o log.
+func (s *WebhookService) Emit(ctx context.Context, event, userID string, data any) {
+	if !s.enabled {
+		return
+	}
+	ctx, span := tracing.Start(ctx, "WebhookService.Emit", tracing.Attrs("wisp.webhook_event", event))
+	defer span.End()
+
+	hooks, err := s.hooks.Subscribed(ctx, event, userID)
+	if err != nil {
+		tracing.Fail(span, err)
+		zerolog.Ctx(ctx).Error().Err(err).Str("event", event).Msg("Erro ao buscar webhooks")
+		return
+	}
+
+	now := time.Now()
+	for _, hook := range hooks {
+		id := primitive.NewObjectID()
+		payload, err := json.Marshal(map[string]any{
+			"id":        id.Hex(),
+			"event":     event,
+			"createdAt": now,
+			"data":      data,
+		})
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("event", event).Msg("Erro ao serializar evento de webhook")
+			return
+		}
+		err = s.deliveries.Insert(ctx, &model.WebhookDelivery{
+			ID:            id,
+			WebhookID:     hook.ID,
+			OwnerID:       hook.OwnerID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        model.DeliveryPending,
+			NextAttemptAt: now,
+			Log:           []model.WebhookAttempt{},
+			CreatedAt:     now,
+		})
+		if err != nil {
+			tracing.Fail(span, err)
+			zerolog.Ctx(ctx).Error().Err(err).Str("event", event).Str("webhookId", hook.ID.Hex()).Msg("Erro ao enfileirar webhook")
+		}
+	}
+}
+
+func (s *WebhookService) owned(ctx context.Context, ownerID, id string) (*model.Webhook, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	hook, err := s.hooks.FindByID(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil || hook.OwnerID != ownerID {
+		return nil, ErrWebhookNotFound
+	}
+	return hook, nil
+}
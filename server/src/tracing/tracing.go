@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"wisp/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "wisp"
+
+var memory *tracetest.InMemoryExporter
+
+// Init configura o provider global conforme tracing.exporter: "otlp" envia
+// para o coletor via OTLP/HTTP, "memory" guarda os spans em memória (útil em
+// testes) e vazio desliga o tracing. A função devolvida faz o flush final.
+func Init(cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Tracing.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint)}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	case "memory":
+		memory = tracetest.NewInMemoryExporter()
+		exporter = memory
+	default:
+		return nil, fmt.Errorf("exporter de tracing desconhecido: %s", cfg.Tracing.Exporter)
+	}
+
+	res := resource.NewSchemaless(
+		semconv.ServiceName("wisp"),
+		semconv.DeploymentEnvironment(cfg.App.Env),
+	)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Memory devolve o exporter em memória quando tracing.exporter é "memory".
+func Memory() *tracetest.InMemoryExporter {
+	return memory
+}
+
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// Attrs é um atalho para trace.WithAttributes com atributos string.
+func Attrs(kv ...string) trace.SpanStartOption {
+	attrs := make([]attribute.KeyValue, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		attrs = append(attrs, attribute.String(kv[i], kv[i+1]))
+	}
+	return trace.WithAttributes(attrs...)
+}
+
+// LinkTo cria um link para o span presente em ctx, se houver.
+func LinkTo(ctx context.Context) trace.SpanStartOption {
+	return trace.WithLinks(trace.LinkFromContext(ctx))
+}
+
+// Fail marca o span com o erro e devolve o próprio erro.
+func Fail(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// Inject serializa o contexto de trace para atravessar o bus.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+func Extract(carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(carrier))
+}
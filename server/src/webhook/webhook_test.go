@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeHooks struct{ hook *model.Webhook }
+
+func (f *fakeHooks) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Webhook, error) {
+	if f.hook == nil || f.hook.ID != id {
+		return nil, nil
+	}
+	return f.hook, nil
+}
+
+// fakeDeliveries guarda uma entrega só e a devolve no Claim enquanto ela
+// não foi entregue nem morreu, sem esperar o backoff.
+type fakeDeliveries struct {
+	mu       sync.Mutex
+	delivery model.WebhookDelivery
+}
+
+func (f *fakeDeliveries) Claim(ctx context.Context, now time.Time, lease time.Duration) (*model.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.delivery.Status == model.DeliveryDelivered || f.delivery.Status == model.DeliveryDead {
+		return nil, nil
+	}
+	d := f.delivery
+	return &d, nil
+}
+
+func (f *fakeDeliveries) Finish(ctx context.Context, d *model.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delivery = *d
+	return nil
+}
+
+// receiver responde com os status de statuses em ordem e confere a
+// assinatura de cada requisição.
+type receiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	calls    int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if err := Verify(r.secret, req.Header.Get(SignatureHeader), body, time.Minute); err != nil {
+		r.t.Errorf("assinatura recusada pelo receptor: %v", err)
+	}
+
+	r.mu.Lock()
+	status := r.statuses[min(r.calls, len(r.statuses)-1)]
+	r.calls++
+	r.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func newTestWorker(t *testing.T, statuses []int, maxAttempts int) (*Worker, *fakeDeliveries, *receiver) {
+	t.Helper()
+
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recv := &receiver{t: t, secret: secret, statuses: statuses}
+	srv := httptest.NewServer(recv)
+	t.Cleanup(srv.Close)
+
+	hook := &model.Webhook{ID: primitive.NewObjectID(), URL: srv.URL, Secret: recv.secret}
+	deliveries := &fakeDeliveries{delivery: model.WebhookDelivery{
+		ID:        primitive.NewObjectID(),
+		WebhookID: hook.ID,
+		Event:     model.EventUserDeleted,
+		Payload:   `{"userId":"abc1234"}`,
+		Status:    model.DeliveryPending,
+	}}
+	w := NewWorker(&fakeHooks{hook: hook}, deliveries, NewClient(5*time.Second, true), maxAttempts, time.Second, time.Minute)
+	return w, deliveries, recv
+}
+
+// drain processa a fila até ela esvaziar.
+func drain(t *testing.T, w *Worker) {
+	t.Helper()
+
+	for range 10 {
+		done, err := w.Process(context.Background())
+		if err != nil {
+			t.Fatalf("Process: %v", err)
+		}
+		if !done {
+			return
+		}
+	}
+	t.Fatal("a fila não esvaziou")
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":"user.deleted"}`)
+	now := time.Now()
+	header := Sign("segredo", now, body)
+
+	if err := Verify("segredo", header, body, time.Minute); err != nil {
+		t.Fatalf("assinatura válida recusada: %v", err)
+	}
+
+	cases := map[string]struct {
+		secret, header string
+		body           []byte
+	}{
+		"segredo errado": {"outro", header, body},
+		"corpo alterado": {"segredo", header, []byte(`{"event":"user.created"}`)},
+		"fora do prazo":  {"segredo", Sign("segredo", now.Add(-time.Hour), body), body},
+		"sem assinatura": {"segredo", "", body},
+		"sem timestamp":  {"segredo", "v1=00", body},
+		"cabeçalho lixo": {"segredo", "t=abc,v1=00", body},
+	}
+	for name, c := range cases {
+		if err := Verify(c.secret, c.header, c.body, time.Minute); !errors.Is(err, ErrSignature) {
+			t.Errorf("%s: esperava ErrSignature, veio %v", name, err)
+		}
+	}
+}
+
+func TestAllowedAddr(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700::1111":      true,
+		"0.0.0.0":              false,
+		"0.1.2.3":              false,
+		"10.1.2.3":             false,
+		"100.64.0.1":           false,
+		"100.127.255.254":      false,
+		"127.0.0.1":            false,
+		"169.254.169.254":      false,
+		"172.16.0.1":           false,
+		"192.0.0.8":            false,
+		"192.168.1.1":          false,
+		"198.18.0.1":           false,
+		"224.0.0.1":            false,
+		"255.255.255.255":      false,
+		"::":                   false,
+		"::1":                  false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.216.34": true,
+		"64:ff9b::a00:1":       false,
+		"2002:a00:1::1":        false,
+		"fd00::1":              false,
+		"fe80::1%eth0":         false,
+		"ff02::1":              false,
+	}
+	for addr, want := range cases {
+		if got := allowedAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("%s: permitido = %v, esperava %v", addr, got, want)
+		}
+	}
+}
+
+func TestRetryAfterServerError(t *testing.T) {
+	w, deliveries, recv := newTestWorker(t, []int{http.StatusInternalServerError, http.StatusOK}, 5)
+
+	if _, err := w.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	d := deliveries.delivery
+	if d.Status != model.DeliveryRetrying || d.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("depois do 500: status %q, código %d", d.Status, d.LastStatusCode)
+	}
+	if wait := time.Until(d.NextAttemptAt); wait < 900*time.Millisecond || wait > 1200*time.Millisecond {
+		t.Fatalf("primeiro reenvio em %s, esperava o atraso base", wait)
+	}
+
+	drain(t, w)
+	d = deliveries.delivery
+	if d.Status != model.DeliveryDelivered || d.Attempts != 2 || d.DeliveredAt == nil {
+		t.Fatalf("depois do reenvio: status %q, tentativas %d", d.Status, d.Attempts)
+	}
+	if len(d.Log) != 2 || d.Log[0].StatusCode != http.StatusInternalServerError || d.Log[1].StatusCode != http.StatusOK {
+		t.Fatalf("log de tentativas inesperado: %+v", d.Log)
+	}
+	if recv.calls != 2 {
+		t.Fatalf("receptor chamado %d vezes", recv.calls)
+	}
+}
+
+func TestDeadAfterMaxAttempts(t *testing.T) {
+	w, deliveries, recv := newTestWorker(t, []int{http.StatusBadGateway}, 3)
+
+	drain(t, w)
+	d := deliveries.delivery
+	if d.Status != model.DeliveryDead || d.Attempts != 3 {
+		t.Fatalf("status %q, tentativas %d", d.Status, d.Attempts)
+	}
+	if d.LastError == "" || d.LastStatusCode != http.StatusBadGateway {
+		t.Fatalf("erro final não registrado: %q, código %d", d.LastError, d.LastStatusCode)
+	}
+	if recv.calls != 3 {
+		t.Fatalf("receptor chamado %d vezes", recv.calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	w := NewWorker(nil, nil, nil, 10, time.Second, 8*time.Second)
+	for attempts, base := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 9: 8 * time.Second} {
+		d := w.backoff(attempts)
+		if d < base || d > base+base/10 {
+			t.Errorf("backoff(%d) = %s, esperava entre %s e %s", attempts, d, base, base+base/10)
+		}
+	}
+}
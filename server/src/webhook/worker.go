@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hooks devolve o webhook de uma entrega, ou nil se ele foi removido.
+type Hooks interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.Webhook, error)
+}
+
+// Deliveries é a fila de entregas. Claim reserva a próxima entrega vencida
+// por lease, para que várias instâncias dividam a fila sem entregar duas
+// vezes; devolve nil quando não há nada a fazer.
+type Deliveries interface {
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (*model.WebhookDelivery, error)
+	Finish(ctx context.Context, d *model.WebhookDelivery) error
+}
+
+// maxLog limita o histórico de tentativas guardado em cada entrega.
+const maxLog = 20
+
+// Worker consome a fila de entregas. Uma falha reagenda a entrega com
+// backoff exponencial; depois de MaxAttempts ela vai para a lista de mortas.
+type Worker struct {
+	hooks       Hooks
+	deliveries  Deliveries
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	poll        time.Duration
+}
+
+func NewWorker(hooks Hooks, deliveries Deliveries, client *http.Client, maxAttempts int, baseDelay, maxDelay time.Duration) *Worker {
+	return &Worker{
+		hooks:       hooks,
+		deliveries:  deliveries,
+		client:      client,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+		poll:        time.Second,
+	}
+}
+
+// Run sobe n consumidores até ctx ser cancelado.
+func (w *Worker) Run(ctx context.Context, n int) {
+	for range n {
+		go func() {
+			for ctx.Err() == nil {
+				done, err := w.Process(ctx)
+				if err != nil && ctx.Err() == nil {
+					log.Error().Err(err).Msg("Erro na fila de webhooks")
+				}
+				if done {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+				case <-time.After(w.poll):
+				}
+			}
+		}()
+	}
+}
+
+// Process faz uma tentativa da próxima entrega vencida e informa se havia
+// alguma.
+func (w *Worker) Process(ctx context.Context) (bool, error) {
+	lease := w.client.Timeout + 30*time.Second
+	d, err := w.deliveries.Claim(ctx, time.Now(), lease)
+	if err != nil || d == nil {
+		return false, err
+	}
+
+	ctx, span := tracing.Start(ctx, "webhook.deliver", tracing.Attrs("wisp.webhook_event", d.Event, "wisp.delivery_id", d.ID.Hex()))
+	defer span.End()
+
+	hook, err := w.hooks.FindByID(ctx, d.WebhookID)
+	if err != nil {
+		return true, err
+	}
+
+	start := time.Now()
+	attempt := model.WebhookAttempt{At: start}
+	if hook == nil {
+		err = errors.New("webhook removido")
+	} else {
+		attempt.StatusCode, err = send(ctx, w.client, hook.URL, hook.Secret, d.Event, d.ID.Hex(), []byte(d.Payload))
+	}
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	d.Attempts++
+	d.LastStatusCode = attempt.StatusCode
+	d.LastError = ""
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		d.Status = model.DeliveryDelivered
+		d.DeliveredAt = &now
+	case hook == nil || d.Attempts >= w.maxAttempts:
+		attempt.Error = err.Error()
+		d.Status = model.DeliveryDead
+		d.LastError = attempt.Error
+		tracing.Fail(span, err)
+	default:
+		attempt.Error = err.Error()
+		d.Status = model.DeliveryRetrying
+		d.LastError = attempt.Error
+		d.NextAttemptAt = time.Now().Add(w.backoff(d.Attempts))
+	}
+	metrics.WebhookDeliveries.WithLabelValues(d.Event, d.Status).Inc()
+
+	d.Log = append(d.Log, attempt)
+	if len(d.Log) > maxLog {
+		d.Log = d.Log[len(d.Log)-maxLog:]
+	}
+	return true, w.deliveries.Finish(ctx, d)
+}
+
+// backoff dobra a espera a cada tentativa, até maxDelay, com até 10% de
+// jitter para espalhar os reenvios de um receptor que voltou do ar.
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.baseDelay
+	for i := 1; i < attempts && d < w.maxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, w.maxDelay)
+	return d + time.Duration(rand.Int63n(int64(d)/10+1))
+}
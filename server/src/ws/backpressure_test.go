@@ -0,0 +1,84 @@
+package ws
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/ws/wstest"
+)
+
+// TestFullBufferSpillsToPending enche o buffer de um dispositivo que não lê
+// e confere que o excedente vai para a fila pendente dele, sem desconectar
+// antes do prazo, e que ele recebe tudo ao reconectar: o desviado e, da
+// sessão descartada, o que ficou no buffer sem confirmação.
+func TestFullBufferSpillsToPending(t *testing.T) {
+	store := wstest.NewStore()
+	h := NewHub(wstest.Config("spill"), store, wstest.NewReceipts(), bus.NewLocal(), bus.NewMemoryPresence())
+	h.Run()
+
+	bob := dial(t, h, "bob0001", "phone", "", 0, false)
+	const total = 300
+	for i := range total {
+		h.Broadcast(context.Background(), message("alice01", "bob0001", fmt.Sprint(i)))
+	}
+
+	spilled := total - cap(bob.Send)
+	waitFor(t, func() bool { return h.Stats().FramesSpilled == uint64(spilled) })
+	pending, _ := store.GetPendingFor(context.Background(), "bob0001", "phone")
+	if len(pending) != spilled {
+		t.Fatalf("%d mensagens na fila pendente, esperava %d", len(pending), spilled)
+	}
+	for _, pm := range pending {
+		if pm.DeviceID != "phone" || pm.MessageID == "" {
+			t.Fatalf("cópia desviada sem dispositivo ou id original: %+v", pm)
+		}
+	}
+	if other, _ := store.GetPendingFor(context.Background(), "bob0001", "tablet"); len(other) != 0 {
+		t.Fatalf("cópia desviada visível para outro dispositivo: %d", len(other))
+	}
+	if s := h.Stats(); s.SlowDisconnects != 0 || s.FramesDropped != 0 {
+		t.Fatalf("stats inesperadas: %+v", s)
+	}
+
+	bob.disconnect(t)
+	again := dial(t, h, "bob0001", "phone", "", 0, true)
+	waitFor(t, func() bool { return len(again.ofType("message")) == total })
+	for i, m := range again.ofType("message") {
+		if m.Content != fmt.Sprint(i) {
+			t.Fatalf("mensagem %d fora de ordem: %q", i, m.Content)
+		}
+	}
+}
+
+func TestSaturatedClientClosedWith4008(t *testing.T) {
+	cfg := wstest.Config("slow")
+	cfg.Hub.SlowConsumerTimeout = time.Millisecond
+	h := NewHub(cfg, wstest.NewStore(), wstest.NewReceipts(), bus.NewLocal(), bus.NewMemoryPresence())
+	h.Run()
+
+	bob := dial(t, h, "bob0001", "phone", "", 0, false)
+	for i := range cap(bob.Send) + 1 {
+		h.Broadcast(context.Background(), message("alice01", "bob0001", fmt.Sprint(i)))
+	}
+	waitFor(t, func() bool { return h.Stats().FramesSpilled == 1 })
+	time.Sleep(5 * time.Millisecond)
+	h.Broadcast(context.Background(), message("alice01", "bob0001", "mais uma"))
+
+	select {
+	case <-bob.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cliente saturado não foi desconectado")
+	}
+	bob.Client.mu.Lock()
+	frame := bob.closeFrame
+	bob.Client.mu.Unlock()
+	if len(frame) < 2 || binary.BigEndian.Uint16(frame) != CloseSlowConsumer {
+		t.Fatalf("frame de fechamento %q, esperava o código %d", frame, CloseSlowConsumer)
+	}
+	if n := h.Stats().SlowDisconnects; n != 1 {
+		t.Fatalf("%d desconexões por lentidão", n)
+	}
+}
@@ -0,0 +1,69 @@
+package ws
+
+import (
+	"context"
+	"time"
+	"wisp/src/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+const tokenSaveInterval = time.Second
+
+// WatchPending entrega aos clientes locais as mensagens que outras
+// instâncias gravaram na fila pendente. É a alternativa ao bus para rodar
+// várias réplicas usando só o MongoDB: quem não acha o destinatário grava a
+// mensagem e a instância onde ele está conectado a recebe pelo change stream.
+// Todas as inserções chegam a todas as instâncias e o filtro pelos usuários
+// locais é feito aqui, porque o conjunto muda a cada conexão. A presença
+// fica no MongoDB, para quem grava a mensagem não mandar push a quem está
+// conectado em outra instância. Frames que não são mensagens (confirmações e
+// ACKs) não atravessam instâncias nesse modo.
+func (h *Hub) WatchPending(ctx context.Context, watcher PendingWatcher, tokens TokenStore) {
+	for ctx.Err() == nil {
+		token, err := tokens.Load(ctx, h.node)
+		if err != nil {
+			log.Error().Err(err).Msg("Erro ao carregar token do change stream")
+		}
+
+		var lastSaved time.Time
+		var last []byte
+		err = watcher.WatchInserts(ctx, token, func(pm *model.PendingMessage, tok []byte) {
+			h.pushStored(pm)
+
+			last = tok
+			if time.Since(lastSaved) >= tokenSaveInterval {
+				h.saveStreamToken(tokens, tok)
+				lastSaved = time.Now()
+			}
+		})
+		if last != nil {
+			h.saveStreamToken(tokens, last)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		log.Error().Err(err).Msg("Change stream de mensagens pendentes interrompido")
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func (h *Hub) saveStreamToken(tokens TokenStore, token []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := tokens.Save(ctx, h.node, token); err != nil {
+		log.Error().Err(err).Msg("Erro ao salvar token do change stream")
+	}
+}
+
+// pushStored entrega a mensagem gravada se o destinatário estiver conectado
+// aqui; as dos outros usuários nem entram na fila do shard.
+func (h *Hub) pushStored(pm *model.PendingMessage) {
+	s := h.shardFor(pm.To)
+	if len(s.devices(pm.To)) == 0 {
+		return
+	}
+	s.deliver <- &delivery{message: pm.Message(), stored: true, deviceID: pm.DeviceID}
+}
@@ -0,0 +1,111 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/model"
+	"wisp/src/ws/wstest"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sharedStore é a fila pendente vista por todas as instâncias, com um change
+// stream que entrega cada inserção a todos os watchers, cada um na sua
+// goroutine, como o do MongoDB.
+type sharedStore struct {
+	*wstest.Store
+	mu       sync.Mutex
+	watchers []chan *model.PendingMessage
+}
+
+func (s *sharedStore) Insert(ctx context.Context, pm *model.PendingMessage) (primitive.ObjectID, error) {
+	id, err := s.Store.Insert(ctx, pm)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range s.watchers {
+		ch <- pm
+	}
+	return id, err
+}
+
+func (s *sharedStore) WatchInserts(ctx context.Context, resumeAfter []byte, fn func(pm *model.PendingMessage, token []byte)) error {
+	ch := make(chan *model.PendingMessage, 1024)
+	s.mu.Lock()
+	s.watchers = append(s.watchers, ch)
+	s.mu.Unlock()
+
+	for {
+		select {
+		case pm := <-ch:
+			fn(pm, nil)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+type noTokens struct{}
+
+func (noTokens) Load(ctx context.Context, node string) ([]byte, error) { return nil, nil }
+func (noTokens) Save(ctx context.Context, node string, token []byte) error {
+	return nil
+}
+
+type countingNotifier struct{ to sync.Map }
+
+func (n *countingNotifier) MessageQueued(ctx context.Context, msg *model.Message) {
+	v, _ := n.to.LoadOrStore(msg.To, new(atomic.Int64))
+	v.(*atomic.Int64).Add(1)
+}
+
+func (n *countingNotifier) count(userID string) int64 {
+	if v, ok := n.to.Load(userID); ok {
+		return v.(*atomic.Int64).Load()
+	}
+	return 0
+}
+
+func TestChangeStreamDeliversOnlyToOwnUsers(t *testing.T) {
+	store := &sharedStore{Store: wstest.NewStore()}
+	presence := bus.NewMemoryPresence()
+	notifier := &countingNotifier{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	hubs := make([]*Hub, 2)
+	for i, node := range []string{"cs-a", "cs-b"} {
+		cfg := wstest.Config(node)
+		cfg.Cluster.Bus = "changestream"
+		hubs[i] = NewHub(cfg, store, wstest.NewReceipts(), bus.NewLocal(), presence)
+		hubs[i].SetNotifier(notifier)
+		hubs[i].Run()
+		go hubs[i].WatchPending(ctx, store, noTokens{})
+	}
+	a, b := hubs[0], hubs[1]
+
+	var sender, recipient atomic.Int64
+	connect(t, a, &sender, "alice01")
+	connect(t, b, &recipient, "bob0001")
+	waitFor(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		devices, _ := presence.Lookup(ctx, "bob0001")
+		return len(store.watchers) == 2 && devices["test"] == "cs-b"
+	})
+
+	for _, to := range []string{"bob0001", "carol01"} {
+		a.Broadcast(ctx, &model.Message{Type: "message", From: "alice01", To: to, Content: "oi", Timestamp: time.Now().Unix()})
+	}
+
+	waitFor(t, func() bool { return recipient.Load() == 1 && notifier.count("carol01") == 1 })
+	if n := notifier.count("bob0001"); n != 0 {
+		t.Fatalf("push para usuário conectado em outra instância: %d", n)
+	}
+	if n := sender.Load(); n != 0 {
+		t.Fatalf("mensagem de outro usuário entregue ao remetente: %d", n)
+	}
+}
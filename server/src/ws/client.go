@@ -0,0 +1,283 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+	"wisp/src/model"
+	"wisp/src/ratelimit"
+	"wisp/src/tracing"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+// Código de fechamento enviado a clientes que não consomem o buffer a tempo.
+const CloseSlowConsumer = 4008
+
+// Código de fechamento para clientes que insistem acima do limite de frames.
+const CloseRateLimited = 4029
+
+// Frames seguidos descartados pelo limite antes de a conexão ser fechada.
+const maxDroppedFrames = 100
+
+// Quanto esperar o cliente responder ao frame de fechamento antes de
+// derrubar a conexão.
+const closeTimeout = 5 * time.Second
+
+type Client struct {
+	Hub        *Hub
+	UserID     string
+	DeviceID   string
+	SessionID  string
+	Bot        bool
+	LastSeq    uint64
+	Conn       *websocket.Conn
+	Send       chan []byte
+	mu         sync.Mutex
+	session    *deviceSession
+	done       chan struct{}
+	stopped    chan struct{}
+	readDone   chan struct{}
+	closeOnce  sync.Once
+	closeFrame []byte
+	fullSince  time.Time
+	log        zerolog.Logger
+}
+
+// NewClient cria o cliente de uma conexão. logger deve trazer os campos de
+// correlação da requisição que abriu o WebSocket; o deviceId é acrescentado
+// aqui.
+func NewClient(hub *Hub, userID, deviceID string, conn *websocket.Conn, logger zerolog.Logger) *Client {
+	return &Client{
+		Hub:      hub,
+		UserID:   userID,
+		DeviceID: deviceID,
+		Conn:     conn,
+		Send:     make(chan []byte, 256),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		readDone: make(chan struct{}),
+		log:      logger.With().Str("deviceId", deviceID).Logger(),
+	}
+}
+
+func (c *Client) push(frame []byte) bool {
+	select {
+	case c.Send <- frame:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+func (c *Client) close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// closeWith encerra a conexão enviando o código e o motivo informados no
+// frame de fechamento.
+func (c *Client) closeWith(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closeFrame = websocket.FormatCloseMessage(code, reason)
+		c.mu.Unlock()
+		close(c.done)
+	})
+}
+
+// markFull registra que o buffer estava cheio e informa se isso já dura mais
+// que timeout.
+func (c *Client) markFull(timeout time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.fullSince.IsZero() {
+		c.fullSince = now
+	}
+	return now.Sub(c.fullSince) >= timeout
+}
+
+func (c *Client) markDrained() {
+	c.mu.Lock()
+	c.fullSince = time.Time{}
+	c.mu.Unlock()
+}
+
+func (c *Client) SendMessage(msg []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Conn != nil {
+		c.Conn.WriteMessage(websocket.TextMessage, msg)
+	}
+}
+
+func (c *Client) ReadPump() {
+	defer func() {
+		c.Hub.Unregister(c)
+		c.Conn.Close()
+		close(c.readDone)
+	}()
+
+	c.Conn.SetReadLimit(512 * 1024)
+	c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	c.Conn.SetPongHandler(func(string) error {
+		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		return nil
+	})
+
+	var limiter *ratelimit.Bucket
+	if c.Hub.frameRate.Limit > 0 {
+		limiter = ratelimit.NewBucket(c.Hub.frameRate)
+	}
+	dropped := 0
+	closing := false
+	var lastNotice time.Time
+
+	for {
+		_, message, err := c.Conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				c.log.Error().Err(err).Msg("Erro de conexão WebSocket")
+			}
+			break
+		}
+
+		// Depois do fechamento por limite, os frames são lidos e descartados
+		// até o cliente responder: fechar o socket com dados por ler faz o
+		// TCP mandar RST, e o cliente perde o código de fechamento.
+		if closing {
+			continue
+		}
+		if now := time.Now(); limiter != nil && !limiter.Allow(now) {
+			if dropped++; dropped >= maxDroppedFrames {
+				c.log.Warn().Int("dropped", dropped).Msg("Conexão fechada por exceder o limite de frames")
+				c.closeWith(CloseRateLimited, "rate limited")
+				c.Conn.SetReadDeadline(now.Add(closeTimeout))
+				closing = true
+				continue
+			}
+			if now.Sub(lastNotice) >= time.Second {
+				lastNotice = now
+				c.notifyRateLimited()
+			}
+			continue
+		}
+		dropped = 0
+
+		var data map[string]any
+		if err := json.Unmarshal(message, &data); err != nil {
+			c.log.Error().Err(err).Msg("Formato de mensagem inválido")
+			continue
+		}
+
+		if msgType, ok := data["type"].(string); ok {
+			switch msgType {
+			case "message":
+				var msg model.Message
+				if err := json.Unmarshal(message, &msg); err == nil {
+					if msg.From != c.UserID {
+						c.log.Warn().Str("claimed", msg.From).Str("actual", c.UserID).Msg("Tentativa de envio com ID falsificado")
+						continue
+					}
+					if msg.Timestamp == 0 {
+						msg.Timestamp = time.Now().Unix()
+					}
+					msg.Bot = c.Bot
+					msg.ExpiresAt, msg.Event = 0, nil
+					ctx, span := tracing.Start(context.Background(), "ws.receive", tracing.Attrs(
+						"wisp.from", msg.From,
+						"wisp.to", msg.To,
+						"wisp.device_id", c.DeviceID,
+					))
+					c.Hub.Broadcast(ctx, &msg)
+					span.End()
+				}
+			case "ack":
+				var ack model.Ack
+				if err := json.Unmarshal(message, &ack); err == nil {
+					if ack.To != c.UserID {
+						continue
+					}
+					c.Hub.ProcessMessageAck(&ack, c.DeviceID)
+				}
+			}
+		}
+	}
+}
+
+// notifyRateLimited avisa o cliente de que frames foram descartados. O aviso
+// não passa pelo log da sessão e é perdido se o buffer estiver cheio.
+func (c *Client) notifyRateLimited() {
+	frame, _ := json.Marshal(map[string]any{
+		"type":    "rate_limited",
+		"retryIn": int64(time.Second / time.Millisecond),
+	})
+	select {
+	case c.Send <- frame:
+	default:
+	}
+}
+
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(54 * time.Second)
+	stopped := false
+	stop := func() {
+		if !stopped {
+			stopped = true
+			close(c.stopped)
+		}
+	}
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+		stop()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			c.mu.Lock()
+			closeFrame := c.closeFrame
+			c.mu.Unlock()
+
+			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.Conn.WriteMessage(websocket.CloseMessage, closeFrame)
+
+			// Nada mais será escrito; a conexão fica aberta até o cliente
+			// responder ao fechamento, para o frame não se perder.
+			stop()
+			select {
+			case <-c.readDone:
+			case <-time.After(closeTimeout):
+			}
+			return
+		case message := <-c.Send:
+			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+
+			w, err := c.Conn.NextWriter(websocket.TextMessage)
+			if err != nil {
+				return
+			}
+			w.Write(message)
+
+			n := len(c.Send)
+			for range n {
+				w.Write([]byte{'\n'})
+				w.Write(<-c.Send)
+			}
+
+			if err := w.Close(); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
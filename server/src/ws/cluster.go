@@ -0,0 +1,156 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/model"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog/log"
+)
+
+const presenceHeartbeat = 30 * time.Second
+
+func (h *Hub) Node() string {
+	return h.node
+}
+
+// remoteNodes devolve as outras instâncias onde o usuário tem dispositivos
+// conectados, segundo o registro de presença, para onde o bus deve levar os
+// frames dele.
+func (h *Hub) remoteNodes(userID string) []string {
+	if !h.relay {
+		return nil
+	}
+	return h.otherNodes(userID)
+}
+
+// onlineElsewhere diz se o usuário está conectado em outra instância que vai
+// receber a mensagem pelo change stream. Com bus, quem está em outra
+// instância recebe pelo bus e a mensagem nem chega à fila pendente.
+func (h *Hub) onlineElsewhere(userID string) bool {
+	return !h.relay && len(h.otherNodes(userID)) > 0
+}
+
+func (h *Hub) otherNodes(userID string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	devices, err := h.presence.Lookup(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userId", userID).Msg("Erro ao consultar presença")
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var nodes []string
+	for _, node := range devices {
+		if node == h.node {
+			continue
+		}
+		if _, ok := seen[node]; !ok {
+			seen[node] = struct{}{}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (h *Hub) publish(node string, env *bus.Envelope) bool {
+	env.Origin = h.node
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Error().Err(err).Msg("Erro ao serializar envelope")
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := h.bus.Publish(ctx, node, data); err != nil {
+		log.Error().Err(err).Str("node", node).Msg("Erro ao publicar no bus")
+		return false
+	}
+	return true
+}
+
+// forwardMessage repassa a mensagem para as instâncias onde o destinatário
+// está conectado. Retorna true se ao menos uma delas recebeu; nesse caso a
+// fila pendente fica por conta de quem recebeu.
+func (h *Hub) forwardMessage(ctx context.Context, message *model.Message) bool {
+	forwarded := false
+	for _, node := range h.remoteNodes(message.To) {
+		env := &bus.Envelope{
+			Kind:    bus.KindMessage,
+			UserID:  message.To,
+			Message: message,
+			Trace:   tracing.Inject(ctx),
+		}
+		if h.publish(node, env) {
+			forwarded = true
+		}
+	}
+	return forwarded
+}
+
+func (h *Hub) forwardFrame(userID string, frame []byte) {
+	for _, node := range h.remoteNodes(userID) {
+		h.publish(node, &bus.Envelope{Kind: bus.KindFrame, UserID: userID, Frame: frame})
+	}
+}
+
+func (h *Hub) handleEnvelope(data []byte) {
+	var env bus.Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		log.Error().Err(err).Msg("Envelope inválido recebido do bus")
+		return
+	}
+
+	switch env.Kind {
+	case bus.KindMessage:
+		if env.Message != nil {
+			h.shardFor(env.Message.To).deliver <- &delivery{ctx: tracing.Extract(env.Trace), message: env.Message}
+		}
+	case bus.KindFrame:
+		h.sendLocal(env.UserID, env.Frame)
+	case bus.KindDisconnect:
+		h.disconnectLocal(env.UserID, env.Frame)
+	}
+}
+
+func (h *Hub) trackPresence(client *Client, online bool) {
+	h.store.Submit(client.UserID, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var err error
+		if online {
+			err = h.presence.Register(ctx, client.UserID, client.DeviceID, h.node)
+		} else {
+			err = h.presence.Unregister(ctx, client.UserID, client.DeviceID, h.node)
+		}
+		if err != nil {
+			client.log.Error().Err(err).Msg("Erro ao atualizar presença")
+		}
+	})
+}
+
+func (h *Hub) heartbeat() {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := h.presence.Heartbeat(ctx, h.node); err != nil {
+			log.Error().Err(err).Msg("Erro ao renovar presença")
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,53 @@
+package ws
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/model"
+	"wisp/src/ws/wstest"
+)
+
+func TestForwardToOtherNode(t *testing.T) {
+	b, presence := bus.NewLocal(), bus.NewMemoryPresence()
+	storeA, storeB := wstest.NewStore(), wstest.NewStore()
+	a := NewHub(wstest.Config("node-a"), storeA, wstest.NewReceipts(), b, presence)
+	c := NewHub(wstest.Config("node-b"), storeB, wstest.NewReceipts(), b, presence)
+	a.Run()
+	c.Run()
+
+	var sender, recipient atomic.Int64
+	connect(t, a, &sender, "alice01")
+	connect(t, c, &recipient, "bob0001")
+	waitFor(t, func() bool {
+		devices, _ := presence.Lookup(context.Background(), "bob0001")
+		return devices["test"] == "node-b"
+	})
+
+	a.Broadcast(context.Background(), &model.Message{
+		Type:      "message",
+		From:      "alice01",
+		To:        "bob0001",
+		Content:   "oi",
+		Timestamp: time.Now().Unix(),
+	})
+
+	waitFor(t, func() bool { return recipient.Load() == 1 })
+	if n := storeA.Inserted() + storeB.Inserted(); n != 0 {
+		t.Fatalf("mensagem entregue foi para a fila pendente: %d", n)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condição não atingida a tempo")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,77 @@
+package ws
+
+import (
+	"container/list"
+	"sync"
+	"time"
+	"wisp/src/model"
+)
+
+const maxDedupEntries = 50000
+
+type dedupEntry struct {
+	key     string
+	receipt *model.MessageReceipt
+	expires time.Time
+}
+
+// dedupCache guarda os recibos recentes em memória para evitar uma ida ao
+// Mongo em cada reenvio. Entradas saem por idade ou quando o limite estoura.
+type dedupCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newDedupCache(window time.Duration) *dedupCache {
+	return &dedupCache{
+		window:  window,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func dedupKey(from, clientID string) string {
+	return from + "|" + clientID
+}
+
+func (d *dedupCache) Get(from, clientID string) (*model.MessageReceipt, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.evict(time.Now())
+	el, ok := d.entries[dedupKey(from, clientID)]
+	if !ok {
+		return nil, false
+	}
+	return el.Value.(*dedupEntry).receipt, true
+}
+
+func (d *dedupCache) Put(rc *model.MessageReceipt) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := dedupKey(rc.From, rc.ClientID)
+	if _, ok := d.entries[key]; ok {
+		return
+	}
+	d.entries[key] = d.order.PushBack(&dedupEntry{
+		key:     key,
+		receipt: rc,
+		expires: time.Now().Add(d.window),
+	})
+	d.evict(time.Now())
+}
+
+func (d *dedupCache) evict(now time.Time) {
+	for d.order.Len() > 0 {
+		front := d.order.Front()
+		e := front.Value.(*dedupEntry)
+		if d.order.Len() <= maxDedupEntries && now.Before(e.expires) {
+			return
+		}
+		d.order.Remove(front)
+		delete(d.entries, e.key)
+	}
+}
@@ -0,0 +1,86 @@
+package ws
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/model"
+	"wisp/src/ws/wstest"
+)
+
+// TestDuplicateSendIsConfirmedNotDelivered reenvia a mesma mensagem três
+// vezes: a segunda cai no LRU, e a terceira, numa instância sem nada em
+// memória, no índice único de message_receipts. O destinatário recebe uma
+// vez só e todos os reenvios são confirmados com o id original.
+func TestDuplicateSendIsConfirmedNotDelivered(t *testing.T) {
+	b, presence, receipts := bus.NewLocal(), bus.NewMemoryPresence(), wstest.NewReceipts()
+	a := NewHub(wstest.Config("dedup-a"), wstest.NewStore(), receipts, b, presence)
+	c := NewHub(wstest.Config("dedup-b"), wstest.NewStore(), receipts, b, presence)
+	a.Run()
+	c.Run()
+
+	alice := dial(t, a, "alice01", "phone", "", 0, true)
+	bob := dial(t, a, "bob0001", "phone", "", 0, true)
+
+	send := func(h *Hub) {
+		msg := message("alice01", "bob0001", "oi")
+		msg.ClientID = "c-1"
+		h.Broadcast(context.Background(), msg)
+	}
+
+	send(a)
+	waitFor(t, func() bool { return len(alice.ofType("sent")) == 1 })
+	send(a)
+	waitFor(t, func() bool { return len(alice.ofType("sent")) == 2 })
+	if n := receipts.Reserves(); n != 1 {
+		t.Fatalf("reenvio consultou o índice %d vezes, esperava só o LRU", n)
+	}
+
+	send(c)
+	waitFor(t, func() bool { return len(alice.ofType("sent")) == 3 })
+	if n := receipts.Reserves(); n != 2 {
+		t.Fatalf("instância sem cache consultou o índice %d vezes", n)
+	}
+
+	sent := alice.ofType("sent")
+	for i, f := range sent {
+		if f.MessageID != sent[0].MessageID || f.Duplicate != (i > 0) {
+			t.Fatalf("confirmação %d: id %q, duplicate %v; original %q", i, f.MessageID, f.Duplicate, sent[0].MessageID)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := bob.ofType("message"); len(got) != 1 || got[0].ID != sent[0].MessageID {
+		t.Fatalf("destinatário recebeu %+v", got)
+	}
+}
+
+func TestDedupCacheEviction(t *testing.T) {
+	d := newDedupCache(50 * time.Millisecond)
+	d.Put(&model.MessageReceipt{From: "alice01", ClientID: "c-1", MessageID: "m-1"})
+
+	// Um segundo Put com a mesma chave não troca o original.
+	d.Put(&model.MessageReceipt{From: "alice01", ClientID: "c-1", MessageID: "m-2"})
+	if rc, ok := d.Get("alice01", "c-1"); !ok || rc.MessageID != "m-1" {
+		t.Fatalf("recibo %+v, %v", rc, ok)
+	}
+	if _, ok := d.Get("bob0001", "c-1"); ok {
+		t.Fatal("clientId de outro remetente tratado como reenvio")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := d.Get("alice01", "c-1"); ok {
+		t.Fatal("recibo continua no cache depois da janela")
+	}
+
+	d = newDedupCache(time.Hour)
+	for i := range maxDedupEntries + 1 {
+		d.Put(&model.MessageReceipt{From: "alice01", ClientID: testUser(i)})
+	}
+	if _, ok := d.Get("alice01", testUser(0)); ok {
+		t.Fatal("cache passou do limite sem descartar o mais antigo")
+	}
+	if _, ok := d.Get("alice01", testUser(maxDedupEntries)); !ok {
+		t.Fatal("recibo mais recente descartado")
+	}
+}
@@ -0,0 +1,60 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"wisp/src/bus"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Código de fechamento para contas suspensas, banidas ou deslogadas à força.
+const CloseAccountBlocked = 4003
+
+// DisconnectUser envia notice a todos os dispositivos do usuário e fecha as
+// conexões, nesta e nas demais instâncias. Mensagens que ainda estavam no
+// buffer voltam para a fila pendente.
+//
+// Com o bus "changestream" não há presença entre instâncias, então só os
+// dispositivos conectados aqui são desconectados; os outros caem na próxima
+// validação de token.
+func (h *Hub) DisconnectUser(userID string, notice any) {
+	frame, err := json.Marshal(notice)
+	if err != nil {
+		log.Error().Err(err).Msg("Erro ao serializar aviso de conta")
+		return
+	}
+
+	h.disconnectLocal(userID, frame)
+	for _, node := range h.remoteNodes(userID) {
+		h.publish(node, &bus.Envelope{Kind: bus.KindDisconnect, UserID: userID, Frame: frame})
+	}
+}
+
+func (h *Hub) disconnectLocal(userID string, frame []byte) {
+	clients := h.shardFor(userID).devices(userID)
+	if len(clients) == 0 {
+		return
+	}
+	for _, client := range clients {
+		client.session.send(frame, "")
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		h.waitFlush(ctx, clients)
+		for _, client := range clients {
+			client.closeWith(CloseAccountBlocked, "account blocked")
+		}
+		for _, client := range clients {
+			select {
+			case <-client.stopped:
+			case <-ctx.Done():
+			}
+			h.spillQueued(client)
+		}
+	}()
+}
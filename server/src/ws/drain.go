@@ -0,0 +1,126 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"time"
+	"wisp/src/model"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+func (h *Hub) StopAccepting() {
+	h.draining.Store(true)
+}
+
+func (h *Hub) Draining() bool {
+	return h.draining.Load()
+}
+
+// Drain encerra todas as conexões para um deploy. Cada cliente recebe um
+// frame pedindo para reconectar após um atraso com jitter, o hub espera os
+// buffers esvaziarem até o prazo de ctx e fecha os sockets com
+// CloseGoingAway. Mensagens que ficaram no buffer vão para a fila pendente.
+//
+// A ordem importa: primeiro para a entrada (accept e bus), depois esvaziam
+// as filas dos shards, que ainda podem desviar mensagens para o pool de
+// armazenamento, e só então esse pool fecha.
+func (h *Hub) Drain(ctx context.Context) {
+	h.StopAccepting()
+
+	clients := h.allClients()
+	log.Info().Int("clients", len(clients)).Msg("Drenando conexões WebSocket")
+
+	for _, client := range clients {
+		client.session.send(h.restartFrame(), "")
+	}
+
+	h.waitFlush(ctx, clients)
+
+	for _, client := range clients {
+		client.closeWith(websocket.CloseGoingAway, "server restarting")
+	}
+	for _, client := range clients {
+		select {
+		case <-client.stopped:
+		case <-ctx.Done():
+		}
+		h.spillQueued(client)
+	}
+
+	h.accept.Close()
+	if h.unsubscribe != nil {
+		h.unsubscribe()
+	}
+	close(h.stop)
+	for _, s := range h.shards {
+		s.flush(ctx)
+	}
+	h.store.Close()
+}
+
+func (h *Hub) allClients() []*Client {
+	var out []*Client
+	for _, s := range h.shards {
+		s.mu.RLock()
+		for _, devices := range s.clients {
+			for _, c := range devices {
+				out = append(out, c)
+			}
+		}
+		s.mu.RUnlock()
+	}
+	return out
+}
+
+func (h *Hub) restartFrame() []byte {
+	delay := h.restartDelay
+	if h.restartJitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(h.restartJitter)))
+	}
+	frame, _ := json.Marshal(&model.ServerRestart{
+		Type:        "server_restart",
+		ReconnectIn: int64(delay.Round(time.Second) / time.Second),
+	})
+	return frame
+}
+
+func (h *Hub) waitFlush(ctx context.Context, clients []*Client) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		pending := 0
+		for _, c := range clients {
+			pending += len(c.Send)
+		}
+		if pending == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// spillQueued grava na fila pendente, para o dispositivo, as mensagens que
+// não chegaram a sair do buffer.
+func (h *Hub) spillQueued(client *Client) {
+	for {
+		select {
+		case frame := <-client.Send:
+			var msg model.Message
+			if err := json.Unmarshal(frame, &msg); err != nil || msg.Type != "message" {
+				continue
+			}
+			h.spillMessage(context.Background(), client, &msg)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ws
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/filter"
+	"wisp/src/model"
+	"wisp/src/ws/wstest"
+)
+
+// TestFilteredMessages confere a resposta ao remetente de cada ação: o
+// shadow drop confirma como se a mensagem tivesse saído, o drop avisa que
+// ela foi recusada, e em nenhum dos dois o destinatário recebe algo.
+func TestFilteredMessages(t *testing.T) {
+	cases := []struct {
+		action   filter.Action
+		rejected bool
+	}{
+		{filter.ShadowDrop, false},
+		{filter.Drop, true},
+	}
+	for _, c := range cases {
+		t.Run(c.action.String(), func(t *testing.T) {
+			store := wstest.NewStore()
+			h := NewHub(wstest.Config("filter"), store, wstest.NewReceipts(), bus.NewLocal(), bus.NewMemoryPresence())
+			h.SetFilter(filter.NewChain(nil, nil, time.Hour, filter.NewSenderRate(0, time.Minute, c.action)))
+			h.Run()
+
+			var received atomic.Int64
+			connect(t, h, &received, "bob0001")
+
+			msg := &model.Message{Type: "message", From: "alice01", To: "bob0001", Content: "oi", ClientID: "c-1", Timestamp: time.Now().Unix()}
+			switch frame := h.Send(context.Background(), msg).(type) {
+			case *model.SendConfirmation:
+				if c.rejected {
+					t.Fatal("mensagem barrada foi confirmada")
+				}
+				if frame.MessageID == "" || frame.Duplicate {
+					t.Fatalf("confirmação inesperada: %+v", frame)
+				}
+			case *model.SendRejected:
+				if !c.rejected {
+					t.Fatal("shadow drop avisou o remetente")
+				}
+			default:
+				t.Fatalf("frame inesperado: %#v", frame)
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			if n := received.Load(); n != 0 {
+				t.Fatalf("destinatário recebeu %d mensagens filtradas", n)
+			}
+			if n := store.Inserted(); n != 0 {
+				t.Fatalf("mensagem filtrada foi para a fila pendente: %d", n)
+			}
+		})
+	}
+}
@@ -3,147 +3,476 @@ package ws
 import (
 	"context"
 	"encoding/json"
-	"sync"
+	"fmt"
+	"sync/atomic"
 	"time"
+	"wisp/config"
+	"wisp/src/bus"
+	"wisp/src/filter"
+	"wisp/src/metrics"
 	"wisp/src/model"
-	"wisp/src/repository"
+	"wisp/src/ratelimit"
+	"wisp/src/tracing"
 
-	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-type Client struct {
-	Hub      *Hub
-	UserID   string
-	DeviceID string
-	Conn     *websocket.Conn
-	Send     chan []byte
-	mu       sync.Mutex
-}
-
 type Hub struct {
-	Clients    map[string]map[string]*Client
-	Register   chan *Client
-	Unregister chan *Client
-	Broadcast  chan *model.Message
-	mu         sync.RWMutex
-	msgRepo    *repository.MessageRepo
+	node        string
+	bus         bus.Bus
+	presence    bus.Presence
+	shards      []*shard
+	accept      *workerPool
+	store       *workerPool
+	msgRepo     MessageStore
+	receiptRepo ReceiptStore
+	dedup       *dedupCache
+	slowTimeout time.Duration
+	stats       hubStats
+	acks        *ackTracker
+	filters     *filter.Chain
+	notifier    Notifier
+	bots        BotInbox
+	expirer     Expirer
+	history     History
+	frameRate   ratelimit.Rate
+	unsubscribe func()
+	// stop encerra o heartbeat de presença no Drain.
+	stop chan struct{}
+	// relay é falso no modo changestream, em que nada passa pelo bus: a
+	// entrega entre instâncias é feita pelo change stream da fila pendente.
+	relay bool
+
+	draining      atomic.Bool
+	restartDelay  time.Duration
+	restartJitter time.Duration
 }
 
-func NewHub(msgRepo *repository.MessageRepo) *Hub {
-	return &Hub{
-		Clients:    make(map[string]map[string]*Client),
-		Register:   make(chan *Client),
-		Unregister: make(chan *Client),
-		Broadcast:  make(chan *model.Message),
-		msgRepo:    msgRepo,
+func NewHub(cfg *config.Config, msgRepo MessageStore, receiptRepo ReceiptStore, b bus.Bus, presence bus.Presence) *Hub {
+	h := &Hub{
+		node:        bus.NodeID(cfg),
+		bus:         b,
+		presence:    presence,
+		accept:      newWorkerPool(cfg.Hub.Workers, cfg.Hub.QueueSize),
+		store:       newWorkerPool(cfg.Hub.Workers, cfg.Hub.QueueSize),
+		msgRepo:     msgRepo,
+		receiptRepo: receiptRepo,
+		dedup:       newDedupCache(cfg.Hub.DedupWindow),
+		slowTimeout: cfg.Hub.SlowConsumerTimeout,
+		acks:        newAckTracker(),
+		stop:        make(chan struct{}),
+		relay:       cfg.Cluster.Bus != "changestream",
+
+		restartDelay:  cfg.Hub.RestartDelay,
+		restartJitter: cfg.Hub.RestartJitter,
+	}
+
+	if cfg.RateLimit.Enabled {
+		f := cfg.RateLimit.WSFrames
+		h.frameRate = ratelimit.Rate{Limit: f.Limit, Period: f.Period, Burst: f.Burst}
+	}
+
+	h.shards = make([]*shard, cfg.Hub.Shards)
+	for i := range h.shards {
+		h.shards[i] = newShard(h)
 	}
+	return h
 }
 
+// Run inicia os shards, os workers de banco e a assinatura no bus. Os
+// workers de aceite entregam aos shards e os shards só enfileiram nos
+// workers de armazenamento, então as filas nunca esperam umas pelas outras
+// em ciclo.
 func (h *Hub) Run() {
-	for {
+	h.accept.start()
+	h.store.start()
+	for _, s := range h.shards {
+		go s.run()
+	}
+
+	unsubscribe, err := h.bus.Subscribe(h.node, h.handleEnvelope)
+	if err != nil {
+		log.Error().Err(err).Str("node", h.node).Msg("Erro ao assinar o bus")
+		unsubscribe = func() {}
+	}
+	h.unsubscribe = unsubscribe
+	go h.heartbeat()
+}
+
+// SetFilter instala a cadeia de filtros anti-abuso. Deve ser chamado antes
+// de Run.
+func (h *Hub) SetFilter(c *filter.Chain) {
+	h.filters = c
+}
+
+// SetNotifier instala quem avisa os dispositivos offline. Deve ser chamado
+// antes de Run.
+func (h *Hub) SetNotifier(n Notifier) {
+	h.notifier = n
+}
+
+// SetBotInbox instala quem recebe as mensagens enviadas a bots. Deve ser
+// chamado antes de Run.
+func (h *Hub) SetBotInbox(b BotInbox) {
+	h.bots = b
+}
+
+// SetExpirer instala o controle de mensagens temporárias. Deve ser chamado
+// antes de Run.
+func (h *Hub) SetExpirer(e Expirer) {
+	h.expirer = e
+}
+
+// SetHistory instala o histórico de mensagens. Deve ser chamado antes de
+// Run.
+func (h *Hub) SetHistory(hs History) {
+	h.history = hs
+}
+
+// Notify envia um frame aos dispositivos do usuário, nesta e nas outras
+// instâncias. Dispositivos offline não o recebem depois.
+func (h *Hub) Notify(userID string, v any) {
+	h.sendToUser(userID, v)
+}
+
+func (h *Hub) shardFor(userID string) *shard {
+	return h.shards[hashKey(userID)%uint32(len(h.shards))]
+}
+
+// Ping passa por cada shard e retorna erro se algum loop não responder até o
+// prazo de ctx.
+func (h *Hub) Ping(ctx context.Context) error {
+	for i, s := range h.shards {
 		select {
-		case client := <-h.Register:
-			h.mu.Lock()
-			if _, ok := h.Clients[client.UserID]; !ok {
-				h.Clients[client.UserID] = make(map[string]*Client)
-			}
-			h.Clients[client.UserID][client.DeviceID] = client
-			h.mu.Unlock()
-
-			go h.sendPendingMessages(client)
-
-		case client := <-h.Unregister:
-			h.mu.Lock()
-			if devices, ok := h.Clients[client.UserID]; ok {
-				if _, ok := devices[client.DeviceID]; ok {
-					delete(devices, client.DeviceID)
-					close(client.Send)
-
-					if len(devices) == 0 {
-						delete(h.Clients, client.UserID)
-					}
-				}
+		case s.ping <- struct{}{}:
+		case <-ctx.Done():
+			return fmt.Errorf("shard %d não respondeu: %w", i, ctx.Err())
+		}
+	}
+	return nil
+}
+
+func (h *Hub) Register(client *Client) {
+	h.shardFor(client.UserID).register <- client
+}
+
+func (h *Hub) Unregister(client *Client) {
+	h.shardFor(client.UserID).unregister <- client
+}
+
+// Broadcast aceita uma mensagem de um remetente. A deduplicação e o recibo
+// rodam no worker do remetente; a entrega fica com o shard do destinatário.
+// O span em ctx é o pai do aceite e o link da entrega e do ACK.
+func (h *Hub) Broadcast(ctx context.Context, message *model.Message) {
+	h.submit(ctx, message, nil)
+}
+
+// Send é o Broadcast de quem não tem um socket para receber a confirmação:
+// espera o aceite e devolve o frame enviado ao remetente, um
+// *model.SendConfirmation (com o id original se for reenvio) ou um
+// *model.SendRejected. Se ctx vencer antes, a mensagem segue sendo
+// processada e o retorno é nil.
+func (h *Hub) Send(ctx context.Context, message *model.Message) any {
+	result := make(chan any, 1)
+	h.submit(context.WithoutCancel(ctx), message, func(frame any) { result <- frame })
+	select {
+	case frame := <-result:
+		return frame
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// submit aceita a mensagem e manda o resultado ao remetente; done, se não
+// for nil, recebe o mesmo frame.
+func (h *Hub) submit(ctx context.Context, message *model.Message, done func(frame any)) {
+	reply := func(frame any) {
+		h.sendToUser(message.From, frame)
+		if done != nil {
+			done(frame)
+		}
+	}
+
+	message.ID = primitive.NewObjectID().Hex()
+	receipt := &model.MessageReceipt{
+		From:      message.From,
+		ClientID:  message.ClientID,
+		MessageID: message.ID,
+		To:        message.To,
+		Timestamp: message.Timestamp,
+	}
+
+	if message.ClientID != "" {
+		if original, ok := h.dedup.Get(message.From, message.ClientID); ok {
+			h.confirmDuplicate(message, original, reply)
+			return
+		}
+	}
+
+	h.accept.Submit(message.From, func() {
+		ctx, span := tracing.Start(ctx, "hub.accept", tracing.Attrs(
+			"wisp.message_id", message.ID,
+			"wisp.from", message.From,
+			"wisp.to", message.To,
+		))
+		defer span.End()
+
+		// O filtro roda antes do recibo: uma mensagem barrada não fica
+		// registrada como enviada para os reenvios.
+		if h.filters != nil {
+			switch d := h.filters.Check(ctx, message); d.Action {
+			case filter.Drop, filter.Suspend:
+				span.SetAttributes(attribute.String("wisp.filtered", d.Action.String()))
+				reply(&model.SendRejected{
+					Type:     "rejected",
+					ClientID: message.ClientID,
+					To:       message.To,
+					Reason:   "mensagem bloqueada pelo filtro anti-abuso",
+				})
+				return
+			case filter.ShadowDrop:
+				span.SetAttributes(attribute.String("wisp.filtered", d.Action.String()))
+				reply(receipt.Confirmation(false))
+				return
 			}
-			h.mu.Unlock()
+		}
 
-		case message := <-h.Broadcast:
-			delivered := h.deliverMessage(message)
+		if message.ClientID != "" {
+			if original, dup := h.reserveReceipt(ctx, receipt); dup {
+				span.SetAttributes(attribute.Bool("wisp.duplicate", true))
+				h.confirmDuplicate(message, original, reply)
+				return
+			}
+		}
 
-			if !delivered {
-				h.storePendingMessage(message)
+		// Eventos de sistema não vencem: o aviso de troca do timer fica.
+		if h.expirer != nil && message.Event == nil {
+			if d := h.expirer.DisappearAfter(ctx, message.From, message.To); d > 0 {
+				message.ExpiresAt = time.Now().Add(d).Unix()
+				h.expirer.Track(ctx, message)
 			}
 		}
-	}
+
+		if h.history != nil {
+			h.history.Record(ctx, message)
+		}
+
+		metrics.MessagesRouted.Inc()
+		if h.bots != nil && h.bots.IsBot(ctx, message.To) {
+			h.bots.Received(ctx, message)
+		}
+		forwarded := h.forwardMessage(ctx, message)
+		h.shardFor(message.To).deliver <- &delivery{ctx: ctx, message: message, forwarded: forwarded}
+		reply(receipt.Confirmation(false))
+	})
 }
 
-func (h *Hub) deliverMessage(message *model.Message) bool {
-	h.mu.RLock()
-	devices, exists := h.Clients[message.To]
-	h.mu.RUnlock()
+func (h *Hub) confirmDuplicate(message *model.Message, original *model.MessageReceipt, reply func(frame any)) {
+	metrics.MessagesDuplicated.Inc()
+	log.Debug().Str("from", message.From).Str("clientId", message.ClientID).Msg("Mensagem duplicada ignorada")
+	reply(original.Confirmation(true))
+}
 
-	if !exists || len(devices) == 0 {
-		return false
+func (h *Hub) reserveReceipt(ctx context.Context, receipt *model.MessageReceipt) (*model.MessageReceipt, bool) {
+	if original, ok := h.dedup.Get(receipt.From, receipt.ClientID); ok {
+		return original, true
 	}
 
-	msgJSON, err := json.Marshal(message)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	stored, dup, err := h.receiptRepo.Reserve(ctx, receipt)
 	if err != nil {
-		log.Error().Err(err).Msg("Erro ao serializar mensagem")
-		return false
+		log.Error().Err(err).Msg("Erro ao registrar recibo de envio")
+		h.dedup.Put(receipt)
+		return receipt, false
 	}
 
-	delivered := false
-	for _, client := range devices {
-		select {
-		case client.Send <- msgJSON:
-			delivered = true
-		default:
+	h.dedup.Put(stored)
+	return stored, dup
+}
+
+// replay envia o frame de sessão, os frames perdidos desde LastSeq e as
+// mensagens pendentes, nessa ordem. Frames novos que chegam no meio ficam no
+// log e são entregues antes de o replay terminar.
+func (h *Hub) replay(client *Client, resumed bool, lost *deviceSession) {
+	sess := client.session
+	info, _ := json.Marshal(&model.SessionInfo{
+		Type:      "session",
+		SessionID: sess.id,
+		Seq:       client.LastSeq,
+		Resumed:   resumed,
+	})
+	if !client.push(info) {
+		return
+	}
+
+	cursor, alive := h.drain(client, client.LastSeq)
+	if !alive {
+		return
+	}
+
+	var unacked []*model.Message
+	if lost != nil {
+		unacked = h.spillSession(client.UserID, client.DeviceID, lost)
+	}
+	cursor, alive = h.sendPendingMessages(client, cursor, unacked)
+	if !alive {
+		return
+	}
+
+	for !sess.finishReplay(client, cursor) {
+		if cursor, alive = h.drain(client, cursor); !alive {
+			return
 		}
 	}
+}
+
+func (h *Hub) drain(client *Client, cursor uint64) (uint64, bool) {
+	entries, ok := client.session.after(cursor)
+	if !ok {
+		client.log.Warn().Uint64("seq", cursor).Msg("Frames descartados do log de sessão durante o replay")
+	}
 
-	return delivered
+	for _, e := range entries {
+		if !client.push(e.frame) {
+			return cursor, false
+		}
+		cursor = e.seq
+	}
+	return cursor, true
 }
 
-func (h *Hub) storePendingMessage(message *model.Message) {
-	pendingMsg := &model.PendingMessage{
-		From:      message.From,
-		To:        message.To,
-		Payload:   message.Content,
-		CreatedAt: time.Now(),
+// spillMessage guarda a mensagem na fila persistente apenas para o
+// dispositivo cujo buffer está cheio; ela volta no próximo replay.
+func (h *Hub) spillMessage(ctx context.Context, client *Client, message *model.Message) {
+	pendingMsg := model.NewPendingMessage(message)
+	pendingMsg.DeviceID = client.DeviceID
+	pendingMsg.MessageID = message.ID
+
+	h.store.Submit(message.To, func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		if _, err := h.msgRepo.Insert(ctx, pendingMsg); err != nil {
+			h.stats.framesDropped.Add(1)
+			client.log.Error().Err(err).Msg("Erro ao desviar mensagem de cliente lento")
+			return
+		}
+
+		h.stats.framesSpilled.Add(1)
+		metrics.MessagesQueued.Inc()
+		client.log.Debug().Str("id", message.ID).Msg("Buffer cheio, mensagem desviada para a fila pendente")
+	})
+}
+
+// spillSession desvia para a fila pendente do dispositivo as mensagens não
+// confirmadas de uma sessão descartada, como no desvio de cliente lento, e
+// as devolve na ordem do log. As que já estão na fila não são gravadas de
+// novo.
+func (h *Hub) spillSession(userID, deviceID string, sess *deviceSession) []*model.Message {
+	entries := sess.unacked()
+	if len(entries) == 0 {
+		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	id, err := h.msgRepo.Insert(ctx, pendingMsg)
+	// Sem a consulta, desviar tudo: repetir uma mensagem é melhor que perdê-la.
+	stored, err := h.msgRepo.GetPendingFor(ctx, userID, deviceID)
 	if err != nil {
-		log.Error().Err(err).Msg("Erro ao armazenar mensagem pendente")
-	} else {
-		log.Debug().Str("id", id.Hex()).Msg("Mensagem armazenada para entrega posterior")
+		log.Error().Err(err).Str("to", userID).Msg("Erro ao buscar mensagens pendentes")
+	}
+	seen := make(map[string]struct{}, len(stored))
+	for _, pm := range stored {
+		seen[pm.MessageIDHex()] = struct{}{}
+	}
+
+	var msgs []*model.Message
+	for _, e := range entries {
+		var msg model.Message
+		if err := json.Unmarshal(e.frame, &msg); err != nil || msg.Type != "message" {
+			continue
+		}
+		msgs = append(msgs, &msg)
+		if _, ok := seen[msg.ID]; ok {
+			continue
+		}
+
+		pendingMsg := model.NewPendingMessage(&msg)
+		pendingMsg.DeviceID = deviceID
+		pendingMsg.MessageID = msg.ID
+		if _, err := h.msgRepo.Insert(ctx, pendingMsg); err != nil {
+			h.stats.framesDropped.Add(1)
+			log.Error().Err(err).Str("to", userID).Str("deviceId", deviceID).Msg("Erro ao desviar mensagem de sessão descartada")
+			continue
+		}
+		h.stats.framesSpilled.Add(1)
+		metrics.MessagesQueued.Inc()
 	}
+	return msgs
 }
 
-func (h *Hub) sendPendingMessages(client *Client) {
+// checkSaturation desconecta o cliente se o buffer dele está cheio há mais
+// tempo que o limite configurado.
+func (h *Hub) checkSaturation(client *Client) {
+	if !client.markFull(h.slowTimeout) {
+		return
+	}
+
+	h.stats.slowDisconnects.Add(1)
+	client.log.Warn().Msg("Cliente lento desconectado")
+	client.closeWith(CloseSlowConsumer, "slow consumer")
+}
+
+func (h *Hub) storePendingMessage(ctx context.Context, message *model.Message) {
+	pendingMsg := model.NewPendingMessage(message)
+	pendingMsg.ID, _ = primitive.ObjectIDFromHex(message.ID)
+	pendingMsg.CreatedAt = time.Now()
+
+	h.store.Submit(message.To, func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		id, err := h.msgRepo.Insert(ctx, pendingMsg)
+		if err != nil {
+			log.Error().Err(err).Msg("Erro ao armazenar mensagem pendente")
+			return
+		}
+		metrics.MessagesQueued.Inc()
+		log.Debug().Str("id", id.Hex()).Msg("Mensagem armazenada para entrega posterior")
+		if h.notifier != nil && !h.onlineElsewhere(message.To) {
+			h.notifier.MessageQueued(ctx, message)
+		}
+	})
+}
+
+// sendPendingMessages envia first e depois a fila pendente do dispositivo,
+// pulando o que a sessão já tem.
+func (h *Hub) sendPendingMessages(client *Client, cursor uint64, first []*model.Message) (uint64, bool) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	userId := client.UserID
-	pendingMsgs, err := h.msgRepo.GetPendingFor(ctx, userId)
+	pendingMsgs, err := h.msgRepo.GetPendingFor(ctx, userId, client.DeviceID)
 	if err != nil {
 		log.Error().Err(err).Msg("Erro ao buscar mensagens pendentes")
-		return
 	}
 
+	msgs := first
 	for _, pm := range pendingMsgs {
-		msg := &model.Message{
-			Type:      "message",
-			From:      pm.From,
-			To:        pm.To,
-			Content:   pm.Payload,
-			Timestamp: pm.CreatedAt.Unix(),
-			ID:        pm.ID.Hex(),
+		msgs = append(msgs, pm.Message())
+	}
+
+	seen := client.session.messageIDs()
+	for _, msg := range msgs {
+		if _, ok := seen[msg.ID]; ok {
+			continue
 		}
+		seen[msg.ID] = struct{}{}
 
 		msgJSON, err := json.Marshal(msg)
 		if err != nil {
@@ -151,11 +480,17 @@ func (h *Hub) sendPendingMessages(client *Client) {
 			continue
 		}
 
-		client.Send <- msgJSON
+		client.session.send(msgJSON, msg.ID)
+
+		var alive bool
+		if cursor, alive = h.drain(client, cursor); !alive {
+			return cursor, false
+		}
 	}
+	return cursor, true
 }
 
-func (h *Hub) ProcessMessageAck(ack *model.Ack) {
+func (h *Hub) ProcessMessageAck(ack *model.Ack, deviceID string) {
 	if ack.Type != "ack" {
 		return
 	}
@@ -166,10 +501,25 @@ func (h *Hub) ProcessMessageAck(ack *model.Ack) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	metrics.MessagesAcked.Inc()
+	deliveredCtx := h.acks.acked(ack.MessageID)
+	for _, c := range h.shardFor(ack.To).devices(ack.To) {
+		if c.DeviceID == deviceID {
+			c.session.ack(ack.MessageID)
+		}
+	}
+
+	ctx, span := tracing.Start(context.Background(), "hub.ack", tracing.LinkTo(deliveredCtx), tracing.Attrs(
+		"wisp.message_id", ack.MessageID,
+		"wisp.from", ack.From,
+		"wisp.to", ack.To,
+	))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err = h.msgRepo.Delete(ctx, msgID)
+	err = h.msgRepo.DeleteAcked(ctx, msgID, ack.To, deviceID)
 	if err != nil {
 		log.Error().Err(err).Msg("Erro ao excluir mensagem pendente após ACK")
 	}
@@ -178,128 +528,32 @@ func (h *Hub) ProcessMessageAck(ack *model.Ack) {
 }
 
 func (h *Hub) notifySender(ack *model.Ack) {
-	h.mu.RLock()
-	devices, exists := h.Clients[ack.From]
-	h.mu.RUnlock()
-
-	if !exists || len(devices) == 0 {
-		return
-	}
+	h.sendToUser(ack.From, ack)
+}
 
-	ackJSON, err := json.Marshal(ack)
+// sendToUser envia o frame para todos os dispositivos do usuário, inclusive
+// os conectados em outras instâncias. Faz consulta de presença, então não
+// deve ser chamada de dentro do loop de um shard.
+func (h *Hub) sendToUser(userID string, v any) {
+	frame, err := json.Marshal(v)
 	if err != nil {
-		log.Error().Err(err).Msg("Erro ao serializar ACK")
+		log.Error().Err(err).Msg("Erro ao serializar frame")
 		return
 	}
 
-	for _, client := range devices {
-		select {
-		case client.Send <- ackJSON:
-		default:
-		}
-	}
-}
-
-func (c *Client) SendMessage(msg []byte) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.Conn != nil {
-		c.Conn.WriteMessage(websocket.TextMessage, msg)
-	}
+	h.sendLocal(userID, frame)
+	h.forwardFrame(userID, frame)
 }
 
-func (c *Client) ReadPump() {
-	defer func() {
-		c.Hub.Unregister <- c
-		c.Conn.Close()
-	}()
-
-	c.Conn.SetReadLimit(512 * 1024)
-	c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-	c.Conn.SetPongHandler(func(string) error {
-		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		return nil
-	})
-
-	for {
-		_, message, err := c.Conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Error().Err(err).Str("userId", c.UserID).Str("deviceId", c.DeviceID).Msg("Erro de conexão WebSocket")
-			}
-			break
-		}
-
-		var data map[string]any
-		if err := json.Unmarshal(message, &data); err != nil {
-			log.Error().Err(err).Msg("Formato de mensagem inválido")
-			continue
-		}
-
-		if msgType, ok := data["type"].(string); ok {
-			switch msgType {
-			case "message":
-				var msg model.Message
-				if err := json.Unmarshal(message, &msg); err == nil {
-					if msg.From != c.UserID {
-						log.Warn().Str("claimed", msg.From).Str("actual", c.UserID).Msg("Tentativa de envio com ID falsificado")
-						continue
-					}
-					if msg.Timestamp == 0 {
-						msg.Timestamp = time.Now().Unix()
-					}
-					c.Hub.Broadcast <- &msg
-				}
-			case "ack":
-				var ack model.Ack
-				if err := json.Unmarshal(message, &ack); err == nil {
-					if ack.To != c.UserID {
-						continue
-					}
-					c.Hub.ProcessMessageAck(&ack)
-				}
-			}
-		}
-	}
-}
-
-func (c *Client) WritePump() {
-	ticker := time.NewTicker(54 * time.Second)
-	defer func() {
-		ticker.Stop()
-		c.Conn.Close()
-	}()
-
-	for {
-		select {
-		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.Conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			n := len(c.Send)
-			for range n {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
-			}
-
-			if err := w.Close(); err != nil {
-				return
-			}
-		case <-ticker.C:
-			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				return
-			}
+func (h *Hub) sendLocal(userID string, frame []byte) {
+	devices := h.shardFor(userID).devices(userID)
+	for _, client := range devices {
+		switch client.session.send(frame, "") {
+		case sendQueued:
+			client.markDrained()
+		case sendFull:
+			h.stats.framesDropped.Add(1)
+			h.checkSaturation(client)
 		}
 	}
 }
@@ -0,0 +1,229 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/model"
+	"wisp/src/ws/wstest"
+
+	"github.com/rs/zerolog"
+)
+
+// connect registra um cliente em memória para cada usuário. Os clientes
+// contam e confirmam as mensagens recebidas, como um cliente real. Retorna
+// depois que todos receberam o frame de sessão.
+func connect(tb testing.TB, h *Hub, received *atomic.Int64, users ...string) {
+	tb.Helper()
+
+	var ready sync.WaitGroup
+	ready.Add(len(users))
+	msgMarker := []byte(`"type":"message"`)
+	for _, userID := range users {
+		c := NewClient(h, userID, "test", nil, zerolog.Nop())
+		h.Register(c)
+		go func() {
+			first := true
+			for frame := range c.Send {
+				if first {
+					first = false
+					ready.Done()
+				}
+				if !bytes.Contains(frame, msgMarker) {
+					continue
+				}
+				received.Add(1)
+				var msg model.Message
+				if json.Unmarshal(frame, &msg) == nil {
+					h.ProcessMessageAck(&model.Ack{Type: "ack", MessageID: msg.ID, From: msg.From, To: msg.To}, c.DeviceID)
+				}
+			}
+		}()
+	}
+	ready.Wait()
+}
+
+// testFrame junta os campos dos frames que os testes conferem.
+type testFrame struct {
+	Seq       uint64 `json:"seq"`
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	MessageID string `json:"messageId"`
+	SessionID string `json:"sessionId"`
+	Resumed   bool   `json:"resumed"`
+	Duplicate bool   `json:"duplicate"`
+	Content   string `json:"content"`
+}
+
+// testClient é um dispositivo em memória que guarda os frames recebidos.
+// Com read false ele lê só o frame de sessão e deixa o buffer encher.
+type testClient struct {
+	*Client
+	mu     sync.Mutex
+	frames []testFrame
+}
+
+func dial(t *testing.T, h *Hub, userID, deviceID, sessionID string, lastSeq uint64, read bool) *testClient {
+	t.Helper()
+
+	c := &testClient{Client: NewClient(h, userID, deviceID, nil, zerolog.Nop())}
+	c.SessionID, c.LastSeq = sessionID, lastSeq
+	h.Register(c.Client)
+
+	ready := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case raw := <-c.Send:
+				var f testFrame
+				json.Unmarshal(raw, &f)
+				c.mu.Lock()
+				c.frames = append(c.frames, f)
+				c.mu.Unlock()
+				if f.Type == "session" {
+					close(ready)
+					if !read {
+						return
+					}
+				}
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("frame de sessão não recebido")
+	}
+	waitFor(t, func() bool { return !c.replaying() })
+	return c
+}
+
+func (c *testClient) replaying() bool {
+	c.session.mu.Lock()
+	defer c.session.mu.Unlock()
+	return c.session.attached == c.Client && c.session.replaying
+}
+
+// ofType devolve os frames recebidos do tipo informado.
+func (c *testClient) ofType(typ string) []testFrame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out []testFrame
+	for _, f := range c.frames {
+		if f.Type == typ {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (c *testClient) sessionFrame() testFrame {
+	return c.ofType("session")[0]
+}
+
+// disconnect derruba a conexão e espera o hub desligá-la da sessão.
+func (c *testClient) disconnect(t *testing.T) {
+	t.Helper()
+	c.Hub.Unregister(c.Client)
+	waitFor(t, func() bool {
+		c.session.mu.Lock()
+		defer c.session.mu.Unlock()
+		return c.session.attached != c.Client
+	})
+}
+
+func message(from, to, content string) *model.Message {
+	return &model.Message{Type: "message", From: from, To: to, Content: content, Timestamp: time.Now().Unix()}
+}
+
+func testUser(i int) string {
+	return fmt.Sprintf("u%06d", i)
+}
+
+var benchClients = flag.Int("clients", 20000, "clientes conectados no BenchmarkHubBroadcast")
+
+func BenchmarkHubBroadcast(b *testing.B) {
+	const senders = 64
+	clients := *benchClients
+
+	h := NewHub(wstest.Config("bench"), wstest.NewStore(), wstest.NewReceipts(), bus.NewLocal(), bus.NewMemoryPresence())
+	h.Run()
+
+	users := make([]string, clients)
+	for i := range users {
+		users[i] = testUser(i)
+	}
+	var received atomic.Int64
+	connect(b, h, &received, users...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var wg sync.WaitGroup
+	for s := range senders {
+		n := b.N / senders
+		if s < b.N%senders {
+			n++
+		}
+		wg.Add(1)
+		go func(seed int64, n int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for range n {
+				h.Broadcast(context.Background(), &model.Message{
+					Type:      "message",
+					From:      testUser(rnd.Intn(clients)),
+					To:        testUser(rnd.Intn(clients)),
+					Content:   "bench",
+					Timestamp: time.Now().Unix(),
+				})
+			}
+		}(int64(s), n)
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(time.Minute)
+	for received.Load() < int64(b.N) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	b.StopTimer()
+
+	if got := received.Load(); got < int64(b.N) {
+		b.Fatalf("entregues %d de %d mensagens", got, b.N)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msg/s")
+}
+
+func TestSendReturnsOriginalOnDuplicate(t *testing.T) {
+	h := NewHub(wstest.Config("send"), wstest.NewStore(), wstest.NewReceipts(), bus.NewLocal(), bus.NewMemoryPresence())
+	h.Run()
+
+	send := func() *model.SendConfirmation {
+		t.Helper()
+		msg := &model.Message{Type: "message", From: "bot0001", To: "alice01", Content: "oi", ClientID: "c-1", Timestamp: time.Now().Unix()}
+		conf, ok := h.Send(context.Background(), msg).(*model.SendConfirmation)
+		if !ok {
+			t.Fatal("Send não devolveu confirmação")
+		}
+		return conf
+	}
+
+	first, again := send(), send()
+	if first.Duplicate || !again.Duplicate {
+		t.Fatalf("duplicate %v e %v", first.Duplicate, again.Duplicate)
+	}
+	if again.MessageID != first.MessageID {
+		t.Fatalf("reenvio devolveu %q, esperava o id original %q", again.MessageID, first.MessageID)
+	}
+}
@@ -0,0 +1,74 @@
+package ws
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// workerPool executa o trabalho de banco fora das goroutines de roteamento.
+// Jobs com a mesma chave caem sempre no mesmo worker, o que preserva a ordem
+// por remetente. As filas são limitadas: quando enchem, Submit bloqueia.
+// Depois de Close, Submit roda o job no próprio chamador: uma mensagem já
+// confirmada ao remetente não pode ser descartada no desligamento.
+type workerPool struct {
+	queues []chan func()
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(workers, queueSize int) *workerPool {
+	p := &workerPool{queues: make([]chan func(), workers)}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), queueSize)
+	}
+	return p
+}
+
+func (p *workerPool) start() {
+	for _, q := range p.queues {
+		p.wg.Add(1)
+		go func(q chan func()) {
+			defer p.wg.Done()
+			for job := range q {
+				job()
+			}
+		}(q)
+	}
+}
+
+func (p *workerPool) Submit(key string, job func()) {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		log.Debug().Str("key", key).Msg("Pool encerrado, job executado no chamador")
+		job()
+		return
+	}
+	defer p.mu.RUnlock()
+	p.queues[hashKey(key)%uint32(len(p.queues))] <- job
+}
+
+// Close para de aceitar jobs e espera os que já estão nas filas.
+func (p *workerPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wisp/src/bus"
+	"wisp/src/ws/wstest"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+// TestFrameFloodClosesWith4029 manda frames sem parar numa conexão de
+// verdade e espera o fechamento com CloseRateLimited depois do aviso.
+func TestFrameFloodClosesWith4029(t *testing.T) {
+	cfg := wstest.Config("flood")
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.WSFrames.Limit = 5
+	cfg.RateLimit.WSFrames.Period = time.Minute
+	h := NewHub(cfg, wstest.NewStore(), wstest.NewReceipts(), bus.NewLocal(), bus.NewMemoryPresence())
+	h.Run()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		c := NewClient(h, "alice01", "phone", conn, zerolog.Nop())
+		h.Register(c)
+		go c.WritePump()
+		go c.ReadPump()
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go func() {
+		for range 10 * maxDroppedFrames {
+			if conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"typing","to":"bob0001"}`)) != nil {
+				return
+			}
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	notified := false
+	for {
+		_, frame, err := conn.ReadMessage()
+		var closed *websocket.CloseError
+		if errors.As(err, &closed) {
+			if closed.Code != CloseRateLimited {
+				t.Fatalf("fechado com %d, esperava %d", closed.Code, CloseRateLimited)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("conexão caiu sem frame de fechamento: %v", err)
+		}
+		notified = notified || strings.Contains(string(frame), `"rate_limited"`)
+	}
+	if !notified {
+		t.Fatal("cliente não recebeu o aviso rate_limited antes do fechamento")
+	}
+}
@@ -0,0 +1,217 @@
+package ws
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	sessionLogSize = 512
+	sessionIdleTTL = 10 * time.Minute
+)
+
+type logEntry struct {
+	seq       uint64
+	frame     []byte
+	messageID string
+	acked     bool
+}
+
+// deviceSession sobrevive às reconexões de um dispositivo. Todo frame enviado
+// ao cliente recebe um seq crescente e fica num log circular, permitindo que
+// o cliente retome a partir do último seq que viu.
+type deviceSession struct {
+	mu         sync.Mutex
+	id         string
+	seq        uint64
+	log        []logEntry
+	attached   *Client
+	replaying  bool
+	detachedAt time.Time
+}
+
+func newDeviceSession() *deviceSession {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return &deviceSession{
+		id:  hex.EncodeToString(buf),
+		log: make([]logEntry, 0, sessionLogSize),
+	}
+}
+
+type sendResult int
+
+const (
+	sendDetached sendResult = iota
+	sendQueued
+	sendFull
+)
+
+// send carimba o frame, grava no log e entrega ao cliente conectado sem
+// bloquear. Enquanto um replay está em curso o frame fica só no log; o
+// replay o entrega na ordem certa.
+func (s *deviceSession) send(frame []byte, messageID string) sendResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	stamped := stampSeq(frame, s.seq)
+	s.append(logEntry{seq: s.seq, frame: stamped, messageID: messageID})
+
+	if s.attached == nil {
+		return sendDetached
+	}
+	if s.replaying {
+		return sendQueued
+	}
+
+	select {
+	case s.attached.Send <- stamped:
+		return sendQueued
+	default:
+		return sendFull
+	}
+}
+
+func (s *deviceSession) append(e logEntry) {
+	if len(s.log) < sessionLogSize {
+		s.log = append(s.log, e)
+		return
+	}
+	copy(s.log, s.log[1:])
+	s.log[len(s.log)-1] = e
+}
+
+// after retorna os frames com seq maior que lastSeq. ok é false quando parte
+// desse intervalo já saiu do log; nesse caso vem o que ainda resta.
+func (s *deviceSession) after(lastSeq uint64) ([]logEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lastSeq > s.seq {
+		return nil, false
+	}
+	if lastSeq == s.seq || len(s.log) == 0 {
+		return nil, lastSeq == s.seq
+	}
+
+	start := 0
+	ok := s.log[0].seq <= lastSeq+1
+	if ok {
+		start = int(lastSeq + 1 - s.log[0].seq)
+	}
+	out := make([]logEntry, len(s.log)-start)
+	copy(out, s.log[start:])
+	return out, ok
+}
+
+func (s *deviceSession) messageIDs() map[string]struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make(map[string]struct{}, len(s.log))
+	for _, e := range s.log {
+		if e.messageID != "" {
+			ids[e.messageID] = struct{}{}
+		}
+	}
+	return ids
+}
+
+// ack marca a mensagem como confirmada pelo dispositivo.
+func (s *deviceSession) ack(messageID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.log {
+		if s.log[i].messageID == messageID {
+			s.log[i].acked = true
+		}
+	}
+}
+
+// unacked devolve as mensagens do log que o dispositivo não confirmou.
+func (s *deviceSession) unacked() []logEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []logEntry
+	for _, e := range s.log {
+		if e.messageID != "" && !e.acked {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (s *deviceSession) hasMessage(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.log {
+		if e.messageID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *deviceSession) attach(c *Client) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attached = c
+	s.replaying = true
+	s.detachedAt = time.Time{}
+	return s.seq
+}
+
+func (s *deviceSession) detach(c *Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attached == c {
+		s.attached = nil
+		s.replaying = false
+		s.detachedAt = time.Now()
+	}
+}
+
+// finishReplay encerra o replay se não houver frames novos depois de cursor.
+func (s *deviceSession) finishReplay(c *Client, cursor uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attached != c {
+		return true
+	}
+	if s.seq > cursor {
+		return false
+	}
+	s.replaying = false
+	return true
+}
+
+func (s *deviceSession) expired(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attached == nil && !s.detachedAt.IsZero() && now.Sub(s.detachedAt) > sessionIdleTTL
+}
+
+func stampSeq(frame []byte, seq uint64) []byte {
+	if len(frame) < 2 || frame[0] != '{' {
+		return frame
+	}
+
+	out := make([]byte, 0, len(frame)+24)
+	out = append(out, `{"seq":`...)
+	out = strconv.AppendUint(out, seq, 10)
+	if len(frame) > 2 {
+		out = append(out, ',')
+	}
+	return append(out, frame[1:]...)
+}
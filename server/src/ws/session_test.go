@@ -0,0 +1,158 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"wisp/src/bus"
+	"wisp/src/model"
+	"wisp/src/ws/wstest"
+)
+
+func newSessionHub(t *testing.T) *Hub {
+	t.Helper()
+	h := NewHub(wstest.Config("session"), wstest.NewStore(), wstest.NewReceipts(), bus.NewLocal(), bus.NewMemoryPresence())
+	h.Run()
+	return h
+}
+
+// TestResumeReplaysInFlightFrames derruba um dispositivo com mensagens ainda
+// no buffer e confere que a retomada as entrega com os seqs originais,
+// seguidas da que chegou com ele desconectado.
+func TestResumeReplaysInFlightFrames(t *testing.T) {
+	h := newSessionHub(t)
+	bob := dial(t, h, "bob0001", "phone", "", 0, false)
+	first := bob.sessionFrame()
+
+	for i := range 3 {
+		h.Broadcast(context.Background(), message("alice01", "bob0001", fmt.Sprint(i)))
+	}
+	waitFor(t, func() bool { return len(bob.Send) == 3 })
+	bob.disconnect(t)
+	h.Broadcast(context.Background(), message("alice01", "bob0001", "3"))
+	waitFor(t, func() bool { return h.msgRepo.(*wstest.Store).Inserted() == 1 })
+
+	again := dial(t, h, "bob0001", "phone", first.SessionID, first.Seq, true)
+	s := again.sessionFrame()
+	if !s.Resumed || s.SessionID != first.SessionID || s.Seq != first.Seq {
+		t.Fatalf("sessão não retomada: %+v", s)
+	}
+	waitFor(t, func() bool { return len(again.ofType("message")) == 4 })
+	for i, m := range again.ofType("message") {
+		if m.Content != fmt.Sprint(i) || m.Seq != first.Seq+uint64(i)+1 {
+			t.Fatalf("mensagem %d: conteúdo %q, seq %d", i, m.Content, m.Seq)
+		}
+	}
+}
+
+func TestResumeWithUnknownSessionStartsOver(t *testing.T) {
+	h := newSessionHub(t)
+	bob := dial(t, h, "bob0001", "phone", "", 0, true)
+	first := bob.sessionFrame()
+	bob.disconnect(t)
+
+	again := dial(t, h, "bob0001", "phone", "outra", first.Seq, true)
+	if s := again.sessionFrame(); s.Resumed || s.SessionID == first.SessionID {
+		t.Fatalf("sessão desconhecida foi retomada: %+v", s)
+	}
+}
+
+// TestResumeAfterLogRollover confere que a retomada dentro do log funciona
+// depois de ele dar a volta, e que pedir um seq que já saiu do log começa
+// uma sessão nova.
+func TestResumeAfterLogRollover(t *testing.T) {
+	h := newSessionHub(t)
+	bob := dial(t, h, "bob0001", "phone", "", 0, true)
+	first := bob.sessionFrame()
+
+	// Em lotes menores que o buffer, para nada ser desviado à fila pendente.
+	const total = sessionLogSize + 100
+	for i := range total {
+		h.Broadcast(context.Background(), message("alice01", "bob0001", fmt.Sprint(i)))
+		if (i+1)%100 == 0 || i == total-1 {
+			waitFor(t, func() bool { return len(bob.ofType("message")) == i+1 })
+		}
+	}
+	last := bob.ofType("message")[total-1].Seq
+	bob.disconnect(t)
+
+	recent := dial(t, h, "bob0001", "phone", first.SessionID, last-10, true)
+	if !recent.sessionFrame().Resumed {
+		t.Fatal("retomada dentro do log recusada")
+	}
+	waitFor(t, func() bool { return len(recent.ofType("message")) == 10 })
+	if m := recent.ofType("message"); m[0].Seq != last-9 || m[9].Content != fmt.Sprint(total-1) {
+		t.Fatalf("replay de %d a %q", m[0].Seq, m[9].Content)
+	}
+	recent.disconnect(t)
+
+	stale := dial(t, h, "bob0001", "phone", first.SessionID, first.Seq, true)
+	if s := stale.sessionFrame(); s.Resumed || s.SessionID == first.SessionID {
+		t.Fatalf("retomada de seq fora do log: %+v", s)
+	}
+}
+
+func TestSessionLogAfter(t *testing.T) {
+	s := newDeviceSession()
+	for range sessionLogSize + 10 {
+		s.send([]byte(`{"type":"typing"}`), "")
+	}
+	cases := []struct {
+		lastSeq uint64
+		n       int
+		ok      bool
+	}{
+		{sessionLogSize + 10, 0, true},
+		{sessionLogSize + 9, 1, true},
+		{10, sessionLogSize, true},
+		{9, sessionLogSize, false},
+		{0, sessionLogSize, false},
+		{sessionLogSize + 11, 0, false},
+	}
+	for _, c := range cases {
+		entries, ok := s.after(c.lastSeq)
+		if len(entries) != c.n || ok != c.ok {
+			t.Errorf("after(%d): %d frames, ok %v", c.lastSeq, len(entries), ok)
+		}
+		if len(entries) > 0 && entries[0].seq != max(c.lastSeq+1, 11) {
+			t.Errorf("after(%d) começa no seq %d", c.lastSeq, entries[0].seq)
+		}
+	}
+}
+
+// TestFailedResumeSpillsUnacked reconecta sem retomar a sessão e confere que
+// as mensagens que o dispositivo não confirmou vão para a fila pendente e
+// chegam na conexão nova, sem repetir as já confirmadas nem as que já
+// estavam na fila.
+func TestFailedResumeSpillsUnacked(t *testing.T) {
+	h := newSessionHub(t)
+	store := h.msgRepo.(*wstest.Store)
+
+	h.Broadcast(context.Background(), message("alice01", "bob0001", "guardada"))
+	waitFor(t, func() bool { return store.Inserted() == 1 })
+
+	bob := dial(t, h, "bob0001", "phone", "", 0, false)
+	for i := range 3 {
+		h.Broadcast(context.Background(), message("alice01", "bob0001", fmt.Sprint(i)))
+	}
+	waitFor(t, func() bool { return len(bob.Send) == 4 })
+	entries, _ := bob.session.after(0)
+	h.ProcessMessageAck(&model.Ack{Type: "ack", MessageID: entries[1].messageID, From: "alice01", To: "bob0001"}, "phone")
+	bob.disconnect(t)
+
+	again := dial(t, h, "bob0001", "phone", "", 0, true)
+	if again.sessionFrame().Resumed {
+		t.Fatal("sessão retomada sem sessionId")
+	}
+	waitFor(t, func() bool { return len(again.ofType("message")) == 3 })
+	var got []string
+	for _, m := range again.ofType("message") {
+		got = append(got, m.Content)
+	}
+	if fmt.Sprint(got) != "[guardada 1 2]" {
+		t.Fatalf("conexão nova recebeu %v", got)
+	}
+	if n := store.Inserted(); n != 3 {
+		t.Fatalf("%d inserções na fila, esperava a guardada e as duas não confirmadas", n)
+	}
+}
@@ -0,0 +1,263 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+	"wisp/src/metrics"
+	"wisp/src/model"
+	"wisp/src/tracing"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// shard cuida de uma fatia dos usuários, escolhida pelo hash do userId. Cada
+// shard tem seu próprio loop, de modo que registro e entrega de usuários em
+// shards diferentes não disputam a mesma goroutine.
+type shard struct {
+	hub        *Hub
+	clients    map[string]map[string]*Client
+	sessions   map[string]*deviceSession
+	mu         sync.RWMutex
+	register   chan *Client
+	unregister chan *Client
+	deliver    chan *delivery
+	ping       chan struct{}
+}
+
+type delivery struct {
+	ctx       context.Context
+	message   *model.Message
+	forwarded bool
+	stored    bool
+	deviceID  string
+	// flushed, quando não nil, marca o ponto da fila até onde tudo já foi
+	// entregue; o loop o fecha em vez de entregar.
+	flushed chan struct{}
+}
+
+func newShard(h *Hub) *shard {
+	return &shard{
+		hub:        h,
+		clients:    make(map[string]map[string]*Client),
+		sessions:   make(map[string]*deviceSession),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		deliver:    make(chan *delivery, 1024),
+		ping:       make(chan struct{}),
+	}
+}
+
+func (s *shard) run() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case client := <-s.register:
+			s.addClient(client)
+
+		case client := <-s.unregister:
+			s.removeClient(client)
+
+		case d := <-s.deliver:
+			if d.flushed != nil {
+				close(d.flushed)
+				continue
+			}
+			s.deliverMessage(d)
+
+		case now := <-ticker.C:
+			s.expireSessions(now)
+
+		case <-s.ping:
+		}
+	}
+}
+
+// flush espera o loop processar o que já está na fila de entrega, ou ctx
+// vencer.
+func (s *shard) flush(ctx context.Context) {
+	done := make(chan struct{})
+	select {
+	case s.deliver <- &delivery{flushed: done}:
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (s *shard) addClient(client *Client) {
+	resumed, lost := s.attachSession(client)
+
+	s.mu.Lock()
+	if _, ok := s.clients[client.UserID]; !ok {
+		s.clients[client.UserID] = make(map[string]*Client)
+	}
+	if old, ok := s.clients[client.UserID][client.DeviceID]; ok && old != client {
+		old.close()
+	}
+	s.clients[client.UserID][client.DeviceID] = client
+	s.mu.Unlock()
+
+	s.hub.trackPresence(client, true)
+
+	go s.hub.replay(client, resumed, lost)
+}
+
+func (s *shard) removeClient(client *Client) {
+	s.mu.Lock()
+	if devices, ok := s.clients[client.UserID]; ok {
+		if devices[client.DeviceID] == client {
+			delete(devices, client.DeviceID)
+
+			if len(devices) == 0 {
+				delete(s.clients, client.UserID)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if client.session != nil {
+		client.session.detach(client)
+	}
+	client.close()
+	s.hub.trackPresence(client, false)
+}
+
+// devices devolve uma cópia dos clientes conectados do usuário.
+func (s *shard) devices(userID string) []*Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devices := s.clients[userID]
+	out := make([]*Client, 0, len(devices))
+	for _, c := range devices {
+		out = append(out, c)
+	}
+	return out
+}
+
+func sessionKey(userID, deviceID string) string {
+	return userID + "|" + deviceID
+}
+
+// attachSession liga o cliente à sessão do dispositivo. A sessão antiga só é
+// reaproveitada quando o cliente informa o sessionId dela e o log ainda cobre
+// tudo depois do lastSeq; caso contrário começa uma sessão nova, e a antiga
+// volta em lost para as mensagens não confirmadas irem à fila pendente.
+func (s *shard) attachSession(client *Client) (resumed bool, lost *deviceSession) {
+	key := sessionKey(client.UserID, client.DeviceID)
+
+	sess, ok := s.sessions[key]
+	resumed = ok && client.SessionID != "" && client.SessionID == sess.id
+	if resumed {
+		_, resumed = sess.after(client.LastSeq)
+	}
+	if !resumed {
+		if ok {
+			lost = sess
+		}
+		sess = newDeviceSession()
+		s.sessions[key] = sess
+	}
+
+	client.session = sess
+	seq := sess.attach(client)
+	if !resumed {
+		client.LastSeq = seq
+	}
+	return resumed, lost
+}
+
+func (s *shard) expireSessions(now time.Time) {
+	for key, sess := range s.sessions {
+		if sess.expired(now) {
+			delete(s.sessions, key)
+			userID, deviceID, _ := strings.Cut(key, "|")
+			go s.hub.spillSession(userID, deviceID, sess)
+		}
+	}
+}
+
+// deliverMessage só faz trabalho em memória; gravações na fila pendente vão
+// para o pool de workers. Mensagens repassadas a outra instância só vão para
+// a fila se nenhum dispositivo daqui recebê-las e ninguém mais as recebeu.
+func (s *shard) deliverMessage(d *delivery) {
+	message := d.message
+	if d.ctx == nil {
+		d.ctx = context.Background()
+	}
+	ctx, span := tracing.Start(context.Background(), "hub.deliver", tracing.LinkTo(d.ctx), tracing.Attrs(
+		"wisp.message_id", message.ID,
+		"wisp.to", message.To,
+	))
+	defer span.End()
+
+	devices := s.devices(message.To)
+	span.SetAttributes(attribute.Int("wisp.devices", len(devices)))
+	if d.stored {
+		s.deliverStored(d, devices)
+		return
+	}
+	if len(devices) == 0 {
+		if !d.forwarded {
+			s.hub.storePendingMessage(ctx, message)
+		}
+		return
+	}
+
+	msgJSON, err := json.Marshal(message)
+	if err != nil {
+		log.Error().Err(err).Msg("Erro ao serializar mensagem")
+		return
+	}
+
+	delivered, spilled := false, false
+	for _, client := range devices {
+		switch client.session.send(msgJSON, message.ID) {
+		case sendQueued:
+			client.markDrained()
+			delivered = true
+		case sendFull:
+			s.hub.spillMessage(ctx, client, message)
+			s.hub.checkSaturation(client)
+			spilled = true
+		}
+	}
+
+	if delivered {
+		metrics.MessagesDelivered.Inc()
+		s.hub.acks.delivered(ctx, message.ID)
+	} else if !spilled {
+		s.hub.storePendingMessage(ctx, message)
+	}
+}
+
+// deliverStored entrega uma mensagem que já está na fila pendente. Ela só
+// sai da fila com o ACK, então buffer cheio não precisa de desvio.
+func (s *shard) deliverStored(d *delivery, devices []*Client) {
+	msgJSON, err := json.Marshal(d.message)
+	if err != nil {
+		log.Error().Err(err).Msg("Erro ao serializar mensagem")
+		return
+	}
+
+	for _, client := range devices {
+		if d.deviceID != "" && d.deviceID != client.DeviceID {
+			continue
+		}
+		if client.session.hasMessage(d.message.ID) {
+			continue
+		}
+		if client.session.send(msgJSON, d.message.ID) == sendQueued {
+			client.markDrained()
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wisp/src/metrics"
+)
+
+const (
+	maxAckTracked = 100000
+	ackTrackTTL   = 10 * time.Minute
+)
+
+type Stats struct {
+	FramesDropped   uint64
+	FramesSpilled   uint64
+	SlowDisconnects uint64
+}
+
+type hubStats struct {
+	framesDropped   atomic.Uint64
+	framesSpilled   atomic.Uint64
+	slowDisconnects atomic.Uint64
+}
+
+func (h *Hub) Stats() Stats {
+	return Stats{
+		FramesDropped:   h.stats.framesDropped.Load(),
+		FramesSpilled:   h.stats.framesSpilled.Load(),
+		SlowDisconnects: h.stats.slowDisconnects.Load(),
+	}
+}
+
+// Counts devolve quantos usuários e dispositivos estão conectados nesta
+// instância.
+func (h *Hub) Counts() (users, devices int) {
+	for _, s := range h.shards {
+		s.mu.RLock()
+		users += len(s.clients)
+		for _, d := range s.clients {
+			devices += len(d)
+		}
+		s.mu.RUnlock()
+	}
+	return users, devices
+}
+
+// RegisterMetrics expõe os contadores do hub no registro do Prometheus.
+func (h *Hub) RegisterMetrics() {
+	metrics.GaugeFunc("wisp_connected_users", "Usuários com ao menos um dispositivo conectado.", func() float64 {
+		users, _ := h.Counts()
+		return float64(users)
+	})
+	metrics.GaugeFunc("wisp_connected_devices", "Dispositivos conectados.", func() float64 {
+		_, devices := h.Counts()
+		return float64(devices)
+	})
+	metrics.CounterFunc("wisp_frames_dropped_total", "Frames descartados por buffer de envio cheio.", func() float64 {
+		return float64(h.stats.framesDropped.Load())
+	})
+	metrics.CounterFunc("wisp_frames_spilled_total", "Mensagens desviadas para a fila pendente por buffer cheio.", func() float64 {
+		return float64(h.stats.framesSpilled.Load())
+	})
+	metrics.CounterFunc("wisp_slow_consumer_disconnects_total", "Clientes desconectados por lentidão.", func() float64 {
+		return float64(h.stats.slowDisconnects.Load())
+	})
+}
+
+// ackTracker lembra quando cada mensagem foi entregue para medir o tempo até
+// o ACK. Entradas sem ACK são descartadas quando o mapa cresce demais.
+type ackTracker struct {
+	mu   sync.Mutex
+	sent map[string]ackEntry
+}
+
+type ackEntry struct {
+	at  time.Time
+	ctx context.Context
+}
+
+func newAckTracker() *ackTracker {
+	return &ackTracker{sent: make(map[string]ackEntry)}
+}
+
+// delivered registra a entrega; ctx carrega o span da entrega, ao qual o
+// span do ACK vai se ligar.
+func (t *ackTracker) delivered(ctx context.Context, messageID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.sent[messageID]; ok {
+		return
+	}
+	if len(t.sent) >= maxAckTracked {
+		cutoff := time.Now().Add(-ackTrackTTL)
+		for id, e := range t.sent {
+			if e.at.Before(cutoff) {
+				delete(t.sent, id)
+			}
+		}
+		if len(t.sent) >= maxAckTracked {
+			return
+		}
+	}
+	t.sent[messageID] = ackEntry{at: time.Now(), ctx: ctx}
+}
+
+func (t *ackTracker) acked(messageID string) context.Context {
+	t.mu.Lock()
+	e, ok := t.sent[messageID]
+	delete(t.sent, messageID)
+	t.mu.Unlock()
+
+	if !ok {
+		return context.Background()
+	}
+	metrics.AckLatency.Observe(time.Since(e.at).Seconds())
+	return e.ctx
+}
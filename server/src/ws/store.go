@@ -0,0 +1,64 @@
+package ws
+
+import (
+	"context"
+	"time"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MessageStore é a fila persistente de mensagens pendentes usada pelo hub.
+// Em produção é o repository.MessageRepo.
+type MessageStore interface {
+	Insert(ctx context.Context, pm *model.PendingMessage) (primitive.ObjectID, error)
+	DeleteAcked(ctx context.Context, id primitive.ObjectID, to, deviceID string) error
+	GetPendingFor(ctx context.Context, to, deviceID string) ([]model.PendingMessage, error)
+}
+
+// ReceiptStore guarda os recibos de envio usados na deduplicação.
+// Em produção é o repository.ReceiptRepo.
+type ReceiptStore interface {
+	Reserve(ctx context.Context, rc *model.MessageReceipt) (*model.MessageReceipt, bool, error)
+}
+
+// PendingWatcher notifica inserções na fila pendente feitas por qualquer
+// instância. Em produção é o change stream do repository.MessageRepo.
+type PendingWatcher interface {
+	WatchInserts(ctx context.Context, resumeAfter []byte, fn func(pm *model.PendingMessage, token []byte)) error
+}
+
+// TokenStore persiste o ponto de retomada do watcher por instância.
+type TokenStore interface {
+	Load(ctx context.Context, node string) ([]byte, error)
+	Save(ctx context.Context, node string, token []byte) error
+}
+
+// BotInbox recebe as mensagens endereçadas a contas de bot. IsBot é
+// consultado para toda mensagem roteada e precisa responder de cache.
+type BotInbox interface {
+	IsBot(ctx context.Context, userID string) bool
+	Received(ctx context.Context, msg *model.Message)
+}
+
+// Expirer cuida das mensagens temporárias: informa o timer da conversa e
+// registra cada mensagem carimbada para ser apagada quando vencer.
+// DisappearAfter é consultado para toda mensagem e precisa responder de
+// cache.
+type Expirer interface {
+	DisappearAfter(ctx context.Context, from, to string) time.Duration
+	Track(ctx context.Context, msg *model.Message)
+}
+
+// History grava cada mensagem aceita no histórico permanente. Record roda
+// no accept e não deve bloquear. Em produção é o service.HistoryService.
+type History interface {
+	Record(ctx context.Context, msg *model.Message)
+}
+
+// Notifier é avisado quando uma mensagem vai para a fila pendente de um
+// destinatário sem dispositivos conectados. Em produção é o
+// service.PushService.
+type Notifier interface {
+	MessageQueued(ctx context.Context, msg *model.Message)
+}
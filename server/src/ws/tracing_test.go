@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wisp/config"
+	"wisp/src/bus"
+	"wisp/src/model"
+	"wisp/src/tracing"
+	"wisp/src/ws/wstest"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestDeliverySpanLinksToSender confere que o span de entrega ao
+// destinatário, nesta ou em outra instância, aponta para o aceite do frame
+// do remetente.
+func TestDeliverySpanLinksToSender(t *testing.T) {
+	var cfg config.Config
+	cfg.Tracing.Exporter = "memory"
+	cfg.Tracing.SampleRatio = 1
+	shutdown, err := tracing.Init(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { shutdown(context.Background()) })
+
+	b, presence := bus.NewLocal(), bus.NewMemoryPresence()
+	a := NewHub(wstest.Config("trace-a"), wstest.NewStore(), wstest.NewReceipts(), b, presence)
+	c := NewHub(wstest.Config("trace-b"), wstest.NewStore(), wstest.NewReceipts(), b, presence)
+	a.Run()
+	c.Run()
+
+	var sender, local, remote atomic.Int64
+	connect(t, a, &sender, "alice01")
+	connect(t, a, &local, "bob0001")
+	connect(t, c, &remote, "carol01")
+	waitFor(t, func() bool {
+		devices, _ := presence.Lookup(context.Background(), "carol01")
+		return devices["test"] == "trace-b"
+	})
+
+	for _, to := range []string{"bob0001", "carol01"} {
+		t.Run(to, func(t *testing.T) {
+			tracing.Memory().Reset()
+
+			ctx, receive := tracing.Start(context.Background(), "ws.receive")
+			msg := &model.Message{Type: "message", From: "alice01", To: to, Content: "oi", Timestamp: time.Now().Unix()}
+			a.Broadcast(ctx, msg)
+			receive.End()
+
+			var accept, deliver tracetest.SpanStub
+			waitFor(t, func() bool {
+				otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background())
+				accept = findSpan(tracing.Memory().GetSpans(), "hub.accept", msg.ID)
+				deliver = findSpan(tracing.Memory().GetSpans(), "hub.deliver", msg.ID)
+				return accept.Name != "" && deliver.Name != ""
+			})
+
+			if accept.Parent.SpanID() != receive.SpanContext().SpanID() {
+				t.Errorf("hub.accept não é filho do frame do remetente")
+			}
+			if !linksTo(deliver, accept.SpanContext) {
+				t.Errorf("hub.deliver não tem link para hub.accept: %+v", deliver.Links)
+			}
+		})
+	}
+}
+
+func findSpan(spans tracetest.SpanStubs, name, messageID string) tracetest.SpanStub {
+	for _, s := range spans {
+		if s.Name != name {
+			continue
+		}
+		for _, kv := range s.Attributes {
+			if kv.Key == "wisp.message_id" && kv.Value.AsString() == messageID {
+				return s
+			}
+		}
+	}
+	return tracetest.SpanStub{}
+}
+
+func linksTo(s tracetest.SpanStub, sc trace.SpanContext) bool {
+	for _, l := range s.Links {
+		if l.SpanContext.TraceID() == sc.TraceID() && l.SpanContext.SpanID() == sc.SpanID() {
+			return true
+		}
+	}
+	return false
+}
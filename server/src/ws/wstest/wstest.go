@@ -0,0 +1,118 @@
+// Package wstest traz versões em memória das dependências do ws.Hub, usadas
+// nos testes e no wisp-bench.
+package wstest
+
+import (
+	"context"
+	"sync"
+	"time"
+	"wisp/config"
+	"wisp/src/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Config devolve uma configuração de hub para uma instância node, com
+// timeout de consumidor lento longo o bastante para não atrapalhar.
+func Config(node string) *config.Config {
+	var cfg config.Config
+	cfg.Cluster.NodeID = node
+	cfg.Hub.Shards = 8
+	cfg.Hub.Workers = 16
+	cfg.Hub.QueueSize = 1024
+	cfg.Hub.DedupWindow = time.Minute
+	cfg.Hub.SlowConsumerTimeout = time.Hour
+	return &cfg
+}
+
+// Store é a fila pendente em memória, com as mesmas regras de cópia por
+// dispositivo do repository.MessageRepo.
+type Store struct {
+	mu      sync.Mutex
+	pending []model.PendingMessage
+	inserts int
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+func (s *Store) Insert(ctx context.Context, pm *model.PendingMessage) (primitive.ObjectID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pm.ID.IsZero() {
+		pm.ID = primitive.NewObjectID()
+	}
+	pm.CreatedAt = time.Now()
+	s.pending = append(s.pending, *pm)
+	s.inserts++
+	return pm.ID, nil
+}
+
+func (s *Store) DeleteAcked(ctx context.Context, id primitive.ObjectID, to, deviceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.pending[:0]
+	for _, pm := range s.pending {
+		acked := pm.To == to && (pm.ID == id || pm.MessageID == id.Hex() && pm.DeviceID == deviceID)
+		if !acked {
+			kept = append(kept, pm)
+		}
+	}
+	s.pending = kept
+	return nil
+}
+
+func (s *Store) GetPendingFor(ctx context.Context, to, deviceID string) ([]model.PendingMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []model.PendingMessage
+	for _, pm := range s.pending {
+		if pm.To == to && (pm.DeviceID == "" || pm.DeviceID == deviceID) {
+			out = append(out, pm)
+		}
+	}
+	return out, nil
+}
+
+// Inserted conta as inserções desde a criação, inclusive as já confirmadas.
+func (s *Store) Inserted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inserts
+}
+
+// Receipts imita o índice único {from, clientId} de message_receipts.
+type Receipts struct {
+	mu       sync.Mutex
+	receipts map[string]*model.MessageReceipt
+	reserves int
+}
+
+func NewReceipts() *Receipts {
+	return &Receipts{receipts: make(map[string]*model.MessageReceipt)}
+}
+
+func (r *Receipts) Reserve(ctx context.Context, rc *model.MessageReceipt) (*model.MessageReceipt, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reserves++
+	key := rc.From + "\x00" + rc.ClientID
+	if existing, ok := r.receipts[key]; ok {
+		return existing, true, nil
+	}
+	rc.CreatedAt = time.Now()
+	r.receipts[key] = rc
+	return rc, false, nil
+}
+
+// Reserves conta as consultas ao índice, que o cache do hub deve poupar.
+func (r *Receipts) Reserves() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reserves
+}